@@ -0,0 +1,319 @@
+package main
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/MenschMachine/tfc-system-monitor/monitor"
+)
+
+// SilenceRequest is the body of POST /api/silences
+type SilenceRequest struct {
+	monitor.Silence
+	Duration string `json:"duration"` // alternative to ends_at, e.g. "2h"
+}
+
+// AckRequest is the body of POST /api/ack
+type AckRequest struct {
+	Metric     string `json:"metric"`
+	Level      string `json:"level"`
+	Mountpoint string `json:"mountpoint"`
+	Author     string `json:"author"`
+	Comment    string `json:"comment"`
+}
+
+// registerAPI adds the acknowledgement, silence and event endpoints. stateMu guards the
+// state manager against concurrent checks. Endpoints that change alerting require
+// write access, see requireWriteAccess.
+func registerAPI(mux *http.ServeMux, config *monitor.Config, stateManager *monitor.StateManager, stateMu *sync.Mutex) {
+	token := config.API.BearerToken()
+
+	mux.HandleFunc("GET /api/ack", func(w http.ResponseWriter, r *http.Request) {
+		acks, err := stateManager.Acknowledgements()
+		if err != nil {
+			writeAPIError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, acks)
+	})
+
+	mux.HandleFunc("POST /api/ack", requireWriteAccess(token, func(w http.ResponseWriter, r *http.Request) {
+		var req AckRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": fmt.Sprintf("invalid request: %v", err)})
+			return
+		}
+		stateMu.Lock()
+		ack, err := stateManager.Acknowledge(req.Metric, req.Level, req.Mountpoint, req.Author, req.Comment)
+		stateMu.Unlock()
+		if err != nil {
+			writeAPIError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, ack)
+	}))
+
+	mux.HandleFunc("GET /api/silences", func(w http.ResponseWriter, r *http.Request) {
+		silences, err := stateManager.Silences()
+		if err != nil {
+			writeAPIError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, silences)
+	})
+
+	mux.HandleFunc("POST /api/silences", requireWriteAccess(token, func(w http.ResponseWriter, r *http.Request) {
+		var req SilenceRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": fmt.Sprintf("invalid request: %v", err)})
+			return
+		}
+		if req.Duration != "" {
+			d, err := time.ParseDuration(req.Duration)
+			if err != nil {
+				writeJSON(w, http.StatusBadRequest, map[string]string{"error": fmt.Sprintf("invalid duration: %v", err)})
+				return
+			}
+			req.EndsAt = time.Now().Add(d)
+		}
+		silence, err := stateManager.AddSilence(req.Silence)
+		if err != nil {
+			writeAPIError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusCreated, silence)
+	}))
+
+	mux.HandleFunc("DELETE /api/silences/{id}", requireWriteAccess(token, func(w http.ResponseWriter, r *http.Request) {
+		if err := stateManager.ExpireSilence(r.PathValue("id")); err != nil {
+			writeAPIError(w, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	mux.HandleFunc("GET /api/events", func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		filter, err := eventFilter(query.Get("since"), query.Get("until"), query.Get("metric"), query.Get("level"), query.Get("limit"))
+		if err != nil {
+			writeAPIError(w, err)
+			return
+		}
+		events, err := monitor.ReadEvents(config.EventLogs(), filter)
+		if err != nil {
+			log.Printf("API error: %v", err)
+			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+			return
+		}
+		if events == nil {
+			events = []monitor.Event{}
+		}
+		writeJSON(w, http.StatusOK, events)
+	})
+}
+
+// requireWriteAccess guards a handler that changes alerting. With a token, requests must
+// send it as "Authorization: Bearer <token>"; without one, only requests from the local
+// host are accepted.
+func requireWriteAccess(token string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if token != "" {
+			given, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+			if !ok || subtle.ConstantTimeCompare([]byte(given), []byte(token)) != 1 {
+				w.Header().Set("WWW-Authenticate", `Bearer realm="tfc-system-monitor"`)
+				writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "missing or invalid API token"})
+				return
+			}
+		} else if !isLoopback(r.RemoteAddr) {
+			writeJSON(w, http.StatusForbidden, map[string]string{"error": "only local requests are accepted without an API token"})
+			return
+		}
+		next(w, r)
+	}
+}
+
+// isLoopback reports whether a request's remote address is on the local host
+func isLoopback(remoteAddr string) bool {
+	host, _, err := net.SplitHostPort(remoteAddr)
+	if err != nil {
+		return false
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// writeJSON writes v as a JSON response
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// writeAPIError writes an error response: 404 for unknown silences and violations,
+// 400 otherwise
+func writeAPIError(w http.ResponseWriter, err error) {
+	status := http.StatusBadRequest
+	if errors.Is(err, monitor.ErrSilenceNotFound) || errors.Is(err, monitor.ErrViolationNotFound) {
+		status = http.StatusNotFound
+	}
+	log.Printf("API error: %v", err)
+	writeJSON(w, status, map[string]string{"error": err.Error()})
+}
+
+// runSilenceCommand runs the -ack, -silence, -silences and -expire-silence commands
+func runSilenceCommand() error {
+	stateManager, err := monitor.NewStateManager()
+	if err != nil {
+		return fmt.Errorf("failed to initialize state manager: %w", err)
+	}
+
+	var result interface{}
+	switch {
+	case *ackFlag != "":
+		parts := strings.SplitN(*ackFlag, ":", 3)
+		if len(parts) < 2 {
+			return fmt.Errorf("-ack expects metric:level[:mountpoint], got '%s'", *ackFlag)
+		}
+		mountpoint := ""
+		if len(parts) == 3 {
+			mountpoint = parts[2]
+		}
+		if result, err = stateManager.Acknowledge(parts[0], parts[1], mountpoint, *authorFlag, *commentFlag); err != nil {
+			return err
+		}
+	case *silenceFlag != "":
+		silence, err := parseSilenceMatchers(*silenceFlag)
+		if err != nil {
+			return err
+		}
+		d, err := time.ParseDuration(*durationFlag)
+		if err != nil {
+			return fmt.Errorf("invalid -duration: %w", err)
+		}
+		silence.Author = *authorFlag
+		silence.Comment = *commentFlag
+		silence.EndsAt = time.Now().Add(d)
+		if result, err = stateManager.AddSilence(silence); err != nil {
+			return err
+		}
+	case *expireFlag != "":
+		if err := stateManager.ExpireSilence(*expireFlag); err != nil {
+			return err
+		}
+		fmt.Printf("Silence %s expired\n", *expireFlag)
+		return nil
+	default:
+		if result, err = stateManager.Silences(); err != nil {
+			return err
+		}
+	}
+
+	data, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal result: %w", err)
+	}
+	fmt.Println(string(data))
+	return nil
+}
+
+// runHistoryCommand runs the -history command
+func runHistoryCommand() error {
+	config, err := monitor.LoadConfig(*configPath)
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+	paths := config.EventLogs()
+	if len(paths) == 0 {
+		return fmt.Errorf("no file alert action configured in %s", *configPath)
+	}
+
+	filter, err := eventFilter(*sinceFlag, *untilFlag, *metricFlag, *levelFlag, strconv.Itoa(*limitFlag))
+	if err != nil {
+		return err
+	}
+	events, err := monitor.ReadEvents(paths, filter)
+	if err != nil {
+		return err
+	}
+	if events == nil {
+		events = []monitor.Event{}
+	}
+
+	data, err := json.MarshalIndent(events, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal events: %w", err)
+	}
+	fmt.Println(string(data))
+	return nil
+}
+
+// eventFilter builds an event filter from -history flags or /api/events parameters
+func eventFilter(since string, until string, metric string, level string, limit string) (monitor.EventFilter, error) {
+	filter := monitor.EventFilter{Metric: metric, Level: level}
+	now := time.Now()
+
+	var err error
+	if since != "" {
+		if filter.Since, err = monitor.ParseEventTime(since, now); err != nil {
+			return filter, fmt.Errorf("invalid since: %w", err)
+		}
+	}
+	if until != "" {
+		if filter.Until, err = monitor.ParseEventTime(until, now); err != nil {
+			return filter, fmt.Errorf("invalid until: %w", err)
+		}
+	}
+	if level != "" && level != "warning" && level != "critical" {
+		return filter, fmt.Errorf("invalid level '%s'", level)
+	}
+	if limit != "" {
+		if filter.Limit, err = strconv.Atoi(limit); err != nil || filter.Limit < 0 {
+			return filter, fmt.Errorf("invalid limit '%s'", limit)
+		}
+	}
+	return filter, nil
+}
+
+// parseSilenceMatchers parses "name=value,..." matchers. metric, level and resource
+// set the corresponding matchers; other names match labels.
+func parseSilenceMatchers(s string) (monitor.Silence, error) {
+	var silence monitor.Silence
+	for _, matcher := range strings.Split(s, ",") {
+		name, value, ok := strings.Cut(strings.TrimSpace(matcher), "=")
+		if !ok || name == "" {
+			return silence, fmt.Errorf("invalid silence matcher '%s', expected name=value", matcher)
+		}
+		switch name {
+		case "metric":
+			silence.Metric = value
+		case "level":
+			silence.Level = value
+		case "resource":
+			silence.Resource = value
+		default:
+			if silence.Labels == nil {
+				silence.Labels = make(map[string]string)
+			}
+			silence.Labels[name] = value
+		}
+	}
+	return silence, nil
+}
+
+// defaultAuthor returns the current user name for -author
+func defaultAuthor() string {
+	if user := os.Getenv("USER"); user != "" {
+		return user
+	}
+	return "unknown"
+}
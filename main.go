@@ -9,7 +9,9 @@ import (
 	"net/http"
 	"os"
 	"os/signal"
+	"path/filepath"
 	"strings"
+	"sync"
 	"syscall"
 	"time"
 
@@ -18,8 +20,23 @@ import (
 
 // Status represents the overall system status response
 type Status struct {
-	Status string   `json:"status"`
-	Info   []string `json:"info"`
+	Status      string                   `json:"status"`
+	Info        []string                 `json:"info"`
+	Schedules   map[string]string        `json:"schedules,omitempty"`   // active threshold schedule per metric
+	Maintenance []string                 `json:"maintenance,omitempty"` // active maintenance windows
+	Deliveries  []monitor.DeliveryResult `json:"deliveries,omitempty"`  // outcome of each alert action
+}
+
+// Health represents the health check response
+type Health struct {
+	Status string       `json:"status"`
+	Spool  *SpoolHealth `json:"spool,omitempty"`
+}
+
+// SpoolHealth reports the depth of the alert spool
+type SpoolHealth struct {
+	Queued int `json:"queued"`
+	Dead   int `json:"dead"`
 }
 
 // ToJSON converts Status to JSON string
@@ -55,6 +72,27 @@ var (
 	versionFlag = flag.Bool("version", false, "")
 )
 
+// Acknowledgement and silence commands
+var (
+	ackFlag      = flag.String("ack", "", "")
+	silenceFlag  = flag.String("silence", "", "")
+	silencesFlag = flag.Bool("silences", false, "")
+	expireFlag   = flag.String("expire-silence", "", "")
+	durationFlag = flag.String("duration", "1h", "")
+	authorFlag   = flag.String("author", defaultAuthor(), "")
+	commentFlag  = flag.String("comment", "", "")
+)
+
+// Event history command
+var (
+	historyFlag = flag.Bool("history", false, "")
+	sinceFlag   = flag.String("since", "24h", "")
+	untilFlag   = flag.String("until", "", "")
+	metricFlag  = flag.String("metric", "", "")
+	levelFlag   = flag.String("level", "", "")
+	limitFlag   = flag.Int("limit", 0, "")
+)
+
 // Set at build time with -ldflags
 var Version = "dev"
 
@@ -81,7 +119,8 @@ FLAGS:
   -port int
       Port for HTTP server (default: 12349)
       Only used when running in server mode (default).
-      The server exposes endpoints: / (status) and /health
+      The server exposes endpoints: / (status), /health (includes alert spool depth),
+    /api/ack, /api/silences and /api/events
 
   -report
       Generate an HTML report from collected RRD data and exit.
@@ -92,6 +131,38 @@ FLAGS:
       Where historical metrics are stored. Directory will be created if it doesn't exist.
       Can also be set in config file via 'rrd_path' key. Flag overrides config file.
 
+  -ack metric:level[:mountpoint]
+      Acknowledge an active violation and exit, e.g. "cpu:critical" or "disk:warning:/var".
+      Stops repeat alerts and escalation of the violation until it resolves.
+
+  -silence matchers
+      Create a silence and exit. Matchers are name=value pairs separated by commas;
+      metric, level and resource (mountpoint or device) match the violation, other
+      names match labels, e.g. "metric=disk,resource=/var".
+
+  -duration string
+      How long a silence created with -silence lasts (default: "1h")
+
+  -author string
+      Author of an acknowledgement or silence (default: $USER)
+
+  -comment string
+      Comment of an acknowledgement or silence (required for silences)
+
+  -silences
+      List silences and exit.
+
+  -expire-silence id
+      Expire a silence and exit.
+
+  -history
+      Print the events recorded by file alert actions and exit. Filter them with:
+        -since   Start time or age, e.g. "2h" or "2024-05-01 08:00" (default: "24h")
+        -until   End time or age (default: now)
+        -metric  Metric name
+        -level   "warning" or "critical"
+        -limit   Print only the latest events
+
   -h, -help
       Show this help message
 
@@ -133,6 +204,12 @@ EXAMPLES:
 
   # Generate report from collected data
   tfc-system-monitor -report
+
+  # Silence disk alerts for /var during maintenance
+  tfc-system-monitor -silence "metric=disk,resource=/var" -duration 2h -comment "resizing volume"
+
+  # Show critical disk alerts of the last week
+  tfc-system-monitor -history -since 168h -metric disk -level critical
 `)
 }
 
@@ -174,6 +251,10 @@ func run() error {
 	}
 
 	switch {
+	case *ackFlag != "" || *silenceFlag != "" || *silencesFlag || *expireFlag != "":
+		return runSilenceCommand()
+	case *historyFlag:
+		return runHistoryCommand()
 	case *reportMode:
 		return runReport()
 	case *cliMode:
@@ -227,6 +308,12 @@ func runCLI() error {
 	if err != nil {
 		return fmt.Errorf("failed to initialize state manager: %w", err)
 	}
+
+	baselines, err := monitor.NewBaselineStore(filepath.Join(rrdPathToUse, "baselines.json"))
+	if err != nil {
+		return fmt.Errorf("failed to load baselines: %w", err)
+	}
+	stateManager.Baselines = baselines
 	status, err := checkSystemStatus(config, stateManager, recorder)
 	if err != nil {
 		return err
@@ -235,6 +322,17 @@ func runCLI() error {
 	if *debugMode {
 		fmt.Println(status.ToJSON())
 	}
+
+	// Signal failed alert deliveries through the exit code
+	failed := 0
+	for _, delivery := range status.Deliveries {
+		if delivery.Err() != nil {
+			failed++
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d alert deliveries failed", failed, len(status.Deliveries))
+	}
 	return nil
 }
 
@@ -261,9 +359,20 @@ func runServer() error {
 		return fmt.Errorf("failed to initialize state manager: %w", err)
 	}
 
+	baselines, err := monitor.NewBaselineStore(filepath.Join(rrdPathToUse, "baselines.json"))
+	if err != nil {
+		return fmt.Errorf("failed to load baselines: %w", err)
+	}
+	stateManager.Baselines = baselines
+
+	// Checks and API requests share the state manager
+	var stateMu sync.Mutex
+
 	http.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
 		log.Printf("GET %s from %s", r.RequestURI, r.RemoteAddr)
+		stateMu.Lock()
 		status, err := checkSystemStatus(config, stateManager, recorder)
+		stateMu.Unlock()
 		if err != nil {
 			fmt.Fprintf(os.Stderr, "ERROR: %v\n", err)
 			http.Error(w, `{"status":"ERROR","info":["internal server error"]}`, http.StatusInternalServerError)
@@ -275,11 +384,26 @@ func runServer() error {
 	})
 
 	http.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
+		health := Health{Status: "OK"}
+		if config.Spool.Enabled {
+			spool, err := monitor.NewSpool(config.Spool)
+			if err == nil {
+				queued, dead, err := spool.Depth()
+				if err != nil {
+					log.Printf("Failed to read spool depth: %v", err)
+				} else {
+					health.Spool = &SpoolHealth{Queued: queued, Dead: dead}
+				}
+			}
+		}
+
 		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(http.StatusOK)
-		w.Write([]byte(`{"status": "OK"}`))
+		json.NewEncoder(w).Encode(health)
 	})
 
+	registerAPI(http.DefaultServeMux, config, stateManager, &stateMu)
+
 	addr := fmt.Sprintf(":%d", *port)
 	log.Printf("Starting TFC System Monitor server on %s", addr)
 
@@ -322,12 +446,27 @@ func checkSystemStatus(config *monitor.Config, stateManager *monitor.StateManage
 		}
 	}
 
+	// Re-learn anomaly baselines from RRD history when stale
+	if recorder != nil && stateManager.Baselines != nil {
+		if err := stateManager.Baselines.Refresh(config, recorder); err != nil {
+			fmt.Fprintf(os.Stderr, "ERROR: failed to refresh baselines: %v\n", err)
+		}
+	}
+
 	// Check thresholds
 	warningViolations, criticalViolations, err := monitor.CheckAllThresholds(config, stats, stateManager)
 	if err != nil {
 		return nil, fmt.Errorf("failed to evaluate thresholds: %w", err)
 	}
 
+	// Report active threshold schedules
+	if active := monitor.ActiveSchedules(config, time.Now()); len(active) > 0 {
+		status.Schedules = active
+	}
+
+	// Report active maintenance windows
+	status.Maintenance = monitor.ActiveMaintenance(config, time.Now())
+
 	// Add violations to status
 	for _, violation := range criticalViolations {
 		status.AddCritical(violation.Metric, violation.Message)
@@ -337,9 +476,48 @@ func checkSystemStatus(config *monitor.Config, stateManager *monitor.StateManage
 		status.AddWarning(violation.Metric, violation.Message)
 	}
 
-	// Process violations (alerts)
-	if err := monitor.ProcessViolations(config, warningViolations, criticalViolations); err != nil {
-		return nil, fmt.Errorf("failed to process violations: %w", err)
+	// Retry spooled alert deliveries that are due; a broken spool does not fail the check
+	if config.Spool.Enabled {
+		if spool, err := monitor.NewSpool(config.Spool); err != nil {
+			fmt.Fprintf(os.Stderr, "ERROR: failed to create spool: %v\n", err)
+		} else if _, err := spool.Retry(config, stateManager); err != nil {
+			fmt.Fprintf(os.Stderr, "ERROR: failed to retry spooled alerts: %v\n", err)
+		}
+	}
+
+	// Hold violations back for grouping and the digest
+	notifications, err := monitor.GroupViolations(config, stateManager, warningViolations, criticalViolations)
+	if err != nil {
+		return nil, fmt.Errorf("failed to group violations: %w", err)
+	}
+
+	// Process violations (alerts); a failing action does not fail the check
+	for _, notification := range notifications {
+		deliveries := monitor.DispatchViolations(config, notification.Warning, notification.Critical)
+		status.Deliveries = append(status.Deliveries, deliveries...)
+	}
+
+	// Escalate violations that persisted past their escalation delays
+	deliveries := monitor.DispatchEscalations(config, stateManager.TakeEscalations())
+	status.Deliveries = append(status.Deliveries, deliveries...)
+
+	// Announce violations that resolved since the last check
+	deliveries = monitor.DispatchResolved(config, stateManager.TakeResolved())
+	status.Deliveries = append(status.Deliveries, deliveries...)
+	for _, delivery := range status.Deliveries {
+		if err := delivery.Err(); err != nil {
+			fmt.Fprintf(os.Stderr, "ERROR: %v\n", err)
+		}
+	}
+
+	// Push active violations to Alertmanager; a failed push is retried next cycle
+	if err := monitor.PushAlertmanager(config, stateManager, time.Now()); err != nil {
+		fmt.Fprintf(os.Stderr, "ERROR: failed to push alerts to Alertmanager: %v\n", err)
+	}
+
+	// Publish metrics and status to MQTT; an unreachable broker does not fail the check
+	if err := monitor.PublishMQTT(config.MQTT, stats, status.Status, status.Info); err != nil {
+		fmt.Fprintf(os.Stderr, "ERROR: failed to publish to MQTT: %v\n", err)
 	}
 
 	return status, nil
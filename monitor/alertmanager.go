@@ -0,0 +1,247 @@
+package monitor
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// AlertmanagerFile stores the alerts last pushed to Alertmanager, so that alerts of
+// violations that cleared can be resolved
+const AlertmanagerFile = "/tmp/tfc-monitor-alertmanager.json"
+
+// AlertmanagerConfig configures pushing active violations to Prometheus Alertmanager
+type AlertmanagerConfig struct {
+	Enabled        bool     `yaml:"enabled"`
+	URLs           []string `yaml:"urls"`            // Alertmanager base URLs; each receives all alerts
+	Username       string   `yaml:"username"`        // Basic auth (optional); environment variables are expanded
+	Password       string   `yaml:"password"`        // Basic auth password
+	RepostInterval string   `yaml:"repost_interval"` // Re-post active alerts this often (default: "1m")
+	ResolveTimeout string   `yaml:"resolve_timeout"` // Active alerts end this long after a post unless re-posted (default: "15m")
+	Timeout        string   `yaml:"timeout"`         // Request timeout (default: "10s")
+	GeneratorURL   string   `yaml:"generator_url"`   // Link shown with the alerts (optional)
+}
+
+// AlertmanagerAlert is an alert in the format of the Alertmanager v2 API
+type AlertmanagerAlert struct {
+	Labels       map[string]string `json:"labels"`
+	Annotations  map[string]string `json:"annotations"`
+	StartsAt     time.Time         `json:"startsAt"`
+	EndsAt       time.Time         `json:"endsAt"`
+	GeneratorURL string            `json:"generatorURL,omitempty"`
+}
+
+// alertmanagerStore is persisted in the Alertmanager file
+type alertmanagerStore struct {
+	LastPost time.Time                    `json:"last_post"`
+	Alerts   map[string]AlertmanagerAlert `json:"alerts"` // active alerts by state key
+}
+
+// durations returns the repost interval, resolve timeout and request timeout
+func (c AlertmanagerConfig) durations() (time.Duration, time.Duration, time.Duration, error) {
+	durations := []time.Duration{time.Minute, 15 * time.Minute, 10 * time.Second}
+	for i, s := range []string{c.RepostInterval, c.ResolveTimeout, c.Timeout} {
+		if s == "" {
+			continue
+		}
+		d, err := parseDuration(s)
+		if err != nil {
+			return 0, 0, 0, err
+		}
+		if d <= 0 {
+			return 0, 0, 0, fmt.Errorf("durations must be positive")
+		}
+		durations[i] = d
+	}
+	return durations[0], durations[1], durations[2], nil
+}
+
+// validate validates the Alertmanager settings
+func (c AlertmanagerConfig) validate() error {
+	if !c.Enabled {
+		return nil
+	}
+	if len(c.URLs) == 0 {
+		return fmt.Errorf("alertmanager config missing 'urls' field")
+	}
+	for _, u := range c.URLs {
+		if !strings.HasPrefix(u, "http://") && !strings.HasPrefix(u, "https://") {
+			return fmt.Errorf("alertmanager url '%s' must start with http:// or https://", u)
+		}
+	}
+	repost, resolve, _, err := c.durations()
+	if err != nil {
+		return fmt.Errorf("alertmanager config: %w", err)
+	}
+	if resolve <= repost {
+		return fmt.Errorf("alertmanager config 'resolve_timeout' must be longer than 'repost_interval'")
+	}
+	return nil
+}
+
+// PushAlertmanager posts the active violations to Alertmanager. New alerts and alerts of
+// violations that cleared are posted right away; active alerts are re-posted every repost
+// interval, so that Alertmanager keeps them firing.
+func PushAlertmanager(config *Config, stateManager *StateManager, now time.Time) error {
+	am := config.Alertmanager
+	if !am.Enabled {
+		return nil
+	}
+	repost, resolve, timeout, err := am.durations()
+	if err != nil {
+		return err
+	}
+
+	store, err := loadAlertmanagerStore(stateManager.AlertmanagerFile)
+	if err != nil {
+		return err
+	}
+
+	hostname, _ := os.Hostname()
+	active := make(map[string]AlertmanagerAlert)
+	due := now.Sub(store.LastPost) >= repost
+	for key, state := range stateManager.States {
+		// Violations still in their min_duration are pending, as for other alerts
+		if state.DurationMinutes() < config.GetViolationThrottleConfig(state.Violation()).MinDurationMinutes {
+			continue
+		}
+		alert := newAlertmanagerAlert(state, hostname, am.GeneratorURL)
+		alert.EndsAt = now.Add(resolve)
+		if _, ok := store.Alerts[key]; !ok {
+			due = true
+		}
+		active[key] = alert
+	}
+
+	var alerts []AlertmanagerAlert
+	for key, alert := range store.Alerts {
+		if _, ok := active[key]; !ok {
+			alert.EndsAt = now
+			alerts = append(alerts, alert)
+			due = true
+		}
+	}
+	if !due {
+		return nil
+	}
+	for _, alert := range active {
+		alerts = append(alerts, alert)
+	}
+	if len(alerts) == 0 {
+		return nil
+	}
+	sort.Slice(alerts, func(i, j int) bool {
+		return alerts[i].Labels["alertname"]+alerts[i].Labels["mountpoint"]+alerts[i].Labels["level"] <
+			alerts[j].Labels["alertname"]+alerts[j].Labels["mountpoint"]+alerts[j].Labels["level"]
+	})
+
+	body, err := json.Marshal(alerts)
+	if err != nil {
+		return fmt.Errorf("failed to marshal alertmanager alerts: %w", err)
+	}
+
+	// Post to every Alertmanager of an HA cluster; they deduplicate notifications
+	var errs []error
+	for _, u := range am.URLs {
+		if err := postAlertmanager(am, u, body, timeout); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if len(errs) > 0 {
+		// Keep the previous store, so resolved alerts are sent again next time
+		return errors.Join(errs...)
+	}
+	log.Printf("Alertmanager: posted %d alerts (%d active)", len(alerts), len(active))
+
+	store.LastPost = now
+	store.Alerts = active
+	return store.save(stateManager.AlertmanagerFile)
+}
+
+// newAlertmanagerAlert builds the alert of an active violation
+func newAlertmanagerAlert(state *ViolationState, hostname string, generatorURL string) AlertmanagerAlert {
+	violation := state.Violation()
+	labels := violation.routingLabels()
+	labels["alertname"] = violation.Metric
+	labels["host"] = hostname
+	if labels["device"] == "" {
+		delete(labels, "device")
+	}
+
+	return AlertmanagerAlert{
+		Labels: labels,
+		Annotations: map[string]string{
+			"message": violation.Message,
+			"value":   fmt.Sprintf("%g", violation.Value),
+		},
+		StartsAt:     time.Unix(int64(state.FirstDetectedTime), 0).UTC(),
+		GeneratorURL: generatorURL,
+	}
+}
+
+// postAlertmanager posts alerts to an Alertmanager's v2 API
+func postAlertmanager(am AlertmanagerConfig, baseURL string, body []byte, timeout time.Duration) error {
+	endpoint := strings.TrimRight(baseURL, "/") + "/api/v2/alerts"
+	req, err := http.NewRequest(http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create alertmanager request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if am.Username != "" {
+		req.SetBasicAuth(os.ExpandEnv(am.Username), os.ExpandEnv(am.Password))
+	}
+
+	client := &http.Client{Timeout: timeout}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to post alerts to %s: %w", endpoint, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("alertmanager %s returned status %d", endpoint, resp.StatusCode)
+	}
+	return nil
+}
+
+// loadAlertmanagerStore reads the Alertmanager file, returning an empty store if it does not exist
+func loadAlertmanagerStore(path string) (*alertmanagerStore, error) {
+	store := &alertmanagerStore{Alerts: make(map[string]AlertmanagerAlert)}
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return store, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to read alertmanager file: %w", err)
+	}
+
+	if err := json.Unmarshal(data, store); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal alertmanager file: %w", err)
+	}
+	if store.Alerts == nil {
+		store.Alerts = make(map[string]AlertmanagerAlert)
+	}
+	return store, nil
+}
+
+// save atomically writes the Alertmanager store
+func (s *alertmanagerStore) save(path string) error {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal alertmanager alerts: %w", err)
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("failed to write alertmanager file: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("failed to write alertmanager file: %w", err)
+	}
+	return nil
+}
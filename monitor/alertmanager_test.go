@@ -0,0 +1,183 @@
+package monitor
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+// newAlertmanagerServer starts a stand-in for the Alertmanager v2 API that records the
+// posted alert lists; status is the response status code
+func newAlertmanagerServer(t *testing.T, status *int) (*httptest.Server, func() [][]AlertmanagerAlert) {
+	t.Helper()
+	var mu sync.Mutex
+	var posts [][]AlertmanagerAlert
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/api/v2/alerts" {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+		if user, pass, _ := r.BasicAuth(); user != "monitor" || pass != "secret" {
+			t.Errorf("basic auth = %s:%s", user, pass)
+		}
+		var alerts []AlertmanagerAlert
+		if err := json.NewDecoder(r.Body).Decode(&alerts); err != nil {
+			t.Errorf("invalid alerts: %v", err)
+		}
+		mu.Lock()
+		posts = append(posts, alerts)
+		mu.Unlock()
+		w.WriteHeader(*status)
+	}))
+	t.Cleanup(server.Close)
+	return server, func() [][]AlertmanagerAlert {
+		mu.Lock()
+		defer mu.Unlock()
+		return posts
+	}
+}
+
+// newAlertmanagerTest returns a config pushing to url and a state manager with an active
+// disk violation and a pending cpu violation
+func newAlertmanagerTest(t *testing.T, url string) (*Config, *StateManager) {
+	t.Helper()
+	config := DefaultConfig()
+	cpu := config.Metrics["cpu"]
+	cpu.Throttle.MinDurationMinutes = 5
+	config.Metrics["cpu"] = cpu
+	config.Alertmanager = AlertmanagerConfig{
+		Enabled:  true,
+		URLs:     []string{url + "/"},
+		Username: "monitor",
+		Password: "secret",
+	}
+
+	sm := &StateManager{
+		AlertmanagerFile: filepath.Join(t.TempDir(), "alertmanager.json"),
+		States:           make(map[string]*ViolationState),
+	}
+	disk := sm.GetOrCreateFor(chatTestViolation)
+	disk.Update(chatTestViolation)
+	disk.FirstDetectedTime = float64(time.Now().Add(-10 * time.Minute).Unix())
+
+	cpuViolation := ThresholdViolation{Metric: "cpu", Level: "warning", Message: "cpu usage is 75%", Value: 75}
+	sm.GetOrCreateFor(cpuViolation).Update(cpuViolation)
+	return config, sm
+}
+
+// TestPushAlertmanager tests posting, re-posting and resolving alerts
+func TestPushAlertmanager(t *testing.T) {
+	status := http.StatusOK
+	server, posts := newAlertmanagerServer(t, &status)
+	config, sm := newAlertmanagerTest(t, server.URL)
+	now := time.Now()
+
+	if err := PushAlertmanager(config, sm, now); err != nil {
+		t.Fatalf("PushAlertmanager() error = %v", err)
+	}
+	if len(posts()) != 1 || len(posts()[0]) != 1 {
+		t.Fatalf("posts = %v, want the disk alert only (cpu is pending)", posts())
+	}
+	alert := posts()[0][0]
+	for name, want := range map[string]string{"alertname": "disk", "metric": "disk", "level": "critical", "mountpoint": "/"} {
+		if alert.Labels[name] != want {
+			t.Errorf("label %s = %q, want %q", name, alert.Labels[name], want)
+		}
+	}
+	if alert.Labels["host"] == "" {
+		t.Error("missing host label")
+	}
+	if alert.Annotations["message"] != chatTestViolation.Message || alert.Annotations["value"] != "95" {
+		t.Errorf("annotations = %v", alert.Annotations)
+	}
+	if time.Since(alert.StartsAt) < 9*time.Minute {
+		t.Errorf("startsAt = %v, want the first detection", alert.StartsAt)
+	}
+	if alert.EndsAt.Sub(now) < 14*time.Minute {
+		t.Errorf("endsAt = %v, want resolve_timeout ahead", alert.EndsAt)
+	}
+
+	// Nothing changed and the repost interval has not passed
+	if err := PushAlertmanager(config, sm, now.Add(10*time.Second)); err != nil {
+		t.Fatalf("PushAlertmanager() error = %v", err)
+	}
+	if len(posts()) != 1 {
+		t.Fatalf("got %d posts, want no re-post within the interval", len(posts()))
+	}
+
+	// Active alerts are re-posted after the interval
+	if err := PushAlertmanager(config, sm, now.Add(2*time.Minute)); err != nil {
+		t.Fatalf("PushAlertmanager() error = %v", err)
+	}
+	if len(posts()) != 2 {
+		t.Fatalf("got %d posts, want a re-post", len(posts()))
+	}
+
+	// Cleared violations are resolved right away
+	delete(sm.States, chatTestViolation.StateKey())
+	resolvedAt := now.Add(150 * time.Second)
+	if err := PushAlertmanager(config, sm, resolvedAt); err != nil {
+		t.Fatalf("PushAlertmanager() error = %v", err)
+	}
+	if len(posts()) != 3 || len(posts()[2]) != 1 || !posts()[2][0].EndsAt.Equal(resolvedAt) {
+		t.Fatalf("resolve post = %v, want disk alert ending at %v", posts()[len(posts())-1], resolvedAt)
+	}
+
+	// Resolved alerts are sent once
+	if err := PushAlertmanager(config, sm, resolvedAt.Add(time.Second)); err != nil {
+		t.Fatalf("PushAlertmanager() error = %v", err)
+	}
+	if len(posts()) != 3 {
+		t.Errorf("got %d posts, want no post without alerts", len(posts()))
+	}
+}
+
+// TestPushAlertmanagerFailure tests that resolved alerts are sent again after a failed post
+func TestPushAlertmanagerFailure(t *testing.T) {
+	status := http.StatusOK
+	server, posts := newAlertmanagerServer(t, &status)
+	config, sm := newAlertmanagerTest(t, server.URL)
+	now := time.Now()
+
+	if err := PushAlertmanager(config, sm, now); err != nil {
+		t.Fatalf("PushAlertmanager() error = %v", err)
+	}
+
+	delete(sm.States, chatTestViolation.StateKey())
+	status = http.StatusServiceUnavailable
+	if err := PushAlertmanager(config, sm, now.Add(time.Second)); err == nil {
+		t.Fatal("PushAlertmanager() error = nil, want failure")
+	}
+
+	status = http.StatusOK
+	if err := PushAlertmanager(config, sm, now.Add(2*time.Second)); err != nil {
+		t.Fatalf("PushAlertmanager() error = %v", err)
+	}
+	if got := posts(); len(got) != 3 || len(got[2]) != 1 || got[2][0].EndsAt.After(now.Add(2*time.Second)) {
+		t.Errorf("posts = %v, want the resolved alert sent again", got)
+	}
+}
+
+// TestAlertmanagerConfigValidation tests Alertmanager settings validation
+func TestAlertmanagerConfigValidation(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  AlertmanagerConfig
+		wantErr bool
+	}{
+		{"valid", AlertmanagerConfig{Enabled: true, URLs: []string{"http://am:9093"}}, false},
+		{"disabled", AlertmanagerConfig{RepostInterval: "bad"}, false},
+		{"missing urls", AlertmanagerConfig{Enabled: true}, true},
+		{"bad url", AlertmanagerConfig{Enabled: true, URLs: []string{"am:9093"}}, true},
+		{"bad duration", AlertmanagerConfig{Enabled: true, URLs: []string{"http://am"}, Timeout: "soon"}, true},
+		{"resolve before repost", AlertmanagerConfig{Enabled: true, URLs: []string{"http://am"}, RepostInterval: "10m", ResolveTimeout: "5m"}, true},
+	}
+	for _, tt := range tests {
+		if err := tt.config.validate(); (err != nil) != tt.wantErr {
+			t.Errorf("%s: validate() error = %v, wantErr %v", tt.name, err, tt.wantErr)
+		}
+	}
+}
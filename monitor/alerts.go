@@ -2,11 +2,14 @@ package monitor
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"log/syslog"
 	"net/http"
+	"net/url"
 	"os/exec"
 	"strings"
 	"time"
@@ -17,18 +20,156 @@ type AlertAction interface {
 	Execute(violation ThresholdViolation) error
 }
 
+// BatchAlertAction is implemented by actions that deliver all violations of a cycle at once
+type BatchAlertAction interface {
+	AlertAction
+	ExecuteBatch(violations []ThresholdViolation) error
+}
+
+// actionNumber reads a numeric action field, which YAML decodes as int or float64
+func actionNumber(config map[string]interface{}, key string) (float64, bool) {
+	switch v := config[key].(type) {
+	case int:
+		return float64(v), true
+	case float64:
+		return v, true
+	}
+	return 0, false
+}
+
+// actionStrings reads an action field that is either a single string or a list of strings
+func actionStrings(config map[string]interface{}, key string) []string {
+	switch v := config[key].(type) {
+	case string:
+		if v != "" {
+			return []string{v}
+		}
+	case []interface{}:
+		var values []string
+		for _, item := range v {
+			if str, ok := item.(string); ok {
+				values = append(values, str)
+			}
+		}
+		return values
+	case []string:
+		return v
+	}
+	return nil
+}
+
+// actionStringMap reads a map of strings from an action config; YAML decodes maps with interface{} keys
+func actionStringMap(config map[string]interface{}, key string) (map[string]string, error) {
+	values := make(map[string]string)
+	switch v := config[key].(type) {
+	case nil:
+	case map[string]string:
+		for k, val := range v {
+			values[k] = val
+		}
+	case map[string]interface{}:
+		for k, val := range v {
+			str, ok := val.(string)
+			if !ok {
+				return nil, fmt.Errorf("'%s' value of '%s' must be a string", key, k)
+			}
+			values[k] = str
+		}
+	case map[interface{}]interface{}:
+		for k, val := range v {
+			name, ok := k.(string)
+			str, ok2 := val.(string)
+			if !ok || !ok2 {
+				return nil, fmt.Errorf("'%s' entries must be strings", key)
+			}
+			values[name] = str
+		}
+	default:
+		return nil, fmt.Errorf("'%s' must be a map", key)
+	}
+	return values, nil
+}
+
 // LoggerAction sends alerts using system logger command
 type LoggerAction struct {
-	Level string
+	Level string // Syslog priority name; the violation's level if empty
 	Tag   string
 	ID    string
 }
 
+// loggerPriorities maps priority names to the names the logger command accepts
+var loggerPriorities = map[string]string{
+	"emergency": "emerg",
+	"alert":     "alert",
+	"critical":  "crit",
+	"error":     "err",
+	"warning":   "warning",
+	"notice":    "notice",
+	"info":      "info",
+	"debug":     "debug",
+}
+
+// NewLoggerAction creates a new logger alert action
+func NewLoggerAction(config map[string]interface{}) (*LoggerAction, error) {
+	la := &LoggerAction{
+		Tag: "ALERT",
+		ID:  "451",
+	}
+
+	if level, ok := config["level"].(string); ok {
+		if _, exists := loggerPriorities[level]; !exists {
+			return nil, fmt.Errorf("invalid logger level '%s'", level)
+		}
+		la.Level = level
+	}
+
+	if tag, ok := config["tag"].(string); ok {
+		la.Tag = tag
+	}
+
+	switch id := config["id"].(type) {
+	case nil:
+	case string:
+		la.ID = id
+	default:
+		n, ok := actionNumber(config, "id")
+		if !ok {
+			return nil, fmt.Errorf("logger 'id' must be a string or number")
+		}
+		la.ID = fmt.Sprintf("%d", int64(n))
+	}
+
+	return la, nil
+}
+
+// args returns the logger command arguments for a violation
+func (la *LoggerAction) args(violation ThresholdViolation) []string {
+	level := la.Level
+	if level == "" {
+		level = violation.Level
+	}
+	priority, ok := loggerPriorities[level]
+	if !ok {
+		priority = "notice"
+	}
+
+	message := fmt.Sprintf("[%s] %s: %s", strings.ToUpper(violation.Level), violation.Metric, violation.Message)
+	args := []string{"-e", "-p", "user." + priority}
+	if la.Tag != "" {
+		args = append(args, "-t", la.Tag)
+	}
+	if la.ID != "" {
+		args = append(args, fmt.Sprintf("--id=%s", la.ID))
+	}
+	return append(args, "-s", message)
+}
+
 // Execute sends alert using logger command
 func (la *LoggerAction) Execute(violation ThresholdViolation) error {
-	message := fmt.Sprintf("[%s] %s: %s", strings.ToUpper(violation.Level), violation.Metric, violation.Message)
+	args := la.args(violation)
+	message := args[len(args)-1]
 
-	cmd := exec.Command("logger", "-e", "-t", la.Tag, fmt.Sprintf("--id=%s", la.ID), "-s", message)
+	cmd := exec.Command("logger", args...)
 	if err := cmd.Run(); err != nil {
 		return fmt.Errorf("failed to send logger alert: %w", err)
 	}
@@ -125,73 +266,114 @@ func (sa *SyslogAction) Execute(violation ThresholdViolation) error {
 	return nil
 }
 
-// WebhookAction sends alerts via HTTP webhook
-type WebhookAction struct {
+// jsonWebhook posts JSON payloads to an HTTP endpoint, retrying on failure
+type jsonWebhook struct {
+	Name    string
 	URL     string
+	Headers map[string]string // Sent with every request, e.g. for authentication
 	Timeout time.Duration
 	Retry   int
+
+	ctx context.Context // cancels requests, see setContext
 }
 
-// NewWebhookAction creates a new webhook alert action
-func NewWebhookAction(config map[string]interface{}) (*WebhookAction, error) {
-	wa := &WebhookAction{
+// newJSONWebhook reads the url, timeout and retry settings shared by JSON-posting actions
+func newJSONWebhook(actionType string, config map[string]interface{}) (jsonWebhook, error) {
+	jw := jsonWebhook{
+		Name:    actionType,
 		Timeout: 5 * time.Second,
 		Retry:   1,
 	}
 
 	if url, ok := config["url"].(string); ok {
-		wa.URL = url
+		jw.URL = url
 	} else {
-		return nil, fmt.Errorf("webhook action requires 'url' field")
+		return jw, fmt.Errorf("%s action requires 'url' field", actionType)
 	}
 
-	if timeout, ok := config["timeout"].(float64); ok {
-		wa.Timeout = time.Duration(timeout) * time.Second
+	if timeout, ok := actionNumber(config, "timeout"); ok {
+		jw.Timeout = time.Duration(timeout) * time.Second
 	}
 
-	if retry, ok := config["retry"].(float64); ok {
-		wa.Retry = int(retry)
+	if retry, ok := actionNumber(config, "retry"); ok {
+		jw.Retry = int(retry)
 	}
 
-	return wa, nil
+	return jw, nil
 }
 
-// Execute sends alert via webhook
-func (wa *WebhookAction) Execute(violation ThresholdViolation) error {
-	payload := map[string]interface{}{
-		"metric":  violation.Metric,
-		"level":   violation.Level,
-		"message": violation.Message,
-		"value":   violation.Value,
+// withDefaultURL returns the action config with url set to def if it is not configured,
+// so that service endpoints can be overridden (e.g., for a proxy or tests)
+func withDefaultURL(config map[string]interface{}, def string) map[string]interface{} {
+	if _, ok := config["url"]; ok {
+		return config
+	}
+	withURL := make(map[string]interface{}, len(config)+1)
+	for k, v := range config {
+		withURL[k] = v
 	}
+	withURL["url"] = def
+	return withURL
+}
 
+// setContext makes requests stop when ctx is cancelled
+func (jw *jsonWebhook) setContext(ctx context.Context) {
+	jw.ctx = ctx
+}
+
+// post sends a payload, retrying on failure
+func (jw jsonWebhook) post(payload interface{}) error {
+	return jw.send(http.MethodPost, jw.URL, payload, nil)
+}
+
+// send sends a payload with the given method and URL, retrying on failure. If result is
+// not nil, the JSON response of a successful request is decoded into it.
+func (jw jsonWebhook) send(method string, endpoint string, payload interface{}, result interface{}) error {
 	jsonData, err := json.Marshal(payload)
 	if err != nil {
-		return fmt.Errorf("failed to marshal webhook payload: %w", err)
+		return fmt.Errorf("failed to marshal %s payload: %w", jw.Name, err)
 	}
 
 	var lastError error
-	for attempt := 0; attempt < wa.Retry; attempt++ {
-		client := &http.Client{Timeout: wa.Timeout}
-		resp, err := client.Post(wa.URL, "application/json", bytes.NewReader(jsonData))
+	for attempt := 0; attempt < jw.Retry; attempt++ {
+		req, err := http.NewRequestWithContext(actionContext(jw.ctx), method, endpoint, bytes.NewReader(jsonData))
 		if err != nil {
+			return fmt.Errorf("failed to create %s request: %w", jw.Name, err)
+		}
+		req.Header.Set("Content-Type", "application/json")
+		for name, value := range jw.Headers {
+			req.Header.Set(name, value)
+		}
+
+		client := &http.Client{Timeout: jw.Timeout}
+		resp, err := client.Do(req)
+		if err != nil {
+			// Leave the URL out of the error; it may contain a token (e.g. Telegram, Slack)
+			var urlErr *url.Error
+			if errors.As(err, &urlErr) {
+				err = fmt.Errorf("%s: %w", urlErr.Op, urlErr.Err)
+			}
 			lastError = err
-			log.Printf("Webhook alert failed (attempt %d/%d): %v", attempt+1, wa.Retry, err)
+			log.Printf("%s alert failed (attempt %d/%d): %v", jw.Name, attempt+1, jw.Retry, err)
 			continue
 		}
 
 		if resp.StatusCode >= 200 && resp.StatusCode < 300 {
-			log.Printf("Webhook alert sent to %s: %v", wa.URL, payload)
-			resp.Body.Close()
+			defer resp.Body.Close()
+			if result != nil {
+				if err := json.NewDecoder(resp.Body).Decode(result); err != nil {
+					return fmt.Errorf("failed to decode %s response: %w", jw.Name, err)
+				}
+			}
 			return nil
 		}
-
 		resp.Body.Close()
-		lastError = fmt.Errorf("webhook returned status %d", resp.StatusCode)
-		log.Printf("Webhook alert failed (attempt %d/%d): %v", attempt+1, wa.Retry, lastError)
+
+		lastError = fmt.Errorf("%s endpoint returned status %d", jw.Name, resp.StatusCode)
+		log.Printf("%s alert failed (attempt %d/%d): %v", jw.Name, attempt+1, jw.Retry, lastError)
 	}
 
-	return fmt.Errorf("failed to send webhook alert after %d attempts: %w", wa.Retry, lastError)
+	return fmt.Errorf("failed to send %s alert after %d attempts: %w", jw.Name, jw.Retry, lastError)
 }
 
 // StdoutAction prints alerts to stdout
@@ -205,63 +387,36 @@ func (sa *StdoutAction) Execute(violation ThresholdViolation) error {
 	return nil
 }
 
-// ScriptAction executes external script for alert
-type ScriptAction struct {
-	Path    string
-	Args    []string
-	Timeout time.Duration
-}
-
-// NewScriptAction creates a new script alert action
-func NewScriptAction(config map[string]interface{}) (*ScriptAction, error) {
-	sa := &ScriptAction{
-		Timeout: 30 * time.Second,
-	}
-
-	if path, ok := config["path"].(string); ok {
-		sa.Path = path
-	} else {
-		return nil, fmt.Errorf("script action requires 'path' field")
-	}
-
-	if args, ok := config["args"].([]interface{}); ok {
-		for _, arg := range args {
-			if argStr, ok := arg.(string); ok {
-				sa.Args = append(sa.Args, argStr)
-			}
-		}
-	}
-
-	if timeout, ok := config["timeout"].(float64); ok {
-		sa.Timeout = time.Duration(timeout) * time.Second
-	}
-
-	return sa, nil
-}
-
-// Execute executes alert script
-func (sa *ScriptAction) Execute(violation ThresholdViolation) error {
-	args := sa.Args
-	args = append(args, violation.Metric, violation.Level, violation.Message)
-
-	cmd := exec.Command(sa.Path, args...)
-
-	done := make(chan error, 1)
-	go func() {
-		done <- cmd.Run()
-	}()
-
-	select {
-	case err := <-done:
-		if err != nil {
-			return fmt.Errorf("script alert failed: %w", err)
-		}
-		log.Printf("Script alert executed: %s", sa.Path)
-		return nil
-	case <-time.After(sa.Timeout):
-		cmd.Process.Kill()
-		return fmt.Errorf("script alert timed out after %v", sa.Timeout)
-	}
+// actionFields lists the config fields each action type accepts besides "type" and "level"
+var actionFields = map[string][]string{
+	"logger":  {"tag", "id"},
+	"syslog":  {"tag", "facility", "priority"},
+	"webhook": {"url", "method", "encoding", "content_type", "headers", "body", "secret", "timeout", "retry"},
+	"script":  {"path", "args", "timeout", "workdir", "env"},
+	"stdout":  {},
+	"email": {
+		"host", "port", "tls", "insecure_skip_verify", "username", "password",
+		"from", "to", "cc", "subject", "body", "timeout",
+	},
+	"slack":      {"url", "timeout", "retry", "channel", "username"},
+	"mattermost": {"url", "timeout", "retry", "channel", "username"},
+	"teams":      {"url", "timeout", "retry"},
+	"pagerduty":  {"routing_key", "url", "source", "timeout", "retry"},
+	"ntfy":       {"url", "topic", "token", "priority", "tags", "timeout", "retry"},
+	"gotify":     {"url", "token", "priority", "timeout", "retry"},
+	"pushover":   {"url", "token", "user", "priority", "sound", "timeout", "retry"},
+	"telegram":   {"url", "token", "chat_id", "parse_mode", "template", "resolved_template", "resolved", "timeout", "retry"},
+	"matrix":     {"homeserver", "token", "room_id", "template", "resolved_template", "resolved", "timeout", "retry"},
+	"mqtt": {
+		"broker", "topic", "qos", "retain", "client_id", "username", "password",
+		"ca_file", "insecure_skip_verify", "timeout",
+	},
+	"file":     {"path", "max_size", "max_files"},
+	"journald": {"socket", "tag", "priority", "fields"},
+	"snmptrap": {
+		"host", "port", "version", "community", "oid", "timeout", "username",
+		"auth_protocol", "auth_password", "priv_protocol", "priv_password", "engine_id",
+	},
 }
 
 // CreateAction creates appropriate alert action based on config
@@ -273,15 +428,7 @@ func CreateAction(config map[string]interface{}) (AlertAction, error) {
 
 	switch actionType {
 	case "logger":
-		level := "warning"
-		if l, ok := config["level"].(string); ok {
-			level = l
-		}
-		return &LoggerAction{
-			Level: level,
-			Tag:   "ALERT",
-			ID:    "451",
-		}, nil
+		return NewLoggerAction(config)
 	case "syslog":
 		return NewSyslogAction(config)
 	case "webhook":
@@ -290,46 +437,122 @@ func CreateAction(config map[string]interface{}) (AlertAction, error) {
 		return NewScriptAction(config)
 	case "stdout":
 		return &StdoutAction{}, nil
+	case "email":
+		return NewEmailAction(config)
+	case "slack":
+		return NewSlackAction(config)
+	case "mattermost":
+		return NewMattermostAction(config)
+	case "teams":
+		return NewTeamsAction(config)
+	case "pagerduty":
+		return NewPagerDutyAction(config)
+	case "ntfy":
+		return NewNtfyAction(config)
+	case "gotify":
+		return NewGotifyAction(config)
+	case "pushover":
+		return NewPushoverAction(config)
+	case "telegram":
+		return NewTelegramAction(config)
+	case "matrix":
+		return NewMatrixAction(config)
+	case "mqtt":
+		return NewMQTTAction(config)
+	case "file":
+		return NewFileAction(config)
+	case "journald":
+		return NewJournaldAction(config)
+	case "snmptrap":
+		return NewSNMPTrapAction(config)
 	default:
 		return nil, fmt.Errorf("unknown alert action type: %s", actionType)
 	}
 }
 
-// ProcessViolations executes configured alert actions for violations
+// ProcessViolations executes configured alert actions for violations.
+// Every action runs even if others fail; the errors of all failed actions are returned.
 func ProcessViolations(config *Config, warningViolations []ThresholdViolation, criticalViolations []ThresholdViolation) error {
-	// Process critical violations
-	if len(criticalViolations) > 0 {
-		log.Printf("Processing %d critical violations", len(criticalViolations))
-		criticalActions := config.GetAlertActions("critical")
-		for _, actionConfig := range criticalActions {
-			action, err := CreateAction(actionConfig)
-			if err != nil {
-				return fmt.Errorf("failed to create critical alert action: %w", err)
-			}
-			for _, violation := range criticalViolations {
-				if err := action.Execute(violation); err != nil {
-					return fmt.Errorf("failed to execute critical alert: %w", err)
-				}
-			}
+	var errs []error
+	for _, result := range DispatchViolations(config, warningViolations, criticalViolations) {
+		if err := result.Err(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// ProcessResolved sends resolved updates through the actions that each violation is
+// routed to and that support them. Every action runs even if others fail; the errors of
+// all failed actions are returned.
+func ProcessResolved(config *Config, resolved []ThresholdViolation) error {
+	var errs []error
+	for _, result := range DispatchResolved(config, resolved) {
+		if err := result.Err(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// deliver executes the action of a job, or sends resolved updates through it for resolve
+// jobs. With a spool, each failed delivery is queued for retry instead of failing and
+// spooled is true; without one, the first error is returned.
+func deliver(spool *Spool, job dispatchJob, action AlertAction) (spooled bool, err error) {
+	if spool == nil {
+		return false, executeDelivery(action, job.violations, job.resolved)
+	}
+
+	// Spool non-batch actions per violation so successful deliveries are not repeated
+	batches := [][]ThresholdViolation{job.violations}
+	if _, ok := action.(BatchAlertAction); !ok || job.resolved {
+		batches = nil
+		for _, v := range job.violations {
+			batches = append(batches, []ThresholdViolation{v})
 		}
 	}
 
-	// Process warning violations
-	if len(warningViolations) > 0 {
-		log.Printf("Processing %d warning violations", len(warningViolations))
-		warningActions := config.GetAlertActions("warning")
-		for _, actionConfig := range warningActions {
-			action, err := CreateAction(actionConfig)
-			if err != nil {
-				return fmt.Errorf("failed to create warning alert action: %w", err)
+	for _, batch := range batches {
+		if err := executeDelivery(action, batch, job.resolved); err != nil {
+			if job.abandoned != nil && job.abandoned.Load() {
+				return spooled, err
 			}
-			for _, violation := range warningViolations {
-				if err := action.Execute(violation); err != nil {
-					return fmt.Errorf("failed to execute warning alert: %w", err)
-				}
+			log.Printf("Alert delivery failed, spooling for retry: %v", err)
+			if err := spool.Enqueue(job.spoolAction(), batch, job.resolved, err); err != nil {
+				return spooled, fmt.Errorf("failed to spool alert: %w", err)
 			}
+			spooled = true
+		}
+	}
+	return spooled, nil
+}
+
+// executeDelivery delivers violations or their resolved updates through an action
+func executeDelivery(action AlertAction, violations []ThresholdViolation, resolved bool) error {
+	if !resolved {
+		return executeAction(action, violations)
+	}
+	resolver, ok := action.(ResolvingAlertAction)
+	if !ok {
+		return fmt.Errorf("action does not support resolved updates")
+	}
+	for _, violation := range violations {
+		if err := resolver.Resolve(violation); err != nil {
+			return err
 		}
 	}
+	return nil
+}
 
+// executeAction delivers violations through an action, batching them if the action supports it
+func executeAction(action AlertAction, violations []ThresholdViolation) error {
+	if batch, ok := action.(BatchAlertAction); ok {
+		return batch.ExecuteBatch(violations)
+	}
+	for _, violation := range violations {
+		if err := action.Execute(violation); err != nil {
+			return err
+		}
+	}
 	return nil
 }
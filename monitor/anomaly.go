@@ -0,0 +1,357 @@
+package monitor
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/ziutek/rrd"
+)
+
+// Season names for anomaly baselines
+const (
+	SeasonHourOfDay  = "hour_of_day"
+	SeasonHourOfWeek = "hour_of_week"
+)
+
+// AnomalyConfig holds settings for the anomaly threshold mode
+type AnomalyConfig struct {
+	Season          string  `yaml:"season"`           // "hour_of_week" (default) or "hour_of_day"
+	HistoryDays     int     `yaml:"history_days"`     // Days of RRD history to learn from (default: 28)
+	MinSamples      int     `yaml:"min_samples"`      // Samples a bucket needs before it is evaluated (default: 6)
+	MinDeviation    float64 `yaml:"min_deviation"`    // Lower bound for the deviation, in metric units (default: 1)
+	Direction       string  `yaml:"direction"`        // "above" (default), "below" or "both"
+	RelearnInterval string  `yaml:"relearn_interval"` // How often the baseline is re-learned (default: "1h")
+}
+
+// withDefaults returns a copy of the anomaly config with unset fields defaulted
+func (ac AnomalyConfig) withDefaults() AnomalyConfig {
+	if ac.Season == "" {
+		ac.Season = SeasonHourOfWeek
+	}
+	if ac.HistoryDays <= 0 {
+		ac.HistoryDays = 28
+	}
+	if ac.MinSamples <= 0 {
+		ac.MinSamples = 6
+	}
+	if ac.MinDeviation <= 0 {
+		ac.MinDeviation = 1
+	}
+	if ac.Direction == "" {
+		ac.Direction = "above"
+	}
+	if ac.RelearnInterval == "" {
+		ac.RelearnInterval = "1h"
+	}
+	return ac
+}
+
+// Sample is a single timestamped metric value
+type Sample struct {
+	Time  time.Time
+	Value float64
+}
+
+// BaselineBucket accumulates mean and variance for one seasonal slot (Welford's algorithm)
+type BaselineBucket struct {
+	Count int     `json:"count"`
+	Mean  float64 `json:"mean"`
+	M2    float64 `json:"m2"`
+}
+
+// add folds a value into the bucket
+func (b *BaselineBucket) add(value float64) {
+	b.Count++
+	delta := value - b.Mean
+	b.Mean += delta / float64(b.Count)
+	b.M2 += delta * (value - b.Mean)
+}
+
+// StdDev returns the sample standard deviation of the bucket
+func (b *BaselineBucket) StdDev() float64 {
+	if b.Count < 2 {
+		return 0
+	}
+	return math.Sqrt(b.M2 / float64(b.Count-1))
+}
+
+// Baseline is a learned seasonal profile of a metric
+type Baseline struct {
+	Metric    string           `json:"metric"`
+	Season    string           `json:"season"`
+	LearnedAt time.Time        `json:"learned_at"`
+	Buckets   []BaselineBucket `json:"buckets"`
+}
+
+// NewBaseline creates an empty baseline for the given season
+func NewBaseline(metric string, season string) (*Baseline, error) {
+	var size int
+	switch season {
+	case SeasonHourOfDay:
+		size = 24
+	case SeasonHourOfWeek:
+		size = 7 * 24
+	default:
+		return nil, fmt.Errorf("unknown anomaly season '%s'", season)
+	}
+	return &Baseline{
+		Metric:  metric,
+		Season:  season,
+		Buckets: make([]BaselineBucket, size),
+	}, nil
+}
+
+// LearnBaseline builds a baseline from a series of samples, skipping NaN values
+func LearnBaseline(metric string, season string, samples []Sample) (*Baseline, error) {
+	baseline, err := NewBaseline(metric, season)
+	if err != nil {
+		return nil, err
+	}
+	for _, s := range samples {
+		baseline.Observe(s.Time, s.Value)
+	}
+	baseline.LearnedAt = time.Now()
+	return baseline, nil
+}
+
+// bucketIndex returns the seasonal slot for a point in time
+func (b *Baseline) bucketIndex(t time.Time) int {
+	t = t.Local()
+	if b.Season == SeasonHourOfDay {
+		return t.Hour()
+	}
+	return int(t.Weekday())*24 + t.Hour()
+}
+
+// Observe adds a value to the bucket for its time slot
+func (b *Baseline) Observe(t time.Time, value float64) {
+	if math.IsNaN(value) || math.IsInf(value, 0) {
+		return
+	}
+	b.Buckets[b.bucketIndex(t)].add(value)
+}
+
+// Expected returns the learned mean and deviation for a point in time.
+// ok is false when the slot has fewer than minSamples observations.
+func (b *Baseline) Expected(t time.Time, minSamples int) (mean float64, deviation float64, ok bool) {
+	bucket := b.Buckets[b.bucketIndex(t)]
+	if bucket.Count < minSamples {
+		return 0, 0, false
+	}
+	return bucket.Mean, bucket.StdDev(), true
+}
+
+// BaselineStore persists learned baselines so they survive restarts
+type BaselineStore struct {
+	Path      string
+	Baselines map[string]*Baseline
+}
+
+// NewBaselineStore creates a baseline store and loads any saved baselines
+func NewBaselineStore(path string) (*BaselineStore, error) {
+	bs := &BaselineStore{
+		Path:      path,
+		Baselines: make(map[string]*Baseline),
+	}
+	if err := bs.load(); err != nil {
+		return nil, err
+	}
+	return bs, nil
+}
+
+// Get returns the baseline for a metric
+func (bs *BaselineStore) Get(metric string) (*Baseline, bool) {
+	if bs == nil {
+		return nil, false
+	}
+	b, ok := bs.Baselines[metric]
+	return b, ok
+}
+
+// Save writes baselines to file
+func (bs *BaselineStore) Save() error {
+	if err := os.MkdirAll(filepath.Dir(bs.Path), 0755); err != nil {
+		return fmt.Errorf("failed to create baseline directory: %w", err)
+	}
+
+	jsonData, err := json.MarshalIndent(bs.Baselines, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal baselines: %w", err)
+	}
+
+	if err := os.WriteFile(bs.Path, jsonData, 0644); err != nil {
+		return fmt.Errorf("failed to write baseline file: %w", err)
+	}
+	return nil
+}
+
+// load reads baselines from file
+func (bs *BaselineStore) load() error {
+	data, err := os.ReadFile(bs.Path)
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return fmt.Errorf("failed to read baseline file: %w", err)
+	}
+
+	var baselines map[string]*Baseline
+	if err := json.Unmarshal(data, &baselines); err != nil {
+		return fmt.Errorf("failed to unmarshal baselines: %w", err)
+	}
+	if baselines != nil {
+		bs.Baselines = baselines
+	}
+	return nil
+}
+
+// Refresh re-learns baselines from RRD history for metrics in anomaly mode once they are stale.
+// A metric whose history cannot be read or learned keeps its previous baseline (or none),
+// and the remaining metrics are still refreshed.
+func (bs *BaselineStore) Refresh(config *Config, recorder *Recorder) error {
+	changed := false
+	var errs []error
+	for _, metric := range []string{"cpu", "memory"} {
+		metricConfig, ok := config.GetMetricConfig(metric)
+		if !ok || !metricConfig.Enabled || metricConfig.Mode != "anomaly" {
+			continue
+		}
+		anomaly := metricConfig.Anomaly.withDefaults()
+
+		interval, err := parseDuration(anomaly.RelearnInterval)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("invalid relearn_interval for %s: %w", metric, err))
+			continue
+		}
+		if b, ok := bs.Baselines[metric]; ok && b.Season == anomaly.Season && time.Since(b.LearnedAt) < interval {
+			continue
+		}
+
+		end := time.Now()
+		start := end.Add(-time.Duration(anomaly.HistoryDays) * 24 * time.Hour)
+		samples, err := recorder.FetchHistory(metric, start, end)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to fetch history for %s: %w", metric, err))
+			continue
+		}
+
+		baseline, err := LearnBaseline(metric, anomaly.Season, samples)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		bs.Baselines[metric] = baseline
+		changed = true
+		log.Printf("Learned %s baseline for %s from %d samples", anomaly.Season, metric, len(samples))
+	}
+
+	if changed {
+		if err := bs.Save(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// FetchHistory reads averaged samples for a metric from its RRD file
+func (r *Recorder) FetchHistory(metric string, start, end time.Time) ([]Sample, error) {
+	result, err := rrd.Fetch(r.GetRRDPath(metric), "AVERAGE", start, end, 5*time.Minute)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch RRD data for %s: %w", metric, err)
+	}
+	defer result.FreeValues()
+
+	samples := make([]Sample, 0, result.RowCnt)
+	for i := 0; i < result.RowCnt; i++ {
+		value := result.ValueAt(0, i)
+		if math.IsNaN(value) {
+			continue
+		}
+		samples = append(samples, Sample{
+			Time:  result.Start.Add(time.Duration(i+1) * result.Step),
+			Value: value,
+		})
+	}
+	return samples, nil
+}
+
+// checkAnomalyThresholds flags values that are more than k deviations away from the learned baseline
+func checkAnomalyThresholds(config *Config, baselines *BaselineStore, values map[string]float64, now time.Time) []ThresholdViolation {
+	var violations []ThresholdViolation
+
+	for _, metric := range []string{"cpu", "memory"} {
+		metricConfig, ok := config.GetMetricConfig(metric)
+		if !ok || !metricConfig.Enabled || metricConfig.Mode != "anomaly" {
+			continue
+		}
+		value, ok := values[metric]
+		if !ok {
+			continue
+		}
+
+		baseline, ok := baselines.Get(metric)
+		if !ok {
+			log.Printf("Anomaly: no baseline learned for %s yet", metric)
+			continue
+		}
+
+		anomaly := metricConfig.Anomaly.withDefaults()
+		mean, deviation, ok := baseline.Expected(now, anomaly.MinSamples)
+		if !ok {
+			log.Printf("Anomaly: not enough samples in %s baseline for %s", metric, now.Format("Mon 15h"))
+			continue
+		}
+		if deviation < anomaly.MinDeviation {
+			deviation = anomaly.MinDeviation
+		}
+
+		score := (value - mean) / deviation
+		switch anomaly.Direction {
+		case "below":
+			score = -score
+		case "both":
+			score = math.Abs(score)
+		}
+
+		direction := "above"
+		if value < mean {
+			direction = "below"
+		}
+
+		warningThreshold := metricConfig.Thresholds["warning"]
+		criticalThreshold := metricConfig.Thresholds["critical"]
+
+		var level string
+		var threshold float64
+		if criticalThreshold > 0 && score > criticalThreshold {
+			level, threshold = "critical", criticalThreshold
+		} else if warningThreshold > 0 && score > warningThreshold {
+			level, threshold = "warning", warningThreshold
+		} else {
+			continue
+		}
+
+		// The crossed value lies the threshold's deviations away from the baseline
+		boundary := mean + threshold*deviation
+		if direction == "below" {
+			boundary = mean - threshold*deviation
+		}
+
+		message := fmt.Sprintf("%s usage: %.2f%% is %.1f deviations %s baseline %.2f%% ±%.2f (%s threshold: %.1f deviations)",
+			metric, value, math.Abs(value-mean)/deviation, direction, mean, deviation, level, threshold)
+		violations = append(violations, ThresholdViolation{
+			Metric:    metric,
+			Level:     level,
+			Message:   message,
+			Value:     value,
+			Threshold: boundary,
+		})
+	}
+
+	return violations
+}
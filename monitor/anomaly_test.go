@@ -0,0 +1,266 @@
+package monitor
+
+import (
+	"math"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// syntheticDailySeries builds a 5-minute series with a daily load cycle and small noise
+func syntheticDailySeries(start time.Time, days int) []Sample {
+	var samples []Sample
+	for t := start; t.Before(start.Add(time.Duration(days) * 24 * time.Hour)); t = t.Add(5 * time.Minute) {
+		hour := float64(t.Hour()) + float64(t.Minute())/60
+		daily := 40 + 30*math.Sin(2*math.Pi*(hour-6)/24) // peaks at 12:00, lowest at 00:00
+		noise := 2 * math.Sin(float64(t.Unix()/300))
+		samples = append(samples, Sample{Time: t, Value: daily + noise})
+	}
+	return samples
+}
+
+func anomalyTestConfig(direction string) *Config {
+	return &Config{
+		Metrics: map[string]MetricConfig{
+			"cpu": {
+				Enabled: true,
+				Mode:    "anomaly",
+				Thresholds: map[string]float64{
+					"warning":  3,
+					"critical": 6,
+				},
+				Anomaly: AnomalyConfig{
+					Season:    SeasonHourOfDay,
+					Direction: direction,
+				},
+			},
+		},
+	}
+}
+
+// TestLearnBaseline tests learning a seasonal baseline from a synthetic series
+func TestLearnBaseline(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.Local)
+	baseline, err := LearnBaseline("cpu", SeasonHourOfDay, syntheticDailySeries(start, 14))
+	if err != nil {
+		t.Fatalf("LearnBaseline() error = %v", err)
+	}
+
+	noon := time.Date(2024, 2, 1, 12, 30, 0, 0, time.Local)
+	mean, dev, ok := baseline.Expected(noon, 6)
+	if !ok {
+		t.Fatalf("Expected() ok = false for populated bucket")
+	}
+	if mean < 60 || mean > 72 {
+		t.Errorf("noon mean = %.2f, want around 70", mean)
+	}
+	if dev <= 0 || dev > 5 {
+		t.Errorf("noon deviation = %.2f, want small positive value", dev)
+	}
+
+	midnight := time.Date(2024, 2, 1, 0, 30, 0, 0, time.Local)
+	mean, _, _ = baseline.Expected(midnight, 6)
+	if mean > 20 {
+		t.Errorf("midnight mean = %.2f, want around 10", mean)
+	}
+}
+
+// TestLearnBaselineSkipsNaN tests that unknown RRD values are ignored
+func TestLearnBaselineSkipsNaN(t *testing.T) {
+	now := time.Date(2024, 1, 1, 10, 0, 0, 0, time.Local)
+	samples := []Sample{
+		{Time: now, Value: math.NaN()},
+		{Time: now, Value: 50},
+	}
+
+	baseline, err := LearnBaseline("cpu", SeasonHourOfWeek, samples)
+	if err != nil {
+		t.Fatalf("LearnBaseline() error = %v", err)
+	}
+	if _, _, ok := baseline.Expected(now, 2); ok {
+		t.Errorf("Expected() ok = true with one real sample and min 2")
+	}
+	if mean, _, ok := baseline.Expected(now, 1); !ok || mean != 50 {
+		t.Errorf("Expected() = %.2f, %v, want 50, true", mean, ok)
+	}
+}
+
+// TestNewBaselineInvalidSeason tests rejecting unknown seasons
+func TestNewBaselineInvalidSeason(t *testing.T) {
+	if _, err := NewBaseline("cpu", "monthly"); err == nil {
+		t.Errorf("NewBaseline() expected error for unknown season")
+	}
+}
+
+// TestCheckAnomalyThresholds tests flagging values k deviations away from the baseline
+func TestCheckAnomalyThresholds(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.Local)
+	baseline, err := LearnBaseline("cpu", SeasonHourOfDay, syntheticDailySeries(start, 14))
+	if err != nil {
+		t.Fatalf("LearnBaseline() error = %v", err)
+	}
+	store := &BaselineStore{Baselines: map[string]*Baseline{"cpu": baseline}}
+
+	noon := time.Date(2024, 2, 1, 12, 0, 0, 0, time.Local)
+	midnight := time.Date(2024, 2, 1, 0, 0, 0, 0, time.Local)
+
+	tests := []struct {
+		name      string
+		direction string
+		now       time.Time
+		value     float64
+		wantLevel string
+	}{
+		{name: "high load at noon is normal", direction: "above", now: noon, value: 70},
+		{name: "high load at midnight is critical", direction: "above", now: midnight, value: 70, wantLevel: "critical"},
+		{name: "drop at noon ignored when direction is above", direction: "above", now: noon, value: 5},
+		{name: "drop at noon flagged when direction is both", direction: "both", now: noon, value: 5, wantLevel: "critical"},
+		{name: "drop at noon flagged when direction is below", direction: "below", now: noon, value: 5, wantLevel: "critical"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := anomalyTestConfig(tt.direction)
+			violations := checkAnomalyThresholds(config, store, map[string]float64{"cpu": tt.value}, tt.now)
+
+			if tt.wantLevel == "" {
+				if len(violations) != 0 {
+					t.Errorf("checkAnomalyThresholds() got %d violations, want 0: %v", len(violations), violations)
+				}
+				return
+			}
+			if len(violations) != 1 {
+				t.Fatalf("checkAnomalyThresholds() got %d violations, want 1", len(violations))
+			}
+			if violations[0].Level != tt.wantLevel {
+				t.Errorf("checkAnomalyThresholds() level = %s, want %s", violations[0].Level, tt.wantLevel)
+			}
+			if violations[0].Metric != "cpu" {
+				t.Errorf("checkAnomalyThresholds() metric = %s, want cpu", violations[0].Metric)
+			}
+
+			// The threshold is the crossed value, k deviations from the baseline
+			anomaly := config.Metrics["cpu"].Anomaly.withDefaults()
+			mean, deviation, _ := baseline.Expected(tt.now, anomaly.MinSamples)
+			deviation = math.Max(deviation, anomaly.MinDeviation)
+			k := config.Metrics["cpu"].Thresholds[tt.wantLevel]
+			want := mean + k*deviation
+			if tt.value < mean {
+				want = mean - k*deviation
+			}
+			if math.Abs(violations[0].Threshold-want) > 1e-9 {
+				t.Errorf("checkAnomalyThresholds() threshold = %.4f, want %.4f", violations[0].Threshold, want)
+			}
+		})
+	}
+}
+
+// TestCheckAnomalyThresholdsWithoutBaseline tests that missing baselines never alert
+func TestCheckAnomalyThresholdsWithoutBaseline(t *testing.T) {
+	config := anomalyTestConfig("above")
+	now := time.Now()
+
+	if v := checkAnomalyThresholds(config, nil, map[string]float64{"cpu": 99}, now); len(v) != 0 {
+		t.Errorf("checkAnomalyThresholds() with nil store got %d violations, want 0", len(v))
+	}
+
+	empty, _ := NewBaseline("cpu", SeasonHourOfDay)
+	store := &BaselineStore{Baselines: map[string]*Baseline{"cpu": empty}}
+	if v := checkAnomalyThresholds(config, store, map[string]float64{"cpu": 99}, now); len(v) != 0 {
+		t.Errorf("checkAnomalyThresholds() with empty baseline got %d violations, want 0", len(v))
+	}
+}
+
+// TestStaticChecksSkipAnomalyMode tests that static thresholds are not applied in anomaly mode
+func TestStaticChecksSkipAnomalyMode(t *testing.T) {
+	config := anomalyTestConfig("above")
+	if v := checkCPUThresholds(config, 99); len(v) != 0 {
+		t.Errorf("checkCPUThresholds() in anomaly mode got %d violations, want 0", len(v))
+	}
+}
+
+// TestBaselineStorePersistence tests saving and loading baselines
+func TestBaselineStorePersistence(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "baselines.json")
+
+	store, err := NewBaselineStore(path)
+	if err != nil {
+		t.Fatalf("NewBaselineStore() error = %v", err)
+	}
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.Local)
+	baseline, _ := LearnBaseline("memory", SeasonHourOfWeek, syntheticDailySeries(start, 7))
+	store.Baselines["memory"] = baseline
+
+	if err := store.Save(); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	loaded, err := NewBaselineStore(path)
+	if err != nil {
+		t.Fatalf("NewBaselineStore() reload error = %v", err)
+	}
+	got, ok := loaded.Get("memory")
+	if !ok {
+		t.Fatalf("loaded store missing memory baseline")
+	}
+	if got.Season != SeasonHourOfWeek || len(got.Buckets) != 168 {
+		t.Errorf("loaded baseline season=%s buckets=%d, want %s/168", got.Season, len(got.Buckets), SeasonHourOfWeek)
+	}
+
+	at := time.Date(2024, 1, 3, 12, 0, 0, 0, time.Local)
+	wantMean, _, _ := baseline.Expected(at, 1)
+	gotMean, _, _ := got.Expected(at, 1)
+	if math.Abs(wantMean-gotMean) > 1e-9 {
+		t.Errorf("loaded mean = %f, want %f", gotMean, wantMean)
+	}
+}
+
+// TestValidateAnomalyConfig tests anomaly mode validation
+func TestValidateAnomalyConfig(t *testing.T) {
+	tests := []struct {
+		name    string
+		metric  string
+		config  AnomalyConfig
+		wantErr bool
+	}{
+		{name: "defaults", metric: "cpu", config: AnomalyConfig{}},
+		{name: "memory hour of day", metric: "memory", config: AnomalyConfig{Season: SeasonHourOfDay}},
+		{name: "disk not supported", metric: "disk", config: AnomalyConfig{}, wantErr: true},
+		{name: "bad season", metric: "cpu", config: AnomalyConfig{Season: "yearly"}, wantErr: true},
+		{name: "bad direction", metric: "cpu", config: AnomalyConfig{Direction: "sideways"}, wantErr: true},
+		{name: "bad relearn interval", metric: "cpu", config: AnomalyConfig{RelearnInterval: "soon"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateAnomalyConfig(tt.metric, tt.config)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("validateAnomalyConfig() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// TestBaselineRefreshKeepsBaselineOnError tests that a failed refresh keeps the previous baseline
+func TestBaselineRefreshKeepsBaselineOnError(t *testing.T) {
+	dir := t.TempDir()
+	store, err := NewBaselineStore(filepath.Join(dir, "baselines.json"))
+	if err != nil {
+		t.Fatalf("NewBaselineStore() error = %v", err)
+	}
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.Local)
+	stale, _ := LearnBaseline("cpu", SeasonHourOfDay, syntheticDailySeries(start, 7))
+	stale.LearnedAt = start
+	store.Baselines["cpu"] = stale
+
+	config := anomalyTestConfig("")
+	cpu := config.Metrics["cpu"]
+	cpu.Anomaly.RelearnInterval = "soon"
+	config.Metrics["cpu"] = cpu
+	if err := store.Refresh(config, NewRecorder(filepath.Join(dir, "rrd"))); err == nil {
+		t.Fatalf("Refresh() expected error for invalid relearn interval")
+	}
+	if got, ok := store.Get("cpu"); !ok || got != stale {
+		t.Errorf("Refresh() replaced the previous baseline after an error")
+	}
+}
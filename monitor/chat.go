@@ -0,0 +1,292 @@
+package monitor
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"time"
+)
+
+// ResolvingAlertAction is implemented by actions that can announce that a violation resolved
+type ResolvingAlertAction interface {
+	AlertAction
+	Resolve(violation ThresholdViolation) error
+}
+
+// Colors used by chat actions
+const (
+	colorCritical = "#D32F2F"
+	colorWarning  = "#F9A825"
+	colorResolved = "#2E7D32"
+)
+
+// chatField is a labelled value shown in a chat message
+type chatField struct {
+	Title string
+	Value string
+}
+
+// chatMessage is the platform-neutral content of a chat notification
+type chatMessage struct {
+	Title    string
+	Text     string
+	Color    string
+	State    string // "critical", "warning" or "resolved"
+	Fields   []chatField
+	Footer   string
+	Fallback string
+}
+
+// newChatMessage builds the chat content for a violation or its resolution
+func newChatMessage(violation ThresholdViolation, resolved bool) chatMessage {
+	hostname, _ := os.Hostname()
+
+	subject := violation.Metric
+	if violation.Mountpoint != "" {
+		subject = fmt.Sprintf("%s %s", violation.Metric, violation.Mountpoint)
+	}
+
+	msg := chatMessage{
+		State:  violation.Level,
+		Text:   violation.Message,
+		Footer: fmt.Sprintf("tfc-system-monitor · %s", time.Now().Format("2006-01-02 15:04:05 MST")),
+		Fields: []chatField{
+			{Title: "Host", Value: hostname},
+			{Title: "Metric", Value: violation.Metric},
+			{Title: "Level", Value: violation.Level},
+			{Title: "Value", Value: fmt.Sprintf("%.2f", violation.Value)},
+		},
+	}
+	if violation.Mountpoint != "" {
+		msg.Fields = append(msg.Fields, chatField{Title: "Mountpoint", Value: violation.Mountpoint})
+	}
+
+	switch {
+	case resolved:
+		msg.State = "resolved"
+		msg.Color = colorResolved
+		msg.Title = fmt.Sprintf("RESOLVED: %s on %s", subject, hostname)
+		msg.Text = fmt.Sprintf("Resolved: %s", violation.Message)
+	case violation.Level == "critical":
+		msg.Color = colorCritical
+		msg.Title = fmt.Sprintf("CRITICAL: %s on %s", subject, hostname)
+	default:
+		msg.Color = colorWarning
+		msg.Title = fmt.Sprintf("%s: %s on %s", strings.ToUpper(violation.Level), subject, hostname)
+	}
+	msg.Fallback = fmt.Sprintf("%s - %s", msg.Title, msg.Text)
+
+	return msg
+}
+
+// SlackAction posts alerts to a Slack incoming webhook using blocks
+type SlackAction struct {
+	jsonWebhook
+	Channel  string
+	Username string
+}
+
+// NewSlackAction creates a new Slack alert action
+func NewSlackAction(config map[string]interface{}) (*SlackAction, error) {
+	jw, err := newJSONWebhook("slack", config)
+	if err != nil {
+		return nil, err
+	}
+	sa := &SlackAction{jsonWebhook: jw}
+	if channel, ok := config["channel"].(string); ok {
+		sa.Channel = channel
+	}
+	if username, ok := config["username"].(string); ok {
+		sa.Username = username
+	}
+	return sa, nil
+}
+
+// Execute posts a violation to Slack
+func (sa *SlackAction) Execute(violation ThresholdViolation) error {
+	if err := sa.post(sa.payload(newChatMessage(violation, false))); err != nil {
+		return err
+	}
+	log.Printf("Slack alert sent: %s/%s", violation.Metric, violation.Level)
+	return nil
+}
+
+// Resolve posts a resolved update to Slack
+func (sa *SlackAction) Resolve(violation ThresholdViolation) error {
+	if err := sa.post(sa.payload(newChatMessage(violation, true))); err != nil {
+		return err
+	}
+	log.Printf("Slack resolved update sent: %s/%s", violation.Metric, violation.Level)
+	return nil
+}
+
+// payload builds a Slack message; blocks are wrapped in an attachment to get the color bar
+func (sa *SlackAction) payload(msg chatMessage) map[string]interface{} {
+	var fields []map[string]interface{}
+	for _, f := range msg.Fields {
+		fields = append(fields, map[string]interface{}{
+			"type": "mrkdwn",
+			"text": fmt.Sprintf("*%s*\n%s", f.Title, f.Value),
+		})
+	}
+
+	blocks := []map[string]interface{}{
+		{"type": "header", "text": map[string]interface{}{"type": "plain_text", "text": msg.Title}},
+		{"type": "section", "text": map[string]interface{}{"type": "mrkdwn", "text": msg.Text}},
+		{"type": "section", "fields": fields},
+		{"type": "context", "elements": []map[string]interface{}{{"type": "mrkdwn", "text": msg.Footer}}},
+	}
+
+	payload := map[string]interface{}{
+		"text":        msg.Fallback,
+		"attachments": []map[string]interface{}{{"color": msg.Color, "blocks": blocks}},
+	}
+	if sa.Channel != "" {
+		payload["channel"] = sa.Channel
+	}
+	if sa.Username != "" {
+		payload["username"] = sa.Username
+	}
+	return payload
+}
+
+// MattermostAction posts alerts to a Mattermost incoming webhook using attachments
+type MattermostAction struct {
+	jsonWebhook
+	Channel  string
+	Username string
+}
+
+// NewMattermostAction creates a new Mattermost alert action
+func NewMattermostAction(config map[string]interface{}) (*MattermostAction, error) {
+	jw, err := newJSONWebhook("mattermost", config)
+	if err != nil {
+		return nil, err
+	}
+	ma := &MattermostAction{jsonWebhook: jw}
+	if channel, ok := config["channel"].(string); ok {
+		ma.Channel = channel
+	}
+	if username, ok := config["username"].(string); ok {
+		ma.Username = username
+	}
+	return ma, nil
+}
+
+// Execute posts a violation to Mattermost
+func (ma *MattermostAction) Execute(violation ThresholdViolation) error {
+	if err := ma.post(ma.payload(newChatMessage(violation, false))); err != nil {
+		return err
+	}
+	log.Printf("Mattermost alert sent: %s/%s", violation.Metric, violation.Level)
+	return nil
+}
+
+// Resolve posts a resolved update to Mattermost
+func (ma *MattermostAction) Resolve(violation ThresholdViolation) error {
+	if err := ma.post(ma.payload(newChatMessage(violation, true))); err != nil {
+		return err
+	}
+	log.Printf("Mattermost resolved update sent: %s/%s", violation.Metric, violation.Level)
+	return nil
+}
+
+// payload builds a Mattermost message attachment
+func (ma *MattermostAction) payload(msg chatMessage) map[string]interface{} {
+	var fields []map[string]interface{}
+	for _, f := range msg.Fields {
+		fields = append(fields, map[string]interface{}{"short": true, "title": f.Title, "value": f.Value})
+	}
+
+	payload := map[string]interface{}{
+		"attachments": []map[string]interface{}{{
+			"fallback": msg.Fallback,
+			"color":    msg.Color,
+			"title":    msg.Title,
+			"text":     msg.Text,
+			"fields":   fields,
+			"footer":   msg.Footer,
+		}},
+	}
+	if ma.Channel != "" {
+		payload["channel"] = ma.Channel
+	}
+	if ma.Username != "" {
+		payload["username"] = ma.Username
+	}
+	return payload
+}
+
+// TeamsAction posts alerts to a Microsoft Teams webhook as Adaptive Cards
+type TeamsAction struct {
+	jsonWebhook
+}
+
+// teamsColors maps message states to Adaptive Card text colors
+var teamsColors = map[string]string{
+	"critical": "attention",
+	"warning":  "warning",
+	"resolved": "good",
+}
+
+// NewTeamsAction creates a new Teams alert action
+func NewTeamsAction(config map[string]interface{}) (*TeamsAction, error) {
+	jw, err := newJSONWebhook("teams", config)
+	if err != nil {
+		return nil, err
+	}
+	return &TeamsAction{jsonWebhook: jw}, nil
+}
+
+// Execute posts a violation to Teams
+func (ta *TeamsAction) Execute(violation ThresholdViolation) error {
+	if err := ta.post(ta.payload(newChatMessage(violation, false))); err != nil {
+		return err
+	}
+	log.Printf("Teams alert sent: %s/%s", violation.Metric, violation.Level)
+	return nil
+}
+
+// Resolve posts a resolved update to Teams
+func (ta *TeamsAction) Resolve(violation ThresholdViolation) error {
+	if err := ta.post(ta.payload(newChatMessage(violation, true))); err != nil {
+		return err
+	}
+	log.Printf("Teams resolved update sent: %s/%s", violation.Metric, violation.Level)
+	return nil
+}
+
+// payload builds a Teams message carrying an Adaptive Card
+func (ta *TeamsAction) payload(msg chatMessage) map[string]interface{} {
+	var facts []map[string]interface{}
+	for _, f := range msg.Fields {
+		facts = append(facts, map[string]interface{}{"title": f.Title, "value": f.Value})
+	}
+
+	color, ok := teamsColors[msg.State]
+	if !ok {
+		color = "default"
+	}
+
+	card := map[string]interface{}{
+		"$schema": "http://adaptivecards.io/schemas/adaptive-card.json",
+		"type":    "AdaptiveCard",
+		"version": "1.4",
+		"msteams": map[string]interface{}{"width": "Full"},
+		"body": []map[string]interface{}{
+			{"type": "TextBlock", "size": "Large", "weight": "Bolder", "color": color, "text": msg.Title, "wrap": true},
+			{"type": "TextBlock", "text": msg.Text, "wrap": true},
+			{"type": "FactSet", "facts": facts},
+			{"type": "TextBlock", "text": msg.Footer, "size": "Small", "isSubtle": true, "wrap": true},
+		},
+	}
+
+	return map[string]interface{}{
+		"type": "message",
+		"attachments": []map[string]interface{}{{
+			"contentType": "application/vnd.microsoft.card.adaptive",
+			"content":     card,
+		}},
+	}
+}
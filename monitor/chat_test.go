@@ -0,0 +1,217 @@
+package monitor
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// chatRecorder captures JSON payloads posted to a test webhook
+type chatRecorder struct {
+	mu       sync.Mutex
+	payloads []map[string]interface{}
+}
+
+func newChatServer(t *testing.T, rec *chatRecorder) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		var payload map[string]interface{}
+		if err := json.Unmarshal(body, &payload); err != nil {
+			t.Errorf("invalid JSON payload: %v", err)
+		}
+		rec.mu.Lock()
+		rec.payloads = append(rec.payloads, payload)
+		rec.mu.Unlock()
+		w.WriteHeader(http.StatusOK)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+// jsonPath walks decoded JSON by map keys and slice indexes
+func jsonPath(v interface{}, path ...interface{}) interface{} {
+	for _, p := range path {
+		switch key := p.(type) {
+		case string:
+			m, _ := v.(map[string]interface{})
+			v = m[key]
+		case int:
+			s, _ := v.([]interface{})
+			if key >= len(s) {
+				return nil
+			}
+			v = s[key]
+		}
+	}
+	return v
+}
+
+var chatTestViolation = ThresholdViolation{
+	Metric:     "disk",
+	Level:      "critical",
+	Message:    "partition /dev/sda1, mounted at / is 95.00% full",
+	Value:      95,
+	Mountpoint: "/",
+}
+
+// TestSlackActionPayload tests the Slack blocks payload
+func TestSlackActionPayload(t *testing.T) {
+	rec := &chatRecorder{}
+	server := newChatServer(t, rec)
+
+	action, err := CreateAction(map[string]interface{}{"type": "slack", "url": server.URL, "channel": "#ops"})
+	if err != nil {
+		t.Fatalf("CreateAction() error = %v", err)
+	}
+	if err := action.Execute(chatTestViolation); err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+
+	p := rec.payloads[0]
+	if p["channel"] != "#ops" {
+		t.Errorf("channel = %v", p["channel"])
+	}
+	if got := jsonPath(p, "attachments", 0, "color"); got != colorCritical {
+		t.Errorf("color = %v, want %s", got, colorCritical)
+	}
+	header, _ := jsonPath(p, "attachments", 0, "blocks", 0, "text", "text").(string)
+	if !strings.HasPrefix(header, "CRITICAL: disk / on ") {
+		t.Errorf("header = %q", header)
+	}
+	if got := jsonPath(p, "attachments", 0, "blocks", 1, "text", "text"); got != chatTestViolation.Message {
+		t.Errorf("section text = %v", got)
+	}
+	if got := jsonPath(p, "attachments", 0, "blocks", 2, "fields", 0, "text"); !strings.HasPrefix(got.(string), "*Host*\n") {
+		t.Errorf("host field = %v", got)
+	}
+	if text, _ := p["text"].(string); !strings.Contains(text, chatTestViolation.Message) {
+		t.Errorf("fallback text = %q", text)
+	}
+}
+
+// TestMattermostActionPayload tests the Mattermost attachment payload
+func TestMattermostActionPayload(t *testing.T) {
+	rec := &chatRecorder{}
+	server := newChatServer(t, rec)
+
+	action, err := CreateAction(map[string]interface{}{"type": "mattermost", "url": server.URL, "username": "monitor"})
+	if err != nil {
+		t.Fatalf("CreateAction() error = %v", err)
+	}
+	warning := chatTestViolation
+	warning.Level = "warning"
+	if err := action.Execute(warning); err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+
+	p := rec.payloads[0]
+	if p["username"] != "monitor" {
+		t.Errorf("username = %v", p["username"])
+	}
+	if got := jsonPath(p, "attachments", 0, "color"); got != colorWarning {
+		t.Errorf("color = %v, want %s", got, colorWarning)
+	}
+	if got := jsonPath(p, "attachments", 0, "fields", 4, "value"); got != "/" {
+		t.Errorf("mountpoint field = %v", got)
+	}
+}
+
+// TestTeamsActionPayload tests the Teams Adaptive Card payload
+func TestTeamsActionPayload(t *testing.T) {
+	rec := &chatRecorder{}
+	server := newChatServer(t, rec)
+
+	action, err := CreateAction(map[string]interface{}{"type": "teams", "url": server.URL})
+	if err != nil {
+		t.Fatalf("CreateAction() error = %v", err)
+	}
+	if err := action.Execute(chatTestViolation); err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+
+	p := rec.payloads[0]
+	if got := jsonPath(p, "attachments", 0, "contentType"); got != "application/vnd.microsoft.card.adaptive" {
+		t.Errorf("contentType = %v", got)
+	}
+	card := jsonPath(p, "attachments", 0, "content")
+	if got := jsonPath(card, "type"); got != "AdaptiveCard" {
+		t.Errorf("card type = %v", got)
+	}
+	if got := jsonPath(card, "body", 0, "color"); got != "attention" {
+		t.Errorf("title color = %v, want attention", got)
+	}
+	if got := jsonPath(card, "body", 2, "facts", 1, "value"); got != "disk" {
+		t.Errorf("metric fact = %v", got)
+	}
+}
+
+// TestProcessResolved tests sending resolved updates through resolving actions only
+func TestProcessResolved(t *testing.T) {
+	rec := &chatRecorder{}
+	server := newChatServer(t, rec)
+
+	config := &Config{
+		Alerts: map[string]AlertLevel{
+			"critical": {
+				Actions: []map[string]interface{}{
+					{"type": "stdout"},
+					{"type": "slack", "url": server.URL},
+					{"type": "teams", "url": server.URL},
+				},
+			},
+		},
+	}
+
+	if err := ProcessResolved(config, []ThresholdViolation{chatTestViolation}); err != nil {
+		t.Fatalf("ProcessResolved() error = %v", err)
+	}
+	if len(rec.payloads) != 2 {
+		t.Fatalf("got %d resolved updates, want 2", len(rec.payloads))
+	}
+
+	// Actions run in parallel, so the updates arrive in any order
+	slack, teams := rec.payloads[0], rec.payloads[1]
+	if jsonPath(slack, "attachments", 0, "content") != nil {
+		slack, teams = teams, slack
+	}
+	if got := jsonPath(slack, "attachments", 0, "color"); got != colorResolved {
+		t.Errorf("slack color = %v, want %s", got, colorResolved)
+	}
+	if header, _ := jsonPath(slack, "attachments", 0, "blocks", 0, "text", "text").(string); !strings.HasPrefix(header, "RESOLVED: disk /") {
+		t.Errorf("slack header = %q", header)
+	}
+	if got := jsonPath(teams, "attachments", 0, "content", "body", 0, "color"); got != "good" {
+		t.Errorf("teams color = %v, want good", got)
+	}
+}
+
+// TestChatActionErrors tests chat action configuration and delivery errors
+func TestChatActionErrors(t *testing.T) {
+	for _, actionType := range []string{"slack", "mattermost", "teams"} {
+		if _, err := CreateAction(map[string]interface{}{"type": actionType}); err == nil {
+			t.Errorf("CreateAction(%s) without url expected error", actionType)
+		}
+		// Config validation runs the same checks
+		if err := validateActions("critical", []map[string]interface{}{{"type": actionType, "url": 123}}); err == nil {
+			t.Errorf("validateActions(%s) with a non-string url expected error", actionType)
+		}
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	defer server.Close()
+
+	action, err := NewSlackAction(map[string]interface{}{"url": server.URL, "retry": 2})
+	if err != nil {
+		t.Fatalf("NewSlackAction() error = %v", err)
+	}
+	if err := action.Execute(chatTestViolation); err == nil || !strings.Contains(err.Error(), "after 2 attempts") {
+		t.Errorf("Execute() error = %v, want failure after 2 attempts", err)
+	}
+}
@@ -0,0 +1,481 @@
+package monitor
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"html"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+)
+
+// ChatMessageFile stores the IDs of sent chat bot messages, so that resolved
+// notifications can reply to or edit the alert they resolve
+const ChatMessageFile = "/tmp/tfc-monitor-chat-messages.json"
+
+// DefaultTelegramURL is the Telegram Bot API endpoint
+const DefaultTelegramURL = "https://api.telegram.org"
+
+// Resolved notification modes of chat bot actions
+const (
+	resolvedReply = "reply" // send a follow-up that replies to the alert (default)
+	resolvedEdit  = "edit"  // edit the alert message
+	resolvedNone  = "none"  // send no resolved notification
+)
+
+// chatMessageMu serializes access to the chat message file
+var chatMessageMu sync.Mutex
+
+// chatBotTemplateFuncs are available in chat bot message templates, in addition to the
+// webhook template functions
+var chatBotTemplateFuncs = template.FuncMap{
+	"markdown": telegramEscape,
+	"html":     html.EscapeString,
+}
+
+// chatBot holds the settings shared by the Telegram and Matrix actions
+type chatBot struct {
+	jsonWebhook
+	Template         *template.Template // nil sends the default message
+	ResolvedTemplate *template.Template
+	Resolved         string // resolvedReply, resolvedEdit or resolvedNone
+	MessageFile      string // where sent message IDs are kept
+}
+
+// newChatBot reads the template and resolved settings shared by chat bot actions
+func newChatBot(actionType string, config map[string]interface{}) (chatBot, error) {
+	jw, err := newJSONWebhook(actionType, config)
+	if err != nil {
+		return chatBot{}, err
+	}
+	cb := chatBot{jsonWebhook: jw, Resolved: resolvedReply, MessageFile: ChatMessageFile}
+
+	if cb.Template, err = chatBotTemplate(config, "template"); err != nil {
+		return cb, fmt.Errorf("%s action %w", actionType, err)
+	}
+	if cb.ResolvedTemplate, err = chatBotTemplate(config, "resolved_template"); err != nil {
+		return cb, fmt.Errorf("%s action %w", actionType, err)
+	}
+
+	if resolved, ok := config["resolved"].(string); ok {
+		if resolved != resolvedReply && resolved != resolvedEdit && resolved != resolvedNone {
+			return cb, fmt.Errorf("%s action 'resolved' must be 'reply', 'edit' or 'none'", actionType)
+		}
+		cb.Resolved = resolved
+	}
+
+	return cb, nil
+}
+
+// chatBotTemplate parses a message template field, returning nil if it is not set
+func chatBotTemplate(config map[string]interface{}, key string) (*template.Template, error) {
+	text, ok := config[key].(string)
+	if !ok {
+		return nil, nil
+	}
+	tmpl, err := template.New(key).Funcs(webhookTemplateFuncs).Funcs(chatBotTemplateFuncs).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("has invalid '%s': %w", key, err)
+	}
+	return tmpl, nil
+}
+
+// render renders a message template with the same data as webhook body templates
+func (cb chatBot) render(tmpl *template.Template, violation ThresholdViolation, resolved bool) (string, error) {
+	hostname, _ := os.Hostname()
+	data := WebhookData{Violation: violation, Host: hostname, Time: time.Now()}
+	if !resolved {
+		data.Stats = violation.Stats
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("failed to render %s message: %w", cb.Name, err)
+	}
+	return buf.String(), nil
+}
+
+// template returns the template for an alert or resolved message, or nil for the default
+func (cb chatBot) template(resolved bool) *template.Template {
+	if resolved {
+		return cb.ResolvedTemplate
+	}
+	return cb.Template
+}
+
+// messageKey identifies the last message sent about a violation to a chat
+func (cb chatBot) messageKey(chat string, violation ThresholdViolation) string {
+	return cb.Name + "/" + chat + "/" + violation.StateKey()
+}
+
+// messageID returns the ID of the last message sent about a violation, or ""
+func (cb chatBot) messageID(key string) string {
+	chatMessageMu.Lock()
+	defer chatMessageMu.Unlock()
+
+	ids, err := loadChatMessages(cb.MessageFile)
+	if err != nil {
+		log.Printf("Failed to load chat messages: %v", err)
+		return ""
+	}
+	return ids[key]
+}
+
+// setMessageID records the ID of the message sent about a violation; "" forgets it
+func (cb chatBot) setMessageID(key string, id string) {
+	chatMessageMu.Lock()
+	defer chatMessageMu.Unlock()
+
+	ids, err := loadChatMessages(cb.MessageFile)
+	if err != nil {
+		log.Printf("Failed to load chat messages: %v", err)
+		return
+	}
+	if id == "" {
+		delete(ids, key)
+	} else {
+		ids[key] = id
+	}
+
+	data, err := json.MarshalIndent(ids, "", "  ")
+	if err == nil {
+		tmp := cb.MessageFile + ".tmp"
+		if err = os.WriteFile(tmp, data, 0644); err == nil {
+			err = os.Rename(tmp, cb.MessageFile)
+		}
+	}
+	if err != nil {
+		log.Printf("Failed to save chat messages: %v", err)
+	}
+}
+
+// loadChatMessages reads the chat message file, returning an empty map if it does not exist
+func loadChatMessages(path string) (map[string]string, error) {
+	ids := make(map[string]string)
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return ids, nil
+	} else if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &ids); err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
+
+// TelegramAction sends alerts to a Telegram chat through a bot
+type TelegramAction struct {
+	chatBot
+	Token     string
+	ChatID    string
+	ParseMode string // "MarkdownV2" (default), "HTML" or "" for plain text
+}
+
+// NewTelegramAction creates a new Telegram alert action
+func NewTelegramAction(config map[string]interface{}) (*TelegramAction, error) {
+	cb, err := newChatBot("telegram", withDefaultURL(config, DefaultTelegramURL))
+	if err != nil {
+		return nil, err
+	}
+	ta := &TelegramAction{chatBot: cb, ParseMode: "MarkdownV2"}
+
+	token, ok := config["token"].(string)
+	if !ok || token == "" {
+		return nil, fmt.Errorf("telegram action requires 'token' field")
+	}
+	ta.Token = os.ExpandEnv(token)
+
+	// Chat IDs are numbers, or @channelname for public channels
+	switch chatID := config["chat_id"].(type) {
+	case string:
+		ta.ChatID = os.ExpandEnv(chatID)
+	case int:
+		ta.ChatID = fmt.Sprint(chatID)
+	}
+	if ta.ChatID == "" {
+		return nil, fmt.Errorf("telegram action requires 'chat_id' field")
+	}
+
+	if parseMode, ok := config["parse_mode"].(string); ok {
+		switch parseMode {
+		case "MarkdownV2", "HTML":
+			ta.ParseMode = parseMode
+		case "none":
+			ta.ParseMode = ""
+		default:
+			return nil, fmt.Errorf("telegram action 'parse_mode' must be 'MarkdownV2', 'HTML' or 'none'")
+		}
+	}
+
+	return ta, nil
+}
+
+// telegramResponse is the reply of the Bot API
+type telegramResponse struct {
+	OK          bool   `json:"ok"`
+	Description string `json:"description"`
+	Result      struct {
+		MessageID int64 `json:"message_id"`
+	} `json:"result"`
+}
+
+// Execute sends a violation to the chat
+func (ta *TelegramAction) Execute(violation ThresholdViolation) error {
+	text, err := ta.text(violation, false)
+	if err != nil {
+		return err
+	}
+	id, err := ta.call("sendMessage", ta.message(text))
+	if err != nil {
+		return err
+	}
+	ta.setMessageID(ta.messageKey(ta.ChatID, violation), id)
+	log.Printf("Telegram alert sent to %s: %s/%s", ta.ChatID, violation.Metric, violation.Level)
+	return nil
+}
+
+// Resolve edits the alert message, or replies to it, to announce that the violation resolved
+func (ta *TelegramAction) Resolve(violation ThresholdViolation) error {
+	if ta.Resolved == resolvedNone {
+		return nil
+	}
+	text, err := ta.text(violation, true)
+	if err != nil {
+		return err
+	}
+
+	key := ta.messageKey(ta.ChatID, violation)
+	messageID := ta.messageID(key)
+	payload := ta.message(text)
+	method := "sendMessage"
+	if messageID != "" {
+		if ta.Resolved == resolvedEdit {
+			method = "editMessageText"
+			payload["message_id"] = json.Number(messageID)
+		} else {
+			payload["reply_parameters"] = map[string]interface{}{
+				"message_id":                  json.Number(messageID),
+				"allow_sending_without_reply": true,
+			}
+		}
+	}
+
+	if _, err := ta.call(method, payload); err != nil {
+		return err
+	}
+	ta.setMessageID(key, "")
+	log.Printf("Telegram resolved update sent to %s: %s/%s", ta.ChatID, violation.Metric, violation.Level)
+	return nil
+}
+
+// message builds a sendMessage payload
+func (ta *TelegramAction) message(text string) map[string]interface{} {
+	payload := map[string]interface{}{
+		"chat_id": ta.ChatID,
+		"text":    text,
+	}
+	if ta.ParseMode != "" {
+		payload["parse_mode"] = ta.ParseMode
+	}
+	return payload
+}
+
+// call calls a Bot API method and returns the ID of the sent or edited message
+func (ta *TelegramAction) call(method string, payload map[string]interface{}) (string, error) {
+	var resp telegramResponse
+	endpoint := strings.TrimRight(ta.URL, "/") + "/bot" + ta.Token + "/" + method
+	if err := ta.send(http.MethodPost, endpoint, payload, &resp); err != nil {
+		return "", err
+	}
+	if !resp.OK {
+		return "", fmt.Errorf("telegram %s failed: %s", method, resp.Description)
+	}
+	return fmt.Sprint(resp.Result.MessageID), nil
+}
+
+// text renders the message text from the template, or the default message
+func (ta *TelegramAction) text(violation ThresholdViolation, resolved bool) (string, error) {
+	if tmpl := ta.template(resolved); tmpl != nil {
+		return ta.render(tmpl, violation, resolved)
+	}
+
+	msg := newChatMessage(violation, resolved)
+	escape := func(s string) string { return s }
+	bold := func(s string) string { return s }
+	italic := bold
+	switch ta.ParseMode {
+	case "MarkdownV2":
+		escape = telegramEscape
+		bold = func(s string) string { return "*" + s + "*" }
+		italic = func(s string) string { return "_" + s + "_" }
+	case "HTML":
+		escape = html.EscapeString
+		bold = func(s string) string { return "<b>" + s + "</b>" }
+		italic = func(s string) string { return "<i>" + s + "</i>" }
+	}
+
+	lines := []string{bold(escape(msg.Title)), escape(msg.Text), ""}
+	for _, f := range msg.Fields {
+		lines = append(lines, bold(escape(f.Title+":"))+" "+escape(f.Value))
+	}
+	lines = append(lines, "", italic(escape(msg.Footer)))
+	return strings.Join(lines, "\n"), nil
+}
+
+// telegramEscape escapes the characters that are special in Telegram MarkdownV2
+func telegramEscape(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		if strings.ContainsRune("\\_*[]()~`>#+-=|{}.!", r) {
+			b.WriteByte('\\')
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// MatrixAction sends alerts to a Matrix room as m.notice messages
+type MatrixAction struct {
+	chatBot
+	RoomID string
+}
+
+// NewMatrixAction creates a new Matrix alert action
+func NewMatrixAction(config map[string]interface{}) (*MatrixAction, error) {
+	homeserver, ok := config["homeserver"].(string)
+	if !ok || homeserver == "" {
+		return nil, fmt.Errorf("matrix action requires 'homeserver' field")
+	}
+	withURL := make(map[string]interface{}, len(config)+1)
+	for k, v := range config {
+		withURL[k] = v
+	}
+	withURL["url"] = os.ExpandEnv(homeserver)
+
+	cb, err := newChatBot("matrix", withURL)
+	if err != nil {
+		return nil, err
+	}
+	ma := &MatrixAction{chatBot: cb}
+
+	token, ok := config["token"].(string)
+	if !ok || token == "" {
+		return nil, fmt.Errorf("matrix action requires 'token' field")
+	}
+	ma.Headers = map[string]string{"Authorization": "Bearer " + os.ExpandEnv(token)}
+
+	roomID, ok := config["room_id"].(string)
+	if !ok || roomID == "" {
+		return nil, fmt.Errorf("matrix action requires 'room_id' field")
+	}
+	ma.RoomID = roomID
+
+	return ma, nil
+}
+
+// Execute sends a violation to the room
+func (ma *MatrixAction) Execute(violation ThresholdViolation) error {
+	content, err := ma.content(violation, false)
+	if err != nil {
+		return err
+	}
+	eventID, err := ma.sendEvent(content)
+	if err != nil {
+		return err
+	}
+	ma.setMessageID(ma.messageKey(ma.RoomID, violation), eventID)
+	log.Printf("Matrix alert sent to %s: %s/%s", ma.RoomID, violation.Metric, violation.Level)
+	return nil
+}
+
+// Resolve edits the alert message, or replies to it, to announce that the violation resolved
+func (ma *MatrixAction) Resolve(violation ThresholdViolation) error {
+	if ma.Resolved == resolvedNone {
+		return nil
+	}
+	content, err := ma.content(violation, true)
+	if err != nil {
+		return err
+	}
+
+	key := ma.messageKey(ma.RoomID, violation)
+	if eventID := ma.messageID(key); eventID != "" {
+		if ma.Resolved == resolvedEdit {
+			// Clients show the new content in place of the alert; "* " marks the
+			// fallback body as an edit for clients without edit support
+			edit := map[string]interface{}{
+				"msgtype":       "m.notice",
+				"body":          "* " + content["body"].(string),
+				"m.new_content": content,
+				"m.relates_to":  map[string]interface{}{"rel_type": "m.replace", "event_id": eventID},
+			}
+			content = edit
+		} else {
+			content["m.relates_to"] = map[string]interface{}{
+				"m.in_reply_to": map[string]interface{}{"event_id": eventID},
+			}
+		}
+	}
+
+	if _, err := ma.sendEvent(content); err != nil {
+		return err
+	}
+	ma.setMessageID(key, "")
+	log.Printf("Matrix resolved update sent to %s: %s/%s", ma.RoomID, violation.Metric, violation.Level)
+	return nil
+}
+
+// content builds an m.notice message; templates render its HTML body
+func (ma *MatrixAction) content(violation ThresholdViolation, resolved bool) (map[string]interface{}, error) {
+	msg := newChatMessage(violation, resolved)
+	content := map[string]interface{}{
+		"msgtype": "m.notice",
+		"body":    msg.Fallback,
+		"format":  "org.matrix.custom.html",
+	}
+
+	if tmpl := ma.template(resolved); tmpl != nil {
+		formatted, err := ma.render(tmpl, violation, resolved)
+		if err != nil {
+			return nil, err
+		}
+		content["formatted_body"] = formatted
+		return content, nil
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, `<p><strong><font color="%s">%s</font></strong></p>`, msg.Color, html.EscapeString(msg.Title))
+	fmt.Fprintf(&b, "<p>%s</p><ul>", html.EscapeString(msg.Text))
+	for _, f := range msg.Fields {
+		fmt.Fprintf(&b, "<li><strong>%s:</strong> %s</li>", html.EscapeString(f.Title), html.EscapeString(f.Value))
+	}
+	fmt.Fprintf(&b, "</ul><p><em>%s</em></p>", html.EscapeString(msg.Footer))
+	content["formatted_body"] = b.String()
+	return content, nil
+}
+
+// sendEvent sends a message event to the room and returns its event ID. Retries reuse the
+// transaction ID, so the homeserver does not deliver a message twice.
+func (ma *MatrixAction) sendEvent(content map[string]interface{}) (string, error) {
+	txn := make([]byte, 8)
+	rand.Read(txn)
+	endpoint := fmt.Sprintf("%s/_matrix/client/v3/rooms/%s/send/m.room.message/%s",
+		strings.TrimRight(ma.URL, "/"), url.PathEscape(ma.RoomID), hex.EncodeToString(txn))
+
+	var resp struct {
+		EventID string `json:"event_id"`
+	}
+	if err := ma.send(http.MethodPut, endpoint, content, &resp); err != nil {
+		return "", err
+	}
+	return resp.EventID, nil
+}
@@ -0,0 +1,223 @@
+package monitor
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// botRequest is a request received by the chat bot API stand-in
+type botRequest struct {
+	method  string
+	path    string
+	headers http.Header
+	payload map[string]interface{}
+}
+
+// newBotServer starts a stand-in for the Telegram and Matrix APIs that answers every
+// request with the given response
+func newBotServer(t *testing.T, response string) (*httptest.Server, func() []botRequest) {
+	t.Helper()
+	var mu sync.Mutex
+	var requests []botRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		var payload map[string]interface{}
+		if err := json.Unmarshal(body, &payload); err != nil {
+			t.Errorf("invalid JSON payload: %v", err)
+		}
+		mu.Lock()
+		requests = append(requests, botRequest{method: r.Method, path: r.URL.EscapedPath(), headers: r.Header, payload: payload})
+		mu.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+		io.WriteString(w, response)
+	}))
+	t.Cleanup(server.Close)
+	return server, func() []botRequest {
+		mu.Lock()
+		defer mu.Unlock()
+		return requests
+	}
+}
+
+// TestTelegramAction tests the Telegram message and the reply to it when the violation resolves
+func TestTelegramAction(t *testing.T) {
+	server, requests := newBotServer(t, `{"ok":true,"result":{"message_id":42}}`)
+
+	action, err := NewTelegramAction(map[string]interface{}{
+		"url":     server.URL,
+		"token":   "123:ABC",
+		"chat_id": -1001234,
+	})
+	if err != nil {
+		t.Fatalf("NewTelegramAction() error = %v", err)
+	}
+	action.MessageFile = filepath.Join(t.TempDir(), "messages.json")
+
+	if err := action.Execute(chatTestViolation); err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if err := action.Resolve(chatTestViolation); err != nil {
+		t.Fatalf("Resolve() error = %v", err)
+	}
+
+	reqs := requests()
+	if len(reqs) != 2 {
+		t.Fatalf("got %d requests, want 2", len(reqs))
+	}
+	alert, resolved := reqs[0], reqs[1]
+	if alert.path != "/bot123:ABC/sendMessage" {
+		t.Errorf("path = %s", alert.path)
+	}
+	if alert.payload["chat_id"] != "-1001234" || alert.payload["parse_mode"] != "MarkdownV2" {
+		t.Errorf("payload = %v", alert.payload)
+	}
+	text, _ := alert.payload["text"].(string)
+	if !strings.HasPrefix(text, "*CRITICAL: disk / on ") || !strings.Contains(text, `95\.00% full`) {
+		t.Errorf("text = %q", text)
+	}
+	if jsonPath(resolved.payload, "reply_parameters", "message_id") != 42.0 {
+		t.Errorf("resolved update does not reply to the alert: %v", resolved.payload)
+	}
+	if text, _ := resolved.payload["text"].(string); !strings.HasPrefix(text, "*RESOLVED: ") {
+		t.Errorf("resolved text = %q", text)
+	}
+
+	// The message ID is forgotten once the violation resolved
+	if id := action.messageID(action.messageKey(action.ChatID, chatTestViolation)); id != "" {
+		t.Errorf("message ID %q kept after resolve", id)
+	}
+}
+
+// TestTelegramActionEditTemplate tests templates and editing the alert when the violation resolves
+func TestTelegramActionEditTemplate(t *testing.T) {
+	server, requests := newBotServer(t, `{"ok":true,"result":{"message_id":7}}`)
+
+	action, err := NewTelegramAction(map[string]interface{}{
+		"url":               server.URL,
+		"token":             "123:ABC",
+		"chat_id":           "@ops",
+		"resolved":          "edit",
+		"template":          "{{upper .Violation.Level}} {{markdown .Violation.Message}}",
+		"resolved_template": "OK {{.Violation.Metric}}",
+	})
+	if err != nil {
+		t.Fatalf("NewTelegramAction() error = %v", err)
+	}
+	action.MessageFile = filepath.Join(t.TempDir(), "messages.json")
+
+	if err := action.Execute(chatTestViolation); err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if err := action.Resolve(chatTestViolation); err != nil {
+		t.Fatalf("Resolve() error = %v", err)
+	}
+
+	reqs := requests()
+	if got := reqs[0].payload["text"]; got != `CRITICAL partition /dev/sda1, mounted at / is 95\.00% full` {
+		t.Errorf("text = %q", got)
+	}
+	if reqs[1].path != "/bot123:ABC/editMessageText" || reqs[1].payload["message_id"] != 7.0 {
+		t.Errorf("resolved request = %s %v", reqs[1].path, reqs[1].payload)
+	}
+	if reqs[1].payload["text"] != "OK disk" {
+		t.Errorf("resolved text = %v", reqs[1].payload["text"])
+	}
+}
+
+// TestTelegramActionAPIError tests that a Bot API error is reported
+func TestTelegramActionAPIError(t *testing.T) {
+	server, _ := newBotServer(t, `{"ok":false,"description":"Bad Request: chat not found"}`)
+
+	action, err := NewTelegramAction(map[string]interface{}{"url": server.URL, "token": "t", "chat_id": "1"})
+	if err != nil {
+		t.Fatalf("NewTelegramAction() error = %v", err)
+	}
+	action.MessageFile = filepath.Join(t.TempDir(), "messages.json")
+
+	if err := action.Execute(chatTestViolation); err == nil || !strings.Contains(err.Error(), "chat not found") {
+		t.Errorf("Execute() error = %v, want chat not found", err)
+	}
+}
+
+// TestMatrixAction tests the m.notice message and the edit when the violation resolves
+func TestMatrixAction(t *testing.T) {
+	server, requests := newBotServer(t, `{"event_id":"$alert"}`)
+	t.Setenv("MATRIX_TOKEN", "syt_secret")
+
+	action, err := NewMatrixAction(map[string]interface{}{
+		"homeserver": server.URL,
+		"token":      "${MATRIX_TOKEN}",
+		"room_id":    "!ops:example.org",
+		"resolved":   "edit",
+	})
+	if err != nil {
+		t.Fatalf("NewMatrixAction() error = %v", err)
+	}
+	action.MessageFile = filepath.Join(t.TempDir(), "messages.json")
+
+	if err := action.Execute(chatTestViolation); err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if err := action.Resolve(chatTestViolation); err != nil {
+		t.Fatalf("Resolve() error = %v", err)
+	}
+
+	reqs := requests()
+	alert, resolved := reqs[0], reqs[1]
+	if alert.method != http.MethodPut || !strings.HasPrefix(alert.path, "/_matrix/client/v3/rooms/%21ops:example.org/send/m.room.message/") {
+		t.Errorf("request = %s %s", alert.method, alert.path)
+	}
+	if got := alert.headers.Get("Authorization"); got != "Bearer syt_secret" {
+		t.Errorf("Authorization = %q", got)
+	}
+	if alert.payload["msgtype"] != "m.notice" || alert.payload["format"] != "org.matrix.custom.html" {
+		t.Errorf("payload = %v", alert.payload)
+	}
+	if body, _ := alert.payload["formatted_body"].(string); !strings.Contains(body, colorCritical) {
+		t.Errorf("formatted_body = %q", body)
+	}
+	if alert.path == resolved.path {
+		t.Error("resolved update reused the alert's transaction ID")
+	}
+	if jsonPath(resolved.payload, "m.relates_to", "rel_type") != "m.replace" || jsonPath(resolved.payload, "m.relates_to", "event_id") != "$alert" {
+		t.Errorf("resolved update does not edit the alert: %v", resolved.payload)
+	}
+	if body, _ := jsonPath(resolved.payload, "m.new_content", "body").(string); !strings.HasPrefix(body, "RESOLVED: ") {
+		t.Errorf("new content body = %q", body)
+	}
+}
+
+// TestChatBotActionErrors tests required fields and invalid options
+func TestChatBotActionErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  map[string]interface{}
+		wantErr string
+	}{
+		{"telegram without token", map[string]interface{}{"type": "telegram", "chat_id": 1}, "'token'"},
+		{"telegram without chat_id", map[string]interface{}{"type": "telegram", "token": "t"}, "'chat_id'"},
+		{"telegram invalid parse_mode", map[string]interface{}{"type": "telegram", "token": "t", "chat_id": 1, "parse_mode": "Markdown"}, "parse_mode"},
+		{"telegram invalid template", map[string]interface{}{"type": "telegram", "token": "t", "chat_id": 1, "template": "{{.Violation"}, "invalid 'template'"},
+		{"matrix without homeserver", map[string]interface{}{"type": "matrix", "token": "t", "room_id": "!r:x"}, "'homeserver'"},
+		{"matrix without room_id", map[string]interface{}{"type": "matrix", "homeserver": "http://hs", "token": "t"}, "'room_id'"},
+		{"matrix invalid resolved", map[string]interface{}{"type": "matrix", "homeserver": "http://hs", "token": "t", "room_id": "!r:x", "resolved": "delete"}, "'resolved'"},
+	}
+	for _, tt := range tests {
+		if _, err := CreateAction(tt.config); err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("%s: CreateAction() error = %v, want error containing %q", tt.name, err, tt.wantErr)
+		}
+	}
+}
+
+// TestTelegramEscape tests MarkdownV2 escaping
+func TestTelegramEscape(t *testing.T) {
+	if got := telegramEscape("cpu_usage > 90.5% (load)!"); got != `cpu\_usage \> 90\.5% \(load\)\!` {
+		t.Errorf("telegramEscape() = %q", got)
+	}
+}
@@ -4,15 +4,27 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"path/filepath"
 
 	"gopkg.in/yaml.v2"
 )
 
 // Config represents the entire configuration structure
 type Config struct {
-	Metrics map[string]MetricConfig `yaml:"metrics"`
-	Alerts  map[string]AlertLevel   `yaml:"alerts"`
-	RRDPath string                  `yaml:"rrd_path"`
+	Metrics      map[string]MetricConfig `yaml:"metrics"`
+	Alerts       map[string]AlertLevel   `yaml:"alerts"`
+	Rules        []RuleConfig            `yaml:"rules"`
+	RRDPath      string                  `yaml:"rrd_path"`
+	Spool        SpoolConfig             `yaml:"spool"`
+	Dispatch     DispatchConfig          `yaml:"dispatch"`
+	Labels       map[string]string       `yaml:"labels"`    // added to every violation, for routing
+	Receivers    []Receiver              `yaml:"receivers"` // named action sets used by the route
+	Route        *Route                  `yaml:"route"`     // routing tree; alerts are selected by level when unset
+	Grouping     GroupingConfig          `yaml:"grouping"`
+	Maintenance  []MaintenanceWindow     `yaml:"maintenance"`  // windows that hold notifications
+	MQTT         MQTTConfig              `yaml:"mqtt"`         // metric publisher
+	Alertmanager AlertmanagerConfig      `yaml:"alertmanager"` // pushes active violations to Alertmanager
+	API          APIConfig               `yaml:"api"`          // access to the acknowledgement and silence endpoints
 }
 
 // ExcludeConfig represents exclusion settings for metrics (e.g., disk)
@@ -24,12 +36,27 @@ type ExcludeConfig struct {
 
 // MetricConfig represents configuration for a single metric
 type MetricConfig struct {
-	Enabled    bool               `yaml:"enabled"`
-	Thresholds map[string]float64 `yaml:"thresholds"`
-	Throttle   ThrottleConfig     `yaml:"throttle"`
-	Mode       string             `yaml:"mode"` // for memory metric
-	Unit       string             `yaml:"unit"`
-	Exclude    ExcludeConfig      `yaml:"exclude"` // for disk metric
+	Enabled    bool                `yaml:"enabled"`
+	Thresholds map[string]float64  `yaml:"thresholds"`
+	Throttle   ThrottleConfig      `yaml:"throttle"`
+	Mode       string              `yaml:"mode"`      // for memory and disk metrics
+	Unit       string              `yaml:"unit"`      // "percentage" (default) or an absolute unit such as "GiB"
+	Exclude    ExcludeConfig       `yaml:"exclude"`   // for disk metric
+	Overrides  []DiskOverride      `yaml:"overrides"` // for disk metric
+	Anomaly    AnomalyConfig       `yaml:"anomaly"`   // for mode "anomaly"
+	Schedules  []ThresholdSchedule `yaml:"schedules"` // time-scoped thresholds
+	Labels     map[string]string   `yaml:"labels"`    // added to the metric's violations, for routing
+}
+
+// DiskOverride replaces disk settings for partitions matching a mountpoint and/or device glob
+type DiskOverride struct {
+	Mountpoint string             `yaml:"mountpoint"` // Mountpoint pattern (e.g., "/var/lib/docker", "/mnt/*")
+	Device     string             `yaml:"device"`     // Device pattern (e.g., "/dev/nvme*")
+	Thresholds map[string]float64 `yaml:"thresholds"` // Replaces the metric thresholds when set
+	Throttle   *ThrottleConfig    `yaml:"throttle"`   // Replaces the metric throttle when set
+	Mode       string             `yaml:"mode"`       // Replaces the metric mode when set
+	Unit       string             `yaml:"unit"`       // Replaces the metric unit when set
+	Labels     map[string]string  `yaml:"labels"`     // Added to the metric labels
 }
 
 // ThrottleConfig represents throttle settings
@@ -41,7 +68,8 @@ type ThrottleConfig struct {
 
 // AlertLevel represents alert configuration for a severity level
 type AlertLevel struct {
-	Actions []map[string]interface{} `yaml:"actions"`
+	Actions    []map[string]interface{} `yaml:"actions"`
+	Escalation []EscalationStep         `yaml:"escalation"` // Steps run while the violation persists
 }
 
 // DefaultConfig returns the default configuration
@@ -133,6 +161,13 @@ func LoadConfig(configPath string) (*Config, error) {
 		return nil, err
 	}
 
+	// Convert size thresholds such as "10GiB" into numbers in the metric's unit
+	data, err = normalizeConfigData(data)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "ERROR: %v\n", err)
+		return nil, err
+	}
+
 	// Parse user config
 	userConfig := &Config{}
 	if err := yaml.Unmarshal(data, userConfig); err != nil {
@@ -154,6 +189,24 @@ func LoadConfig(configPath string) (*Config, error) {
 	return config, nil
 }
 
+// normalizeConfigData rewrites unit-suffixed thresholds in the raw YAML config
+func normalizeConfigData(data []byte) ([]byte, error) {
+	var raw map[interface{}]interface{}
+	if err := yaml.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("error parsing config file: %w", err)
+	}
+
+	if err := normalizeThresholdUnits(raw); err != nil {
+		return nil, fmt.Errorf("config validation failed: %w", err)
+	}
+
+	normalized, err := yaml.Marshal(raw)
+	if err != nil {
+		return nil, fmt.Errorf("error re-encoding config file: %w", err)
+	}
+	return normalized, nil
+}
+
 // keyToString converts an interface{} key to string for validation
 func keyToString(key interface{}) (string, bool) {
 	if str, ok := key.(string); ok {
@@ -174,8 +227,13 @@ func validateYAMLStructure(data []byte) error {
 		return fmt.Errorf("config must be a YAML map")
 	}
 
-	// Top-level keys should only be "metrics", "alerts", and "rrd_path"
-	allowedTopLevel := map[string]bool{"metrics": true, "alerts": true, "rrd_path": true}
+	// Top-level keys should only be "metrics", "alerts", "rules", "rrd_path", "spool", "dispatch",
+	// "labels", "receivers", "route", "grouping", "maintenance", "mqtt", "alertmanager", and "api"
+	allowedTopLevel := map[string]bool{
+		"metrics": true, "alerts": true, "rules": true, "rrd_path": true, "spool": true, "dispatch": true,
+		"labels": true, "receivers": true, "route": true, "grouping": true, "maintenance": true, "mqtt": true,
+		"alertmanager": true, "api": true,
+	}
 	for key := range rawMap {
 		keyStr, ok := keyToString(key)
 		if !ok {
@@ -210,7 +268,8 @@ func validateYAMLStructure(data []byte) error {
 
 			allowedMetricFields := map[string]bool{
 				"enabled": true, "thresholds": true, "throttle": true,
-				"mode": true, "unit": true, "exclude": true,
+				"mode": true, "unit": true, "exclude": true, "anomaly": true,
+				"overrides": true, "schedules": true, "labels": true,
 			}
 			for fieldKey := range metricConfig {
 				fieldName, ok := keyToString(fieldKey)
@@ -257,6 +316,216 @@ func validateYAMLStructure(data []byte) error {
 					}
 				}
 			}
+
+			// Validate override fields
+			if overridesVal, ok := metricConfig["overrides"]; ok {
+				overridesRaw, ok := overridesVal.([]interface{})
+				if !ok {
+					return fmt.Errorf("overrides of metric '%s' must be a list", metricNameStr)
+				}
+				allowedOverrideFields := map[string]bool{
+					"mountpoint": true, "device": true, "thresholds": true,
+					"throttle": true, "mode": true, "unit": true, "labels": true,
+				}
+				allowedThrottleFields := map[string]bool{
+					"min_duration_minutes": true, "repeat": true, "repeat_interval": true,
+				}
+				for i, overrideVal := range overridesRaw {
+					overrideRaw, ok := overrideVal.(map[interface{}]interface{})
+					if !ok {
+						return fmt.Errorf("override %d of metric '%s' must be a map", i, metricNameStr)
+					}
+					where := fmt.Sprintf("override %d of metric '%s'", i, metricNameStr)
+					if err := validateAllowedFields(overrideRaw, allowedOverrideFields, where); err != nil {
+						return err
+					}
+					if throttleRaw, ok := overrideRaw["throttle"].(map[interface{}]interface{}); ok {
+						if err := validateAllowedFields(throttleRaw, allowedThrottleFields, "throttle config of "+where); err != nil {
+							return err
+						}
+					}
+				}
+			}
+
+			// Validate schedule fields
+			if schedulesVal, ok := metricConfig["schedules"]; ok {
+				schedulesRaw, ok := schedulesVal.([]interface{})
+				if !ok {
+					return fmt.Errorf("schedules of metric '%s' must be a list", metricNameStr)
+				}
+				allowedScheduleFields := map[string]bool{
+					"name": true, "days": true, "start": true, "end": true,
+					"cron": true, "timezone": true, "thresholds": true,
+				}
+				for i, scheduleVal := range schedulesRaw {
+					scheduleRaw, ok := scheduleVal.(map[interface{}]interface{})
+					if !ok {
+						return fmt.Errorf("schedule %d of metric '%s' must be a map", i, metricNameStr)
+					}
+					where := fmt.Sprintf("schedule %d of metric '%s'", i, metricNameStr)
+					if err := validateAllowedFields(scheduleRaw, allowedScheduleFields, where); err != nil {
+						return err
+					}
+				}
+			}
+
+			// Validate anomaly fields
+			if anomalyVal, ok := metricConfig["anomaly"]; ok {
+				if anomalyRaw, ok := anomalyVal.(map[interface{}]interface{}); ok {
+					allowedAnomalyFields := map[string]bool{
+						"season": true, "history_days": true, "min_samples": true,
+						"min_deviation": true, "direction": true, "relearn_interval": true,
+					}
+					for fieldKey := range anomalyRaw {
+						fieldName, ok := keyToString(fieldKey)
+						if !ok {
+							continue
+						}
+						if !allowedAnomalyFields[fieldName] {
+							return fmt.Errorf("unknown field '%s' in anomaly config of metric '%s'", fieldName, metricNameStr)
+						}
+					}
+				}
+			}
+		}
+	}
+
+	// Validate rules section
+	if rulesVal, ok := rawMap["rules"]; ok {
+		rulesRaw, ok := rulesVal.([]interface{})
+		if !ok {
+			return fmt.Errorf("rules must be a list")
+		}
+		allowedRuleFields := map[string]bool{
+			"name": true, "expr": true, "level": true,
+			"message": true, "value": true, "throttle": true, "labels": true,
+		}
+		allowedThrottleFields := map[string]bool{
+			"min_duration_minutes": true, "repeat": true, "repeat_interval": true,
+		}
+		for i, ruleVal := range rulesRaw {
+			ruleRaw, ok := ruleVal.(map[interface{}]interface{})
+			if !ok {
+				return fmt.Errorf("rule %d must be a map", i)
+			}
+			where := fmt.Sprintf("rule %d", i)
+			if err := validateAllowedFields(ruleRaw, allowedRuleFields, where); err != nil {
+				return err
+			}
+			if throttleRaw, ok := ruleRaw["throttle"].(map[interface{}]interface{}); ok {
+				if err := validateAllowedFields(throttleRaw, allowedThrottleFields, "throttle config of "+where); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	// Validate spool section
+	if spoolVal, ok := rawMap["spool"]; ok {
+		spoolRaw, ok := spoolVal.(map[interface{}]interface{})
+		if !ok {
+			return fmt.Errorf("spool must be a map")
+		}
+		allowedSpoolFields := map[string]bool{
+			"enabled": true, "dir": true, "max_age": true, "backoff": true, "max_backoff": true,
+		}
+		if err := validateAllowedFields(spoolRaw, allowedSpoolFields, "spool config"); err != nil {
+			return err
+		}
+	}
+
+	// Validate dispatch section
+	if dispatchVal, ok := rawMap["dispatch"]; ok {
+		dispatchRaw, ok := dispatchVal.(map[interface{}]interface{})
+		if !ok {
+			return fmt.Errorf("dispatch must be a map")
+		}
+		allowedDispatchFields := map[string]bool{"workers": true, "timeout": true}
+		if err := validateAllowedFields(dispatchRaw, allowedDispatchFields, "dispatch config"); err != nil {
+			return err
+		}
+	}
+
+	// Validate grouping section
+	if groupingVal, ok := rawMap["grouping"]; ok {
+		groupingRaw, ok := groupingVal.(map[interface{}]interface{})
+		if !ok {
+			return fmt.Errorf("grouping must be a map")
+		}
+		allowedGroupingFields := map[string]bool{
+			"group_by": true, "group_wait": true, "group_interval": true, "digest": true,
+		}
+		if err := validateAllowedFields(groupingRaw, allowedGroupingFields, "grouping config"); err != nil {
+			return err
+		}
+		if digestRaw, ok := groupingRaw["digest"].(map[interface{}]interface{}); ok {
+			allowedDigestFields := map[string]bool{"enabled": true, "time": true}
+			if err := validateAllowedFields(digestRaw, allowedDigestFields, "digest config"); err != nil {
+				return err
+			}
+		}
+	}
+
+	// Validate maintenance section
+	if maintenanceVal, ok := rawMap["maintenance"]; ok {
+		windowsRaw, ok := maintenanceVal.([]interface{})
+		if !ok {
+			return fmt.Errorf("maintenance must be a list")
+		}
+		allowedWindowFields := map[string]bool{
+			"name": true, "start": true, "end": true, "cron": true, "duration": true,
+			"timezone": true, "metrics": true, "labels": true,
+		}
+		for i, windowVal := range windowsRaw {
+			windowRaw, ok := windowVal.(map[interface{}]interface{})
+			if !ok {
+				return fmt.Errorf("maintenance window %d must be a map", i)
+			}
+			if err := validateAllowedFields(windowRaw, allowedWindowFields, fmt.Sprintf("maintenance window %d", i)); err != nil {
+				return err
+			}
+		}
+	}
+
+	// Validate mqtt section
+	if mqttVal, ok := rawMap["mqtt"]; ok {
+		mqttRaw, ok := mqttVal.(map[interface{}]interface{})
+		if !ok {
+			return fmt.Errorf("mqtt must be a map")
+		}
+		allowedMQTTFields := map[string]bool{
+			"enabled": true, "broker": true, "client_id": true, "username": true, "password": true,
+			"ca_file": true, "insecure_skip_verify": true, "qos": true, "retain": true, "timeout": true,
+			"metrics_topic": true, "status_topic": true,
+		}
+		if err := validateAllowedFields(mqttRaw, allowedMQTTFields, "mqtt config"); err != nil {
+			return err
+		}
+	}
+
+	// Validate alertmanager section
+	if amVal, ok := rawMap["alertmanager"]; ok {
+		amRaw, ok := amVal.(map[interface{}]interface{})
+		if !ok {
+			return fmt.Errorf("alertmanager must be a map")
+		}
+		allowedAlertmanagerFields := map[string]bool{
+			"enabled": true, "urls": true, "username": true, "password": true, "repost_interval": true,
+			"resolve_timeout": true, "timeout": true, "generator_url": true,
+		}
+		if err := validateAllowedFields(amRaw, allowedAlertmanagerFields, "alertmanager config"); err != nil {
+			return err
+		}
+	}
+
+	// Validate api section
+	if apiVal, ok := rawMap["api"]; ok {
+		apiRaw, ok := apiVal.(map[interface{}]interface{})
+		if !ok {
+			return fmt.Errorf("api must be a map")
+		}
+		if err := validateAllowedFields(apiRaw, map[string]bool{"token": true}, "api config"); err != nil {
+			return err
 		}
 	}
 
@@ -282,7 +551,7 @@ func validateYAMLStructure(data []byte) error {
 				return fmt.Errorf("alert level '%s' configuration must be a map", levelName)
 			}
 
-			allowedLevelFields := map[string]bool{"actions": true}
+			allowedLevelFields := map[string]bool{"actions": true, "escalation": true}
 			for fieldKey := range alertLevel {
 				fieldName, ok := keyToString(fieldKey)
 				if !ok {
@@ -294,43 +563,157 @@ func validateYAMLStructure(data []byte) error {
 			}
 
 			// Validate alert actions
-			if actionsVal, ok := alertLevel["actions"]; ok {
-				if actionsRaw, ok := actionsVal.([]interface{}); ok {
-					for i, actionRaw := range actionsRaw {
-						action, ok := actionRaw.(map[interface{}]interface{})
-						if !ok {
-							continue
-						}
+			if err := validateActionFields(alertLevel["actions"], fmt.Sprintf("level '%s'", levelName)); err != nil {
+				return err
+			}
 
-						allowedActionFields := map[string]bool{
-							"type": true, "level": true, "tag": true, "facility": true,
-							"priority": true, "url": true, "timeout": true, "retry": true,
-							"path": true, "args": true,
-						}
-						for fieldKey := range action {
-							fieldName, ok := keyToString(fieldKey)
-							if !ok {
-								continue
-							}
-							if !allowedActionFields[fieldName] {
-								return fmt.Errorf("unknown field '%s' in alert action %d of level '%s'", fieldName, i, levelName)
-							}
-						}
+			// Validate escalation steps
+			if escalationVal, ok := alertLevel["escalation"]; ok {
+				stepsRaw, ok := escalationVal.([]interface{})
+				if !ok {
+					return fmt.Errorf("escalation of level '%s' must be a list", levelName)
+				}
+				for i, stepVal := range stepsRaw {
+					where := fmt.Sprintf("escalation step %d of level '%s'", i+1, levelName)
+					stepRaw, ok := stepVal.(map[interface{}]interface{})
+					if !ok {
+						return fmt.Errorf("%s must be a map", where)
+					}
+					if err := validateAllowedFields(stepRaw, map[string]bool{"after": true, "actions": true}, where); err != nil {
+						return err
+					}
+					if err := validateActionFields(stepRaw["actions"], where); err != nil {
+						return err
 					}
 				}
 			}
 		}
 	}
 
+	// Validate receivers section
+	if receiversVal, ok := rawMap["receivers"]; ok {
+		receiversRaw, ok := receiversVal.([]interface{})
+		if !ok {
+			return fmt.Errorf("receivers must be a list")
+		}
+		allowedReceiverFields := map[string]bool{"name": true, "actions": true}
+		for i, receiverVal := range receiversRaw {
+			receiverRaw, ok := receiverVal.(map[interface{}]interface{})
+			if !ok {
+				return fmt.Errorf("receiver %d must be a map", i)
+			}
+			where := fmt.Sprintf("receiver %d", i)
+			if err := validateAllowedFields(receiverRaw, allowedReceiverFields, where); err != nil {
+				return err
+			}
+			if err := validateActionFields(receiverRaw["actions"], where); err != nil {
+				return err
+			}
+		}
+	}
+
+	// Validate route section
+	if routeVal, ok := rawMap["route"]; ok {
+		if err := validateRouteFields(routeVal, "route"); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// validateActionFields checks that raw alert actions only contain the fields of their type
+func validateActionFields(actionsVal interface{}, where string) error {
+	actionsRaw, ok := actionsVal.([]interface{})
+	if !ok {
+		return nil
+	}
+	for i, actionRaw := range actionsRaw {
+		action, ok := actionRaw.(map[interface{}]interface{})
+		if !ok {
+			continue
+		}
+
+		// Unknown types are reported by validateActions
+		actionType, _ := action["type"].(string)
+		fields, ok := actionFields[actionType]
+		if !ok {
+			continue
+		}
+		allowedActionFields := map[string]bool{"type": true, "level": true}
+		for _, field := range fields {
+			allowedActionFields[field] = true
+		}
+		for fieldKey := range action {
+			fieldName, ok := keyToString(fieldKey)
+			if !ok {
+				continue
+			}
+			if !allowedActionFields[fieldName] {
+				return fmt.Errorf("unknown field '%s' in %s alert action %d of %s", fieldName, actionType, i, where)
+			}
+		}
+	}
+	return nil
+}
+
+// validateRouteFields checks the fields of a raw route and its children
+func validateRouteFields(routeVal interface{}, where string) error {
+	routeRaw, ok := routeVal.(map[interface{}]interface{})
+	if !ok {
+		return fmt.Errorf("%s must be a map", where)
+	}
+	allowedRouteFields := map[string]bool{
+		"receiver": true, "match": true, "match_re": true, "continue": true, "routes": true,
+	}
+	if err := validateAllowedFields(routeRaw, allowedRouteFields, where); err != nil {
+		return err
+	}
+	if routesVal, ok := routeRaw["routes"]; ok {
+		routesRaw, ok := routesVal.([]interface{})
+		if !ok {
+			return fmt.Errorf("routes of %s must be a list", where)
+		}
+		for i, childVal := range routesRaw {
+			if err := validateRouteFields(childVal, fmt.Sprintf("%s.routes[%d]", where, i)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// validateAllowedFields checks that a raw YAML map only contains allowed keys
+func validateAllowedFields(raw map[interface{}]interface{}, allowed map[string]bool, where string) error {
+	for fieldKey := range raw {
+		fieldName, ok := keyToString(fieldKey)
+		if !ok {
+			continue
+		}
+		if !allowed[fieldName] {
+			return fmt.Errorf("unknown field '%s' in %s", fieldName, where)
+		}
+	}
 	return nil
 }
 
 // deepMergeConfig merges user config with defaults
 func deepMergeConfig(defaults, overrides *Config) *Config {
 	result := &Config{
-		Metrics: make(map[string]MetricConfig),
-		Alerts:  make(map[string]AlertLevel),
-		RRDPath: defaults.RRDPath,
+		Metrics:      make(map[string]MetricConfig),
+		Alerts:       make(map[string]AlertLevel),
+		Rules:        defaults.Rules,
+		RRDPath:      defaults.RRDPath,
+		Spool:        defaults.Spool,
+		Dispatch:     defaults.Dispatch,
+		Labels:       defaults.Labels,
+		Receivers:    defaults.Receivers,
+		Route:        defaults.Route,
+		Grouping:     defaults.Grouping,
+		Maintenance:  defaults.Maintenance,
+		MQTT:         defaults.MQTT,
+		Alertmanager: defaults.Alertmanager,
+		API:          defaults.API,
 	}
 
 	// Copy defaults
@@ -349,10 +732,43 @@ func deepMergeConfig(defaults, overrides *Config) *Config {
 		for k, v := range overrides.Alerts {
 			result.Alerts[k] = v
 		}
+		if overrides.Rules != nil {
+			result.Rules = overrides.Rules
+		}
 		// Override rrd_path if provided in config
 		if overrides.RRDPath != "" {
 			result.RRDPath = overrides.RRDPath
 		}
+		if overrides.Spool != (SpoolConfig{}) {
+			result.Spool = overrides.Spool
+		}
+		if overrides.Dispatch != (DispatchConfig{}) {
+			result.Dispatch = overrides.Dispatch
+		}
+		if overrides.Labels != nil {
+			result.Labels = overrides.Labels
+		}
+		if overrides.Receivers != nil {
+			result.Receivers = overrides.Receivers
+		}
+		if overrides.Route != nil {
+			result.Route = overrides.Route
+		}
+		if overrides.Grouping.enabled() {
+			result.Grouping = overrides.Grouping
+		}
+		if overrides.Maintenance != nil {
+			result.Maintenance = overrides.Maintenance
+		}
+		if overrides.MQTT != (MQTTConfig{}) {
+			result.MQTT = overrides.MQTT
+		}
+		if overrides.Alertmanager.Enabled || overrides.Alertmanager.URLs != nil {
+			result.Alertmanager = overrides.Alertmanager
+		}
+		if overrides.API != (APIConfig{}) {
+			result.API = overrides.API
+		}
 	}
 
 	return result
@@ -371,6 +787,46 @@ func validateConfig(config *Config) error {
 		}
 	}
 
+	// Validate rules
+	if err := validateRules(config.Rules); err != nil {
+		return err
+	}
+
+	// Validate spool
+	if _, err := NewSpool(config.Spool); err != nil {
+		return err
+	}
+
+	// Validate dispatch
+	if err := config.Dispatch.validate(); err != nil {
+		return err
+	}
+
+	// Validate grouping
+	if err := config.Grouping.validate(); err != nil {
+		return err
+	}
+
+	// Validate maintenance windows
+	if err := validateMaintenance(config.Maintenance); err != nil {
+		return err
+	}
+
+	// Validate mqtt publisher
+	if err := config.MQTT.validate(); err != nil {
+		return err
+	}
+
+	// Validate alertmanager push
+	if err := config.Alertmanager.validate(); err != nil {
+		return err
+	}
+
+	// Validate receivers and routes
+	if err := validateRouting(config); err != nil {
+		return err
+	}
+
 	// Validate alerts
 	if config.Alerts != nil {
 		for level, alertLevel := range config.Alerts {
@@ -401,9 +857,107 @@ func validateMetricConfig(metricName string, config MetricConfig) error {
 		return fmt.Errorf("metric %s 'min_duration_minutes' must be >= 0", metricName)
 	}
 
+	// Validate unit
+	if err := validateUnit(config.Unit); err != nil {
+		return fmt.Errorf("metric %s: %w", metricName, err)
+	}
+	if !isPercentageUnit(config.Unit) && metricName == "cpu" {
+		return fmt.Errorf("metric cpu only supports unit 'percentage'")
+	}
+	if !isPercentageUnit(config.Unit) && config.Mode == "anomaly" {
+		return fmt.Errorf("metric %s mode 'anomaly' only supports unit 'percentage'", metricName)
+	}
+
+	// Validate disk mode
+	if metricName == "disk" && config.Mode != "" && config.Mode != "max_used" && config.Mode != "min_free" {
+		return fmt.Errorf("disk metric 'mode' must be 'max_used' or 'min_free'")
+	}
+
+	// Validate disk overrides
+	if len(config.Overrides) > 0 && metricName != "disk" {
+		return fmt.Errorf("metric %s does not support 'overrides'", metricName)
+	}
+	for i, override := range config.Overrides {
+		if err := validateDiskOverride(i, override); err != nil {
+			return err
+		}
+	}
+
+	// Validate schedules
+	for i, schedule := range config.Schedules {
+		if err := validateSchedule(metricName, i, schedule); err != nil {
+			return err
+		}
+	}
+
 	// Validate memory mode
-	if metricName == "memory" && config.Mode != "" && config.Mode != "min_free" && config.Mode != "max_used" {
-		return fmt.Errorf("memory metric 'mode' must be 'min_free' or 'max_used'")
+	if metricName == "memory" && config.Mode != "" && config.Mode != "min_free" && config.Mode != "max_used" && config.Mode != "anomaly" {
+		return fmt.Errorf("memory metric 'mode' must be 'min_free', 'max_used' or 'anomaly'")
+	}
+
+	// Validate cpu mode
+	if metricName == "cpu" && config.Mode != "" && config.Mode != "anomaly" {
+		return fmt.Errorf("cpu metric 'mode' must be 'anomaly' if set")
+	}
+
+	// Validate anomaly settings
+	if config.Mode == "anomaly" {
+		if err := validateAnomalyConfig(metricName, config.Anomaly); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// validateDiskOverride validates a single disk override rule
+func validateDiskOverride(index int, override DiskOverride) error {
+	if override.Mountpoint == "" && override.Device == "" {
+		return fmt.Errorf("disk override %d needs a 'mountpoint' or 'device' pattern", index)
+	}
+	for _, pattern := range []string{override.Mountpoint, override.Device} {
+		if _, err := filepath.Match(pattern, ""); err != nil {
+			return fmt.Errorf("disk override %d has invalid pattern '%s': %w", index, pattern, err)
+		}
+	}
+	for level, value := range override.Thresholds {
+		if value < 0 {
+			return fmt.Errorf("disk override %d threshold %s must be >= 0", index, level)
+		}
+	}
+	if override.Unit != "" && override.Thresholds == nil {
+		return fmt.Errorf("disk override %d sets 'unit' without 'thresholds'", index)
+	}
+	if err := validateUnit(override.Unit); err != nil {
+		return fmt.Errorf("disk override %d: %w", index, err)
+	}
+	if override.Mode != "" && override.Mode != "max_used" && override.Mode != "min_free" {
+		return fmt.Errorf("disk override %d 'mode' must be 'max_used' or 'min_free'", index)
+	}
+	if override.Throttle != nil && override.Throttle.MinDurationMinutes < 0 {
+		return fmt.Errorf("disk override %d 'min_duration_minutes' must be >= 0", index)
+	}
+	return nil
+}
+
+// validateAnomalyConfig validates anomaly mode settings of a metric
+func validateAnomalyConfig(metricName string, config AnomalyConfig) error {
+	if metricName != "cpu" && metricName != "memory" {
+		return fmt.Errorf("metric %s does not support mode 'anomaly' (no RRD history)", metricName)
+	}
+
+	anomaly := config.withDefaults()
+	if anomaly.Season != SeasonHourOfDay && anomaly.Season != SeasonHourOfWeek {
+		return fmt.Errorf("metric %s anomaly 'season' must be '%s' or '%s'", metricName, SeasonHourOfDay, SeasonHourOfWeek)
+	}
+	if anomaly.Direction != "above" && anomaly.Direction != "below" && anomaly.Direction != "both" {
+		return fmt.Errorf("metric %s anomaly 'direction' must be 'above', 'below' or 'both'", metricName)
+	}
+	if _, err := parseDuration(anomaly.RelearnInterval); err != nil {
+		return fmt.Errorf("metric %s anomaly 'relearn_interval' is invalid: %w", metricName, err)
+	}
+	if config.HistoryDays < 0 || config.MinSamples < 0 || config.MinDeviation < 0 {
+		return fmt.Errorf("metric %s anomaly settings must be >= 0", metricName)
 	}
 
 	return nil
@@ -416,30 +970,29 @@ func validateAlertLevel(level string, alertLevel AlertLevel) error {
 		return fmt.Errorf("invalid alert level '%s'", level)
 	}
 
-	if alertLevel.Actions == nil {
+	if alertLevel.Actions == nil && len(alertLevel.Escalation) == 0 {
 		return fmt.Errorf("alert level '%s' missing 'actions' field", level)
 	}
 
-	// Validate each action
-	for i, action := range alertLevel.Actions {
+	if err := validateActions(fmt.Sprintf("level '%s'", level), alertLevel.Actions); err != nil {
+		return err
+	}
+	return validateEscalation(level, alertLevel.Escalation)
+}
+
+// validateActions validates the alert actions of a level or receiver
+func validateActions(where string, actions []map[string]interface{}) error {
+	for i, action := range actions {
 		if actionType, ok := action["type"]; !ok {
-			return fmt.Errorf("alert action %d for level '%s' missing 'type' field", i, level)
+			return fmt.Errorf("alert action %d for %s missing 'type' field", i, where)
 		} else if actionTypeStr, ok := actionType.(string); ok {
-			validTypes := map[string]bool{"logger": true, "syslog": true, "webhook": true, "script": true, "stdout": true}
-			if !validTypes[actionTypeStr] {
+			if _, ok := actionFields[actionTypeStr]; !ok {
 				return fmt.Errorf("alert action type '%s' not supported", actionTypeStr)
 			}
 
-			// Validate required fields
-			if actionTypeStr == "webhook" {
-				if _, ok := action["url"]; !ok {
-					return fmt.Errorf("alert action 'webhook' missing required 'url' field")
-				}
-			}
-			if actionTypeStr == "script" {
-				if _, ok := action["path"]; !ok {
-					return fmt.Errorf("alert action 'script' missing required 'path' field")
-				}
+			// Validate the action's settings with its constructor
+			if _, err := CreateAction(action); err != nil {
+				return fmt.Errorf("alert action '%s' is invalid: %w", actionTypeStr, err)
 			}
 		}
 	}
@@ -459,14 +1012,29 @@ func (c *Config) IsMetricEnabled(metricName string) bool {
 	return ok && mc.Enabled
 }
 
-// GetThrottleConfig gets throttle configuration for a metric
+// GetThrottleConfig gets throttle configuration for a metric or rule
 func (c *Config) GetThrottleConfig(metricName string) ThrottleConfig {
 	if mc, ok := c.Metrics[metricName]; ok {
 		return mc.Throttle
 	}
+	if rule, ok := c.GetRule(metricName); ok {
+		return rule.Throttle
+	}
 	return ThrottleConfig{MinDurationMinutes: 0, Repeat: false}
 }
 
+// GetViolationThrottleConfig gets the throttle configuration that applies to a violation,
+// taking disk overrides for its partition into account
+func (c *Config) GetViolationThrottleConfig(violation ThresholdViolation) ThrottleConfig {
+	if violation.Metric == "disk" && (violation.Mountpoint != "" || violation.Device != "") {
+		if mc, ok := c.Metrics["disk"]; ok {
+			partition := PartitionInfo{Device: violation.Device, Mountpoint: violation.Mountpoint}
+			return mc.forPartition(partition).Throttle
+		}
+	}
+	return c.GetThrottleConfig(violation.Metric)
+}
+
 // GetAlertActions gets alert actions for a specific level
 func (c *Config) GetAlertActions(level string) []map[string]interface{} {
 	if alertLevel, ok := c.Alerts[level]; ok {
@@ -0,0 +1,92 @@
+package monitor
+
+import (
+	"testing"
+	"time"
+)
+
+// TestCronMatches tests matching times against cron expressions
+func TestCronMatches(t *testing.T) {
+	// 2024-01-01 is a Monday
+	at := func(day, hour, minute int) time.Time {
+		return time.Date(2024, 1, day, hour, minute, 0, 0, time.UTC)
+	}
+
+	tests := []struct {
+		name string
+		expr string
+		time time.Time
+		want bool
+	}{
+		{name: "every minute", expr: "* * * * *", time: at(1, 13, 37), want: true},
+		{name: "hour range inside", expr: "* 1-3 * * *", time: at(1, 3, 59), want: true},
+		{name: "hour range outside", expr: "* 1-3 * * *", time: at(1, 4, 0), want: false},
+		{name: "step", expr: "*/15 * * * *", time: at(1, 10, 45), want: true},
+		{name: "step miss", expr: "*/15 * * * *", time: at(1, 10, 44), want: false},
+		{name: "list", expr: "0,30 * * * *", time: at(1, 10, 30), want: true},
+		{name: "weekday names", expr: "* * * * mon-fri", time: at(6, 12, 0), want: false},
+		{name: "weekday names match", expr: "* * * * mon-fri", time: at(5, 12, 0), want: true},
+		{name: "sunday as 7", expr: "* * * * 7", time: at(7, 12, 0), want: true},
+		{name: "month name", expr: "* * * jan *", time: at(1, 0, 0), want: true},
+		{name: "day of month or weekday", expr: "* * 15 * sat", time: at(6, 0, 0), want: true},
+		{name: "day of month and star weekday", expr: "* * 15 * *", time: at(6, 0, 0), want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cron, err := ParseCron(tt.expr)
+			if err != nil {
+				t.Fatalf("ParseCron() error = %v", err)
+			}
+			if got := cron.Matches(tt.time); got != tt.want {
+				t.Errorf("Matches(%s) = %v, want %v", tt.time, got, tt.want)
+			}
+		})
+	}
+}
+
+// TestParseCronErrors tests rejecting malformed cron expressions
+func TestParseCronErrors(t *testing.T) {
+	for _, expr := range []string{"* * * *", "60 * * * *", "* 24 * * *", "* * 0 * *", "*/0 * * * *", "5-1 * * * *", "* * * * funday"} {
+		if _, err := ParseCron(expr); err == nil {
+			t.Errorf("ParseCron(%q) expected error", expr)
+		}
+	}
+}
+
+// TestCronPrev tests finding the latest matching minute against scanning back minute by minute
+func TestCronPrev(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Skip("timezone data not available")
+	}
+	exprs := []string{"0 3 * * sun", "30 23 * * *", "*/15 * * * *", "0 0 1 * *", "5,50 2-4 * * mon-fri", "0 12 29 feb *"}
+	times := []time.Time{
+		time.Date(2024, 6, 3, 0, 15, 0, 0, berlin),
+		time.Date(2024, 3, 31, 3, 10, 0, 0, berlin),  // after the spring daylight saving change
+		time.Date(2024, 10, 27, 2, 40, 0, 0, berlin), // within the repeated autumn hour
+		time.Date(2024, 3, 4, 12, 0, 0, 0, berlin),
+	}
+
+	for _, expr := range exprs {
+		cron, err := ParseCron(expr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, now := range times {
+			earliest := now.Add(-maxMaintenanceDuration)
+			var want time.Time
+			for start := now.Truncate(time.Minute); !start.Before(earliest); start = start.Add(-time.Minute) {
+				if cron.Matches(start) {
+					want = start
+					break
+				}
+			}
+
+			got, ok := cron.Prev(now, earliest)
+			if ok != !want.IsZero() || !got.Equal(want) {
+				t.Errorf("%q Prev(%s) = %s, %v, want %s", expr, now, got, ok, want)
+			}
+		}
+	}
+}
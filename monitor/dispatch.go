@@ -0,0 +1,383 @@
+package monitor
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Dispatch defaults
+const (
+	defaultDispatchWorkers = 4
+	defaultDispatchTimeout = 60 * time.Second
+
+	// dispatchCancelGrace is how long a timed-out action may take to return after its
+	// context was cancelled
+	dispatchCancelGrace = 5 * time.Second
+)
+
+// DispatchConfig configures how alert actions are run
+type DispatchConfig struct {
+	Workers int    `yaml:"workers"` // Actions run in parallel (default: 4)
+	Timeout string `yaml:"timeout"` // Time limit of each action (default: "60s")
+}
+
+// DeliveryResult reports the outcome of one action for the violations of one level
+type DeliveryResult struct {
+	Action     string `json:"action"`             // action type
+	Receiver   string `json:"receiver,omitempty"` // receiver selected by the route, if routing is configured
+	Level      string `json:"level"`
+	Step       int    `json:"escalation_step,omitempty"` // escalation step the action belongs to
+	Resolved   bool   `json:"resolved,omitempty"`        // true for resolved updates
+	Violations int    `json:"violations"`
+	Status     string `json:"status"` // "sent", "spooled" or "failed"
+	Error      string `json:"error,omitempty"`
+	DurationMs int64  `json:"duration_ms"`
+
+	err error
+}
+
+// Err returns the delivery error, or nil
+func (r DeliveryResult) Err() error {
+	return r.err
+}
+
+// contextAction is implemented by actions whose network I/O, or script, stops when the
+// context of their dispatch is cancelled
+type contextAction interface {
+	setContext(ctx context.Context)
+}
+
+// actionContext returns ctx, or the background context for an action run without one
+func actionContext(ctx context.Context) context.Context {
+	if ctx == nil {
+		return context.Background()
+	}
+	return ctx
+}
+
+// dispatchJob is one action to run for the violations of a level
+type dispatchJob struct {
+	receiver     string
+	level        string
+	step         int  // escalation step, 0 for regular alerts
+	resolved     bool // send resolved updates instead of alerts
+	index        int  // position of the action in its receiver, level or escalation step
+	actionConfig map[string]interface{}
+	violations   []ThresholdViolation
+	deliveries   map[string][]DeliveryResult // results of the other jobs per violation, for recording actions
+	abandoned    *atomic.Bool                // set when the dispatcher stopped waiting for the action
+}
+
+// workers returns the configured worker count
+func (dc DispatchConfig) workers() int {
+	if dc.Workers > 0 {
+		return dc.Workers
+	}
+	return defaultDispatchWorkers
+}
+
+// timeout returns the configured per-action time limit
+func (dc DispatchConfig) timeout() (time.Duration, error) {
+	if dc.Timeout == "" {
+		return defaultDispatchTimeout, nil
+	}
+	d, err := parseDuration(dc.Timeout)
+	if err != nil {
+		return 0, err
+	}
+	if d <= 0 {
+		return 0, fmt.Errorf("dispatch timeout must be positive")
+	}
+	return d, nil
+}
+
+// validate checks the dispatch settings
+func (dc DispatchConfig) validate() error {
+	if dc.Workers < 0 {
+		return fmt.Errorf("dispatch workers must not be negative")
+	}
+	_, err := dc.timeout()
+	return err
+}
+
+// DispatchViolations runs every configured action for the violations independently,
+// in parallel on a bounded worker pool, and returns one result per action.
+// A failing or hanging action does not affect the others.
+func DispatchViolations(config *Config, warningViolations []ThresholdViolation, criticalViolations []ThresholdViolation) []DeliveryResult {
+	var jobs []dispatchJob
+	for _, level := range []struct {
+		name       string
+		violations []ThresholdViolation
+	}{
+		{"critical", criticalViolations},
+		{"warning", warningViolations},
+	} {
+		if len(level.violations) == 0 {
+			continue
+		}
+		log.Printf("Processing %d %s violations", len(level.violations), level.name)
+		for _, batch := range config.routeViolations(level.name, level.violations) {
+			for i, actionConfig := range batch.actions {
+				jobs = append(jobs, dispatchJob{
+					receiver:     batch.receiver,
+					level:        level.name,
+					index:        i,
+					actionConfig: actionConfig,
+					violations:   batch.violations,
+				})
+			}
+		}
+	}
+	return runDispatchJobs(config, mergeBatchJobs(jobs))
+}
+
+// DispatchResolved sends resolved updates through the actions that the violations are
+// routed to and that support them, like DispatchViolations, and returns one result per action
+func DispatchResolved(config *Config, resolved []ThresholdViolation) []DeliveryResult {
+	byLevel := make(map[string][]ThresholdViolation)
+	for _, violation := range resolved {
+		byLevel[violation.Level] = append(byLevel[violation.Level], violation)
+	}
+
+	var jobs []dispatchJob
+	for _, level := range []string{"critical", "warning"} {
+		if len(byLevel[level]) == 0 {
+			continue
+		}
+		log.Printf("Processing %d resolved %s violations", len(byLevel[level]), level)
+		for _, batch := range config.routeViolations(level, byLevel[level]) {
+			for i, actionConfig := range batch.actions {
+				// Actions that fail to build are kept so that their error is reported
+				if action, err := CreateAction(actionConfig); err == nil {
+					if _, ok := action.(ResolvingAlertAction); !ok {
+						continue
+					}
+				}
+				jobs = append(jobs, dispatchJob{
+					receiver:     batch.receiver,
+					level:        level,
+					resolved:     true,
+					index:        i,
+					actionConfig: actionConfig,
+					violations:   batch.violations,
+				})
+			}
+		}
+	}
+	return runDispatchJobs(config, jobs)
+}
+
+// mergeBatchJobs merges the jobs of a batch action that has the same receiver and
+// configuration at several levels, so that one cycle sends one message per action.
+// The merged job keeps the level of its first, most severe, job.
+func mergeBatchJobs(jobs []dispatchJob) []dispatchJob {
+	var merged []dispatchJob
+	for _, job := range jobs {
+		if i := findBatchJob(merged, job); i >= 0 {
+			merged[i].violations = append(append([]ThresholdViolation{}, merged[i].violations...), job.violations...)
+			continue
+		}
+		merged = append(merged, job)
+	}
+	return merged
+}
+
+// findBatchJob returns the index of the job in jobs that job can be merged into, or -1
+func findBatchJob(jobs []dispatchJob, job dispatchJob) int {
+	action, err := CreateAction(job.actionConfig)
+	if err != nil {
+		return -1
+	}
+	if _, ok := action.(BatchAlertAction); !ok {
+		return -1
+	}
+	for i, other := range jobs {
+		if other.receiver == job.receiver && other.step == job.step && reflect.DeepEqual(other.actionConfig, job.actionConfig) {
+			return i
+		}
+	}
+	return -1
+}
+
+// runDispatchJobs runs jobs on the worker pool and returns their results in order
+func runDispatchJobs(config *Config, jobs []dispatchJob) []DeliveryResult {
+	if len(jobs) == 0 {
+		return nil
+	}
+
+	results := make([]DeliveryResult, len(jobs))
+
+	// Failed deliveries are queued for retry when the spool is enabled
+	var spool *Spool
+	if config.Spool.Enabled {
+		var err error
+		if spool, err = NewSpool(config.Spool); err != nil {
+			err = fmt.Errorf("failed to create spool: %w", err)
+			for i, job := range jobs {
+				results[i] = newDeliveryResult(job, 0, false, err)
+			}
+			return results
+		}
+	}
+
+	timeout, err := config.Dispatch.timeout()
+	if err != nil {
+		timeout = defaultDispatchTimeout
+	}
+
+	run := func(indices []int) {
+		sem := make(chan struct{}, config.Dispatch.workers())
+		var wg sync.WaitGroup
+		for _, i := range indices {
+			wg.Add(1)
+			sem <- struct{}{}
+			go func(i int, job dispatchJob) {
+				defer wg.Done()
+				defer func() { <-sem }()
+				results[i] = runDispatchJob(job, spool, timeout)
+			}(i, jobs[i])
+		}
+		wg.Wait()
+	}
+
+	// Recording actions run after the others, so that they can record their results
+	var delivering, recording []int
+	for i, job := range jobs {
+		if job.actionConfig["type"] == "file" {
+			recording = append(recording, i)
+		} else {
+			delivering = append(delivering, i)
+		}
+	}
+	run(delivering)
+	for _, i := range recording {
+		jobs[i].deliveries = make(map[string][]DeliveryResult)
+		for _, j := range delivering {
+			if jobs[j].step != jobs[i].step {
+				continue
+			}
+			for _, v := range jobs[j].violations {
+				key := v.StateKey()
+				jobs[i].deliveries[key] = append(jobs[i].deliveries[key], results[j])
+			}
+		}
+	}
+	run(recording)
+
+	return results
+}
+
+// runDispatchJob creates and runs one action, cancelling it after timeout
+func runDispatchJob(job dispatchJob, spool *Spool, timeout time.Duration) DeliveryResult {
+	start := time.Now()
+
+	action, err := CreateAction(job.actionConfig)
+	if err != nil {
+		err = fmt.Errorf("failed to create %s alert action: %w", job.level, err)
+		return newDeliveryResult(job, time.Since(start), false, err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	if ca, ok := action.(contextAction); ok {
+		ca.setContext(ctx)
+	}
+	job.abandoned = new(atomic.Bool)
+
+	type outcome struct {
+		spooled bool
+		err     error
+	}
+	done := make(chan outcome, 1)
+	go func() {
+		if recorder, ok := action.(RecordingAlertAction); ok && !job.resolved {
+			done <- outcome{false, recordDeliveries(recorder, job)}
+			return
+		}
+		spooled, err := deliver(spool, job, action)
+		done <- outcome{spooled, err}
+	}()
+
+	var o outcome
+	select {
+	case o = <-done:
+	case <-ctx.Done():
+		// The cancelled context stops the action; wait for it to return so that its
+		// failure is spooled. An action that does not return in time is reported as
+		// failed and abandoned: its late result is discarded and no longer spooled.
+		select {
+		case o = <-done:
+		case <-time.After(dispatchCancelGrace):
+			job.abandoned.Store(true)
+			o = outcome{err: ctx.Err()}
+		}
+	}
+	if o.err != nil && ctx.Err() != nil {
+		o.err = fmt.Errorf("%s action timed out after %v", job.actionConfig["type"], timeout)
+	}
+
+	if o.err != nil && job.resolved {
+		o.err = fmt.Errorf("failed to send resolved update: %w", o.err)
+	} else if o.err != nil {
+		o.err = fmt.Errorf("failed to execute %s alert: %w", job.level, o.err)
+	}
+	return newDeliveryResult(job, time.Since(start), o.spooled, o.err)
+}
+
+// spoolAction returns the reference to the job's action that spooled deliveries keep
+func (job dispatchJob) spoolAction() SpoolAction {
+	actionType, _ := job.actionConfig["type"].(string)
+	return SpoolAction{
+		Type:     actionType,
+		Receiver: job.receiver,
+		Level:    job.level,
+		Step:     job.step,
+		Index:    job.index,
+	}
+}
+
+// recordDeliveries records each violation of a job with the results of the other jobs
+// that delivered it
+func recordDeliveries(recorder RecordingAlertAction, job dispatchJob) error {
+	for _, violation := range job.violations {
+		if err := recorder.Record(violation, job.step, job.deliveries[violation.StateKey()]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// newDeliveryResult builds and logs the result of a job
+func newDeliveryResult(job dispatchJob, duration time.Duration, spooled bool, err error) DeliveryResult {
+	actionType, _ := job.actionConfig["type"].(string)
+	result := DeliveryResult{
+		Action:     actionType,
+		Receiver:   job.receiver,
+		Level:      job.level,
+		Step:       job.step,
+		Resolved:   job.resolved,
+		Violations: len(job.violations),
+		Status:     "sent",
+		DurationMs: duration.Milliseconds(),
+		err:        err,
+	}
+
+	switch {
+	case err != nil:
+		result.Status = "failed"
+		result.Error = err.Error()
+		log.Printf("Delivery %s/%s failed after %v: %v", job.level, actionType, duration.Round(time.Millisecond), err)
+	case spooled:
+		result.Status = "spooled"
+		log.Printf("Delivery %s/%s spooled for retry", job.level, actionType)
+	default:
+		log.Printf("Delivery %s/%s sent %d violations in %v", job.level, actionType, len(job.violations), duration.Round(time.Millisecond))
+	}
+
+	return result
+}
@@ -0,0 +1,275 @@
+package monitor
+
+import (
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// TestDispatchViolationsIsolatesFailures tests that a failing action does not stop the others
+func TestDispatchViolationsIsolatesFailures(t *testing.T) {
+	var hits atomic.Int32
+	ok := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer ok.Close()
+	broken := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer broken.Close()
+
+	config := &Config{
+		Alerts: map[string]AlertLevel{
+			"critical": {
+				Actions: []map[string]interface{}{
+					{"type": "webhook", "url": broken.URL},
+					{"type": "webhook", "url": ok.URL},
+				},
+			},
+			"warning": {
+				Actions: []map[string]interface{}{
+					{"type": "invalid_type"},
+					{"type": "webhook", "url": ok.URL},
+				},
+			},
+		},
+	}
+	critical := []ThresholdViolation{{Metric: "cpu", Level: "critical", Message: "CPU critical", Value: 99}}
+	warning := []ThresholdViolation{{Metric: "memory", Level: "warning", Message: "Memory warning", Value: 85}}
+
+	results := DispatchViolations(config, warning, critical)
+	if len(results) != 4 {
+		t.Fatalf("got %d results, want 4", len(results))
+	}
+
+	wantStatus := []string{"failed", "sent", "failed", "sent"}
+	wantLevel := []string{"critical", "critical", "warning", "warning"}
+	for i, r := range results {
+		if r.Status != wantStatus[i] || r.Level != wantLevel[i] {
+			t.Errorf("result %d = %s/%s, want %s/%s", i, r.Level, r.Status, wantLevel[i], wantStatus[i])
+		}
+		if (r.Err() != nil) != (r.Status == "failed") || (r.Error != "") != (r.Status == "failed") {
+			t.Errorf("result %d: error %q inconsistent with status %s", i, r.Error, r.Status)
+		}
+	}
+	if hits.Load() != 2 {
+		t.Errorf("healthy webhook received %d requests, want 2", hits.Load())
+	}
+
+	err := ProcessViolations(config, warning, critical)
+	if err == nil {
+		t.Fatal("ProcessViolations() expected joined error")
+	}
+	for _, want := range []string{"failed to execute critical alert", "failed to create warning alert action"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error %q does not contain %q", err, want)
+		}
+	}
+}
+
+// TestDispatchResolved tests that resolved updates run through every resolving action and
+// that failed updates are spooled
+func TestDispatchResolved(t *testing.T) {
+	var hits atomic.Int32
+	ok := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer ok.Close()
+	broken := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer broken.Close()
+
+	dir := t.TempDir()
+	config := &Config{
+		Spool: SpoolConfig{Enabled: true, Dir: dir},
+		Alerts: map[string]AlertLevel{
+			"critical": {
+				Actions: []map[string]interface{}{
+					{"type": "slack", "url": broken.URL},
+					{"type": "webhook", "url": ok.URL},
+					{"type": "slack", "url": ok.URL},
+				},
+			},
+		},
+	}
+	resolved := []ThresholdViolation{
+		{Metric: "cpu", Level: "critical", Message: "CPU critical", Value: 99},
+		{Metric: "disk", Level: "critical", Message: "disk critical", Value: 97, Mountpoint: "/"},
+	}
+
+	results := DispatchResolved(config, resolved)
+	if len(results) != 2 {
+		t.Fatalf("got %d results, want 2 (webhook does not send resolved updates)", len(results))
+	}
+	for i, want := range []string{"spooled", "sent"} {
+		if results[i].Status != want || !results[i].Resolved || results[i].Violations != 2 {
+			t.Errorf("result %d = %+v, want %s resolved update of 2 violations", i, results[i], want)
+		}
+	}
+	if hits.Load() != 2 {
+		t.Errorf("healthy slack received %d requests, want 2", hits.Load())
+	}
+
+	spool, _ := NewSpool(config.Spool)
+	entries, err := spool.load()
+	if err != nil || len(entries) != 2 {
+		t.Fatalf("spool has %d entries (err %v), want 2", len(entries), err)
+	}
+	for _, entry := range entries {
+		if !entry.Resolved || len(entry.Violations) != 1 {
+			t.Errorf("spool entry = %+v, want one resolved update", entry)
+		}
+	}
+}
+
+// TestDispatchViolationsTimeout tests the per-action time limit
+func TestDispatchViolationsTimeout(t *testing.T) {
+	release := make(chan struct{})
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer slow.Close()
+	defer close(release)
+
+	config := &Config{
+		Dispatch: DispatchConfig{Timeout: "50ms"},
+		Alerts: map[string]AlertLevel{
+			"warning": {
+				Actions: []map[string]interface{}{
+					{"type": "webhook", "url": slow.URL},
+					{"type": "stdout"},
+				},
+			},
+		},
+	}
+
+	start := time.Now()
+	results := DispatchViolations(config, []ThresholdViolation{{Metric: "cpu", Level: "warning"}}, nil)
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("dispatch took %v, want it bounded by the timeout", elapsed)
+	}
+
+	if results[0].Status != "failed" || !strings.Contains(results[0].Error, "timed out") {
+		t.Errorf("slow action result = %+v, want timeout", results[0])
+	}
+	if results[1].Status != "sent" {
+		t.Errorf("stdout action result = %+v, want sent", results[1])
+	}
+}
+
+// TestDispatchViolationsTimeoutCancels tests that a timeout cancels the action's request
+// and SMTP session, and that the cancelled delivery is spooled once
+func TestDispatchViolationsTimeoutCancels(t *testing.T) {
+	cancelled := make(chan struct{})
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.ReadAll(r.Body) // lets the server notice the client going away
+		select {
+		case <-r.Context().Done():
+			close(cancelled)
+		case <-time.After(5 * time.Second):
+		}
+	}))
+	defer slow.Close()
+
+	// An SMTP server that never greets
+	silent, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	defer silent.Close()
+	go func() {
+		var conns []net.Conn
+		defer func() {
+			for _, conn := range conns {
+				conn.Close()
+			}
+		}()
+		for {
+			conn, err := silent.Accept()
+			if err != nil {
+				return
+			}
+			conns = append(conns, conn)
+		}
+	}()
+
+	config := &Config{
+		Spool:    SpoolConfig{Enabled: true, Dir: t.TempDir()},
+		Dispatch: DispatchConfig{Timeout: "50ms"},
+		Alerts: map[string]AlertLevel{
+			"warning": {
+				Actions: []map[string]interface{}{
+					{"type": "webhook", "url": slow.URL},
+					{"type": "email", "host": "127.0.0.1", "port": silent.Addr().(*net.TCPAddr).Port, "tls": "none", "from": "monitor@example.com", "to": "ops@example.com"},
+				},
+			},
+		},
+	}
+
+	start := time.Now()
+	results := DispatchViolations(config, []ThresholdViolation{{Metric: "cpu", Level: "warning"}}, nil)
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("dispatch took %v, want the actions cancelled at the timeout", elapsed)
+	}
+	for i, result := range results {
+		if result.Status != "spooled" {
+			t.Errorf("result %d = %+v, want spooled", i, result)
+		}
+	}
+
+	select {
+	case <-cancelled:
+	case <-time.After(2 * time.Second):
+		t.Error("webhook request was not cancelled")
+	}
+
+	spool, _ := NewSpool(config.Spool)
+	if queued, _, _ := spool.Depth(); queued != 2 {
+		t.Errorf("queued = %d, want 2", queued)
+	}
+}
+
+// TestDispatchViolationsWorkerPool tests that no more than the configured workers run at once
+func TestDispatchViolationsWorkerPool(t *testing.T) {
+	var running, peak atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := running.Add(1)
+		for {
+			p := peak.Load()
+			if n <= p || peak.CompareAndSwap(p, n) {
+				break
+			}
+		}
+		time.Sleep(20 * time.Millisecond)
+		running.Add(-1)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	var actions []map[string]interface{}
+	for i := 0; i < 6; i++ {
+		actions = append(actions, map[string]interface{}{"type": "webhook", "url": server.URL})
+	}
+	config := &Config{
+		Dispatch: DispatchConfig{Workers: 2},
+		Alerts:   map[string]AlertLevel{"critical": {Actions: actions}},
+	}
+
+	for _, r := range DispatchViolations(config, nil, []ThresholdViolation{{Metric: "cpu", Level: "critical"}}) {
+		if r.Status != "sent" {
+			t.Errorf("result = %+v, want sent", r)
+		}
+	}
+	if peak.Load() > 2 {
+		t.Errorf("peak concurrency = %d, want at most 2", peak.Load())
+	}
+}
@@ -0,0 +1,328 @@
+package monitor
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"log"
+	"mime"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"text/template"
+	"time"
+)
+
+// Default templates for email alerts
+const (
+	defaultEmailSubject = "[{{.Level | upper}}] {{.Host}}: {{len .Violations}} violation(s)"
+	defaultEmailBody    = "{{.Host}} reported {{len .Violations}} violation(s) at {{.Time.Format \"2006-01-02 15:04:05 MST\"}}:\n\n{{.Table}}"
+)
+
+// EmailAction sends alerts by email over SMTP.
+// All violations of a cycle are sent as a single message.
+type EmailAction struct {
+	Host               string
+	Port               int
+	TLS                string // "starttls" (default), "tls" (implicit TLS) or "none"
+	InsecureSkipVerify bool
+	Username           string
+	Password           string
+	From               *mail.Address
+	To                 []*mail.Address
+	Cc                 []*mail.Address
+	Subject            *template.Template
+	Body               *template.Template
+	Timeout            time.Duration
+
+	ctx context.Context // aborts the SMTP session, see setContext
+}
+
+// EmailData is passed to the subject and body templates
+type EmailData struct {
+	Host       string
+	Level      string // highest level among the violations
+	Time       time.Time
+	Violations []ThresholdViolation
+	Table      string // violations rendered as a plain-text table
+}
+
+// emailTemplateFuncs are available in email templates
+var emailTemplateFuncs = template.FuncMap{
+	"upper": strings.ToUpper,
+}
+
+// NewEmailAction creates a new email alert action
+func NewEmailAction(config map[string]interface{}) (*EmailAction, error) {
+	ea := &EmailAction{
+		TLS:     "starttls",
+		Timeout: 10 * time.Second,
+	}
+
+	host, ok := config["host"].(string)
+	if !ok || host == "" {
+		return nil, fmt.Errorf("email action requires 'host' field")
+	}
+	ea.Host = host
+
+	if tlsMode, ok := config["tls"].(string); ok {
+		if tlsMode != "starttls" && tlsMode != "tls" && tlsMode != "none" {
+			return nil, fmt.Errorf("email action 'tls' must be 'starttls', 'tls' or 'none'")
+		}
+		ea.TLS = tlsMode
+	}
+	if insecure, ok := config["insecure_skip_verify"].(bool); ok {
+		ea.InsecureSkipVerify = insecure
+	}
+
+	ea.Port = 587
+	if ea.TLS == "tls" {
+		ea.Port = 465
+	}
+	if port, ok := actionNumber(config, "port"); ok {
+		ea.Port = int(port)
+	}
+
+	if username, ok := config["username"].(string); ok {
+		ea.Username = os.ExpandEnv(username)
+	}
+	if password, ok := config["password"].(string); ok {
+		ea.Password = os.ExpandEnv(password)
+	}
+
+	from, ok := config["from"].(string)
+	if !ok || from == "" {
+		return nil, fmt.Errorf("email action requires 'from' field")
+	}
+	address, err := mail.ParseAddress(from)
+	if err != nil {
+		return nil, fmt.Errorf("email action has invalid 'from' address '%s': %w", from, err)
+	}
+	ea.From = address
+
+	if ea.To, err = parseEmailAddresses("to", actionStrings(config, "to")); err != nil {
+		return nil, err
+	}
+	if len(ea.To) == 0 {
+		return nil, fmt.Errorf("email action requires 'to' field")
+	}
+	if ea.Cc, err = parseEmailAddresses("cc", actionStrings(config, "cc")); err != nil {
+		return nil, err
+	}
+
+	subject := defaultEmailSubject
+	if s, ok := config["subject"].(string); ok {
+		subject = s
+	}
+	tmpl, err := template.New("subject").Funcs(emailTemplateFuncs).Parse(subject)
+	if err != nil {
+		return nil, fmt.Errorf("invalid email subject template: %w", err)
+	}
+	ea.Subject = tmpl
+
+	body := defaultEmailBody
+	if b, ok := config["body"].(string); ok {
+		body = b
+	}
+	tmpl, err = template.New("body").Funcs(emailTemplateFuncs).Parse(body)
+	if err != nil {
+		return nil, fmt.Errorf("invalid email body template: %w", err)
+	}
+	ea.Body = tmpl
+
+	if timeout, ok := actionNumber(config, "timeout"); ok {
+		ea.Timeout = time.Duration(timeout) * time.Second
+	}
+
+	return ea, nil
+}
+
+// parseEmailAddresses parses the addresses of the 'to' or 'cc' field; each entry may
+// hold a comma-separated list
+func parseEmailAddresses(field string, values []string) ([]*mail.Address, error) {
+	var addresses []*mail.Address
+	for _, value := range values {
+		list, err := mail.ParseAddressList(value)
+		if err != nil {
+			return nil, fmt.Errorf("email action has invalid '%s' address '%s': %w", field, value, err)
+		}
+		addresses = append(addresses, list...)
+	}
+	return addresses, nil
+}
+
+// formatAddresses formats addresses for an email header
+func formatAddresses(addresses []*mail.Address) string {
+	formatted := make([]string, len(addresses))
+	for i, address := range addresses {
+		formatted[i] = address.String()
+	}
+	return strings.Join(formatted, ", ")
+}
+
+// setContext makes the SMTP session stop when ctx is cancelled
+func (ea *EmailAction) setContext(ctx context.Context) {
+	ea.ctx = ctx
+}
+
+// Execute sends a single violation by email
+func (ea *EmailAction) Execute(violation ThresholdViolation) error {
+	return ea.ExecuteBatch([]ThresholdViolation{violation})
+}
+
+// ExecuteBatch sends all violations of a cycle as one email
+func (ea *EmailAction) ExecuteBatch(violations []ThresholdViolation) error {
+	if len(violations) == 0 {
+		return nil
+	}
+
+	message, err := ea.buildMessage(violations, time.Now())
+	if err != nil {
+		return err
+	}
+
+	var recipients []string
+	for _, address := range append(append([]*mail.Address{}, ea.To...), ea.Cc...) {
+		recipients = append(recipients, address.Address)
+	}
+	if err := ea.send(recipients, message); err != nil {
+		return fmt.Errorf("failed to send email alert: %w", err)
+	}
+
+	log.Printf("Email alert sent to %s (%d violations)", strings.Join(recipients, ", "), len(violations))
+	return nil
+}
+
+// buildMessage renders the templates and assembles the email headers and body
+func (ea *EmailAction) buildMessage(violations []ThresholdViolation, now time.Time) ([]byte, error) {
+	hostname, _ := os.Hostname()
+	data := EmailData{
+		Host:       hostname,
+		Level:      highestLevel(violations),
+		Time:       now,
+		Violations: violations,
+		Table:      violationTable(violations),
+	}
+
+	var subject, body bytes.Buffer
+	if err := ea.Subject.Execute(&subject, data); err != nil {
+		return nil, fmt.Errorf("failed to render email subject: %w", err)
+	}
+	if err := ea.Body.Execute(&body, data); err != nil {
+		return nil, fmt.Errorf("failed to render email body: %w", err)
+	}
+
+	var msg bytes.Buffer
+	fmt.Fprintf(&msg, "From: %s\r\n", ea.From)
+	fmt.Fprintf(&msg, "To: %s\r\n", formatAddresses(ea.To))
+	if len(ea.Cc) > 0 {
+		fmt.Fprintf(&msg, "Cc: %s\r\n", formatAddresses(ea.Cc))
+	}
+	fmt.Fprintf(&msg, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", strings.TrimSpace(subject.String())))
+	fmt.Fprintf(&msg, "Date: %s\r\n", now.Format(time.RFC1123Z))
+	msg.WriteString("MIME-Version: 1.0\r\n")
+	msg.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	msg.WriteString("Content-Transfer-Encoding: 8bit\r\n")
+	msg.WriteString("\r\n")
+	msg.Write(body.Bytes())
+	msg.WriteString("\r\n")
+
+	return msg.Bytes(), nil
+}
+
+// send delivers a message over SMTP
+func (ea *EmailAction) send(recipients []string, message []byte) error {
+	addr := net.JoinHostPort(ea.Host, strconv.Itoa(ea.Port))
+	tlsConfig := &tls.Config{ServerName: ea.Host, InsecureSkipVerify: ea.InsecureSkipVerify}
+	dialer := &net.Dialer{Timeout: ea.Timeout}
+	ctx := actionContext(ea.ctx)
+
+	var conn net.Conn
+	var err error
+	if ea.TLS == "tls" {
+		conn, err = (&tls.Dialer{NetDialer: dialer, Config: tlsConfig}).DialContext(ctx, "tcp", addr)
+	} else {
+		conn, err = dialer.DialContext(ctx, "tcp", addr)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to connect to %s: %w", addr, err)
+	}
+	conn.SetDeadline(time.Now().Add(ea.Timeout))
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+
+	client, err := smtp.NewClient(conn, ea.Host)
+	if err != nil {
+		conn.Close()
+		return fmt.Errorf("SMTP handshake failed: %w", err)
+	}
+	defer client.Close()
+
+	if ea.TLS == "starttls" {
+		if ok, _ := client.Extension("STARTTLS"); !ok {
+			return fmt.Errorf("server %s does not support STARTTLS", addr)
+		}
+		if err := client.StartTLS(tlsConfig); err != nil {
+			return fmt.Errorf("STARTTLS failed: %w", err)
+		}
+	}
+
+	if ea.Username != "" {
+		if err := client.Auth(smtp.PlainAuth("", ea.Username, ea.Password, ea.Host)); err != nil {
+			return fmt.Errorf("SMTP authentication failed: %w", err)
+		}
+	}
+
+	if err := client.Mail(ea.From.Address); err != nil {
+		return fmt.Errorf("MAIL FROM rejected: %w", err)
+	}
+	for _, rcpt := range recipients {
+		if err := client.Rcpt(rcpt); err != nil {
+			return fmt.Errorf("RCPT TO %s rejected: %w", rcpt, err)
+		}
+	}
+
+	w, err := client.Data()
+	if err != nil {
+		return fmt.Errorf("DATA rejected: %w", err)
+	}
+	if _, err := w.Write(message); err != nil {
+		return fmt.Errorf("failed to write message: %w", err)
+	}
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("message rejected: %w", err)
+	}
+
+	return client.Quit()
+}
+
+// highestLevel returns "critical" if any violation is critical, otherwise "warning"
+func highestLevel(violations []ThresholdViolation) string {
+	for _, v := range violations {
+		if v.Level == "critical" {
+			return "critical"
+		}
+	}
+	return "warning"
+}
+
+// violationTable renders violations as an aligned plain-text table
+func violationTable(violations []ThresholdViolation) string {
+	var buf bytes.Buffer
+	w := tabwriter.NewWriter(&buf, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "LEVEL\tMETRIC\tVALUE\tMESSAGE")
+	for _, v := range violations {
+		metric := v.Metric
+		if v.Mountpoint != "" {
+			metric = fmt.Sprintf("%s (%s)", v.Metric, v.Mountpoint)
+		}
+		fmt.Fprintf(w, "%s\t%s\t%.2f\t%s\n", strings.ToUpper(v.Level), metric, v.Value, v.Message)
+	}
+	w.Flush()
+	return buf.String()
+}
@@ -0,0 +1,346 @@
+package monitor
+
+import (
+	"bufio"
+	"encoding/base64"
+	"net"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// smtpMessage is a message received by the SMTP stand-in
+type smtpMessage struct {
+	Auth string
+	From string
+	To   []string
+	Data string
+}
+
+// smtpStandIn is a minimal in-process SMTP server for tests
+type smtpStandIn struct {
+	listener net.Listener
+	mu       sync.Mutex
+	messages []smtpMessage
+}
+
+func newSMTPStandIn(t *testing.T) *smtpStandIn {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	s := &smtpStandIn{listener: ln}
+	go s.serve()
+	t.Cleanup(func() { ln.Close() })
+	return s
+}
+
+func (s *smtpStandIn) port() int {
+	return s.listener.Addr().(*net.TCPAddr).Port
+}
+
+func (s *smtpStandIn) received() []smtpMessage {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]smtpMessage{}, s.messages...)
+}
+
+func (s *smtpStandIn) serve() {
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		go s.handle(conn)
+	}
+}
+
+func (s *smtpStandIn) handle(conn net.Conn) {
+	defer conn.Close()
+	r := textproto.NewReader(bufio.NewReader(conn))
+	reply := func(line string) { conn.Write([]byte(line + "\r\n")) }
+
+	reply("220 localhost ESMTP stand-in")
+	var msg smtpMessage
+	for {
+		line, err := r.ReadLine()
+		if err != nil {
+			return
+		}
+		cmd := strings.ToUpper(strings.SplitN(line, " ", 2)[0])
+		switch {
+		case cmd == "EHLO" || cmd == "HELO":
+			reply("250-localhost")
+			reply("250 AUTH PLAIN")
+		case cmd == "AUTH":
+			fields := strings.Fields(line)
+			decoded, _ := base64.StdEncoding.DecodeString(fields[len(fields)-1])
+			msg.Auth = string(decoded)
+			reply("235 authenticated")
+		case strings.HasPrefix(strings.ToUpper(line), "MAIL FROM:"):
+			msg.From = strings.Trim(line[len("MAIL FROM:"):], "<>")
+			reply("250 ok")
+		case strings.HasPrefix(strings.ToUpper(line), "RCPT TO:"):
+			msg.To = append(msg.To, strings.Trim(line[len("RCPT TO:"):], "<>"))
+			reply("250 ok")
+		case cmd == "DATA":
+			reply("354 go ahead")
+			data, err := r.ReadDotBytes()
+			if err != nil {
+				return
+			}
+			msg.Data = string(data)
+			s.mu.Lock()
+			s.messages = append(s.messages, msg)
+			s.mu.Unlock()
+			msg = smtpMessage{}
+			reply("250 queued")
+		case cmd == "QUIT":
+			reply("221 bye")
+			return
+		default:
+			reply("502 not implemented")
+		}
+	}
+}
+
+// TestEmailActionBatchesViolations tests that violations of one cycle go out as a single email
+func TestEmailActionBatchesViolations(t *testing.T) {
+	server := newSMTPStandIn(t)
+	t.Setenv("SMTP_USER", "monitor")
+
+	config := &Config{
+		Alerts: map[string]AlertLevel{
+			"critical": {
+				Actions: []map[string]interface{}{
+					{
+						"type":     "email",
+						"host":     "127.0.0.1",
+						"port":     server.port(),
+						"tls":      "none",
+						"username": "${SMTP_USER}",
+						"password": "s3cret",
+						"from":     "Monitor <monitor@example.com>",
+						"to":       []interface{}{"Ops <ops@example.com>", "oncall@example.com"},
+						"cc":       "lead@example.com",
+					},
+				},
+			},
+		},
+	}
+
+	criticals := []ThresholdViolation{
+		{Metric: "disk", Level: "critical", Message: "partition /dev/sda1 is 95.00% full", Value: 95, Mountpoint: "/"},
+		{Metric: "cpu", Level: "critical", Message: "cpu usage: 99.00%", Value: 99},
+	}
+
+	if err := ProcessViolations(config, nil, criticals); err != nil {
+		t.Fatalf("ProcessViolations() error = %v", err)
+	}
+
+	messages := server.received()
+	if len(messages) != 1 {
+		t.Fatalf("received %d emails, want 1", len(messages))
+	}
+	msg := messages[0]
+
+	if msg.From != "monitor@example.com" {
+		t.Errorf("MAIL FROM = %s", msg.From)
+	}
+	if strings.Join(msg.To, ",") != "ops@example.com,oncall@example.com,lead@example.com" {
+		t.Errorf("RCPT TO = %v", msg.To)
+	}
+	if msg.Auth != "\x00monitor\x00s3cret" {
+		t.Errorf("AUTH PLAIN = %q", msg.Auth)
+	}
+
+	for _, want := range []string{
+		"Subject: [CRITICAL] ",
+		"2 violation(s)",
+		"From: \"Monitor\" <monitor@example.com>",
+		"To: \"Ops\" <ops@example.com>, <oncall@example.com>",
+		"Cc: <lead@example.com>",
+		"LEVEL     METRIC    VALUE  MESSAGE",
+		"CRITICAL  disk (/)  95.00  partition /dev/sda1 is 95.00% full",
+		"CRITICAL  cpu       99.00  cpu usage: 99.00%",
+	} {
+		if !strings.Contains(msg.Data, want) {
+			t.Errorf("email missing %q:\n%s", want, msg.Data)
+		}
+	}
+}
+
+// TestEmailActionMergesLevels tests that warnings and criticals of one cycle go out as a single email
+func TestEmailActionMergesLevels(t *testing.T) {
+	server := newSMTPStandIn(t)
+
+	email := func() []map[string]interface{} {
+		return []map[string]interface{}{{
+			"type": "email",
+			"host": "127.0.0.1",
+			"port": server.port(),
+			"tls":  "none",
+			"from": "monitor@example.com",
+			"to":   "ops@example.com",
+		}}
+	}
+	config := &Config{
+		Alerts: map[string]AlertLevel{
+			"critical": {Actions: email()},
+			"warning":  {Actions: email()},
+		},
+	}
+
+	warnings := []ThresholdViolation{{Metric: "memory", Level: "warning", Message: "memory usage: 85.00%", Value: 85}}
+	criticals := []ThresholdViolation{{Metric: "cpu", Level: "critical", Message: "cpu usage: 99.00%", Value: 99}}
+
+	results := DispatchViolations(config, warnings, criticals)
+	if len(results) != 1 || results[0].Violations != 2 || results[0].Status != "sent" {
+		t.Fatalf("results = %+v, want one sent delivery of 2 violations", results)
+	}
+
+	messages := server.received()
+	if len(messages) != 1 {
+		t.Fatalf("received %d emails, want 1", len(messages))
+	}
+	for _, want := range []string{
+		"Subject: [CRITICAL] ",
+		"CRITICAL  cpu     99.00  cpu usage: 99.00%",
+		"WARNING   memory  85.00  memory usage: 85.00%",
+	} {
+		if !strings.Contains(messages[0].Data, want) {
+			t.Errorf("email missing %q:\n%s", want, messages[0].Data)
+		}
+	}
+}
+
+// TestEmailActionTemplates tests custom subject and body templates
+func TestEmailActionTemplates(t *testing.T) {
+	server := newSMTPStandIn(t)
+
+	action, err := NewEmailAction(map[string]interface{}{
+		"host":    "127.0.0.1",
+		"port":    server.port(),
+		"tls":     "none",
+		"from":    "monitor@example.com",
+		"to":      "ops@example.com",
+		"subject": "{{len .Violations}} alert(s) at {{.Level}}",
+		"body":    "{{range .Violations}}* {{.Metric}}={{.Value}}\n{{end}}",
+	})
+	if err != nil {
+		t.Fatalf("NewEmailAction() error = %v", err)
+	}
+
+	if err := action.Execute(ThresholdViolation{Metric: "memory", Level: "warning", Value: 12}); err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+
+	messages := server.received()
+	if len(messages) != 1 {
+		t.Fatalf("received %d emails, want 1", len(messages))
+	}
+	if !strings.Contains(messages[0].Data, "Subject: 1 alert(s) at warning") {
+		t.Errorf("unexpected subject:\n%s", messages[0].Data)
+	}
+	if !strings.Contains(messages[0].Data, "* memory=12") {
+		t.Errorf("unexpected body:\n%s", messages[0].Data)
+	}
+	if messages[0].Auth != "" {
+		t.Errorf("AUTH sent without username")
+	}
+}
+
+// TestEmailActionRequiresStartTLS tests refusing to send when STARTTLS is unavailable
+func TestEmailActionRequiresStartTLS(t *testing.T) {
+	server := newSMTPStandIn(t)
+
+	action, err := NewEmailAction(map[string]interface{}{
+		"host": "127.0.0.1",
+		"port": server.port(),
+		"from": "monitor@example.com",
+		"to":   "ops@example.com",
+	})
+	if err != nil {
+		t.Fatalf("NewEmailAction() error = %v", err)
+	}
+
+	err = action.Execute(ThresholdViolation{Metric: "cpu", Level: "critical"})
+	if err == nil || !strings.Contains(err.Error(), "STARTTLS") {
+		t.Errorf("Execute() error = %v, want STARTTLS error", err)
+	}
+	if len(server.received()) != 0 {
+		t.Errorf("message sent over unencrypted connection")
+	}
+}
+
+// TestEmailActionCreation tests email action configuration
+func TestEmailActionCreation(t *testing.T) {
+	base := func(extra map[string]interface{}) map[string]interface{} {
+		config := map[string]interface{}{"host": "smtp.example.com", "from": "a@example.com", "to": "b@example.com"}
+		for k, v := range extra {
+			config[k] = v
+		}
+		return config
+	}
+
+	tests := []struct {
+		name     string
+		config   map[string]interface{}
+		wantPort int
+		wantErr  bool
+	}{
+		{name: "starttls default port", config: base(nil), wantPort: 587},
+		{name: "implicit tls default port", config: base(map[string]interface{}{"tls": "tls"}), wantPort: 465},
+		{name: "explicit port", config: base(map[string]interface{}{"port": 2525}), wantPort: 2525},
+		{name: "missing host", config: map[string]interface{}{"from": "a@example.com", "to": "b@example.com"}, wantErr: true},
+		{name: "missing from", config: map[string]interface{}{"host": "smtp.example.com", "to": "b@example.com"}, wantErr: true},
+		{name: "missing to", config: map[string]interface{}{"host": "smtp.example.com", "from": "a@example.com"}, wantErr: true},
+		{name: "invalid from", config: base(map[string]interface{}{"from": "Monitor <monitor"}), wantErr: true},
+		{name: "invalid to", config: base(map[string]interface{}{"to": []interface{}{"ops@example.com", "oncall"}}), wantErr: true},
+		{name: "invalid cc", config: base(map[string]interface{}{"cc": "lead@"}), wantErr: true},
+		{name: "bad tls mode", config: base(map[string]interface{}{"tls": "ssl3"}), wantErr: true},
+		{name: "bad template", config: base(map[string]interface{}{"subject": "{{.Nope"}), wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			action, err := NewEmailAction(tt.config)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NewEmailAction() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && action.Port != tt.wantPort {
+				t.Errorf("port = %d, want %d", action.Port, tt.wantPort)
+			}
+		})
+	}
+}
+
+// TestLoadConfigEmailAction tests validating email actions in the config file
+func TestLoadConfigEmailAction(t *testing.T) {
+	tests := []struct {
+		name    string
+		action  string
+		wantErr bool
+	}{
+		{name: "valid", action: "type: email\n        host: smtp.example.com\n        from: a@example.com\n        to: [b@example.com]"},
+		{name: "field of another type", action: "type: email\n        host: smtp.example.com\n        from: a@example.com\n        to: b@example.com\n        url: https://example.com", wantErr: true},
+		{name: "missing to", action: "type: email\n        host: smtp.example.com\n        from: a@example.com", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			yaml := "alerts:\n  critical:\n    actions:\n      - " + tt.action + "\n"
+			path := filepath.Join(t.TempDir(), "config.yaml")
+			if err := os.WriteFile(path, []byte(yaml), 0644); err != nil {
+				t.Fatalf("failed to write config: %v", err)
+			}
+			if _, err := LoadConfig(path); (err != nil) != tt.wantErr {
+				t.Errorf("LoadConfig() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
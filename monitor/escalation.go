@@ -0,0 +1,121 @@
+package monitor
+
+import (
+	"fmt"
+	"log"
+	"time"
+)
+
+// EscalationStep runs additional actions once a violation has persisted for a while
+// after its first alert
+type EscalationStep struct {
+	After   string                   `yaml:"after"` // Delay after the first alert, e.g. "15m"
+	Actions []map[string]interface{} `yaml:"actions"`
+}
+
+// Escalation is an escalation step that became due for a violation
+type Escalation struct {
+	Step      int // 1-based index of the step in the level's escalation
+	Violation ThresholdViolation
+	Actions   []map[string]interface{}
+}
+
+// after returns the delay of the step
+func (s EscalationStep) after() (time.Duration, error) {
+	d, err := parseDuration(s.After)
+	if err != nil {
+		return 0, err
+	}
+	if d < 0 {
+		return 0, fmt.Errorf("escalation delay must not be negative")
+	}
+	return d, nil
+}
+
+// validateEscalation validates the escalation steps of an alert level
+func validateEscalation(level string, steps []EscalationStep) error {
+	var previous time.Duration
+	for i, step := range steps {
+		where := fmt.Sprintf("escalation step %d of level '%s'", i+1, level)
+		if step.After == "" {
+			return fmt.Errorf("%s missing 'after' field", where)
+		}
+		after, err := step.after()
+		if err != nil {
+			return fmt.Errorf("%s: %w", where, err)
+		}
+		if after < previous {
+			return fmt.Errorf("%s must not come before the previous step", where)
+		}
+		previous = after
+		if len(step.Actions) == 0 {
+			return fmt.Errorf("%s missing 'actions' field", where)
+		}
+		if err := validateActions(where, step.Actions); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// collectEscalations records the escalation steps that are due for the current
+// violations. Each step runs once per violation, counted from its first notification;
+// the progress is kept in the violation state until the violation resolves. Acknowledged
+// and silenced violations do not escalate, nor do those in a maintenance window.
+func collectEscalations(config *Config, violations []ThresholdViolation, stateManager *StateManager, now time.Time) error {
+	for _, violation := range violations {
+		steps := config.Alerts[violation.Level].Escalation
+		if len(steps) == 0 {
+			continue
+		}
+		state, ok := stateManager.States[violation.StateKey()]
+		if !ok || !state.Notified {
+			continue
+		}
+		if reason := stateManager.suppressed(violation, now); reason != "" {
+			log.Printf("Escalation: %s/%s stopped (%s)", violation.Metric, violation.Level, reason)
+			continue
+		}
+		if window, ok := config.maintenanceWindow(violation, now); ok {
+			log.Printf("Escalation: %s/%s held by maintenance window %s", violation.Metric, violation.Level, window)
+			continue
+		}
+
+		elapsed := now.Sub(time.Unix(int64(state.escalationStart()), 0))
+		for state.EscalationStep < len(steps) {
+			step := steps[state.EscalationStep]
+			after, err := step.after()
+			if err != nil {
+				return fmt.Errorf("escalation of %s/%s: %w", violation.Metric, violation.Level, err)
+			}
+			if elapsed < after {
+				break
+			}
+			state.EscalationStep++
+			log.Printf("Escalation: %s/%s reached step %d after %v", violation.Metric, violation.Level, state.EscalationStep, elapsed.Round(time.Second))
+			stateManager.Escalations = append(stateManager.Escalations, Escalation{
+				Step:      state.EscalationStep,
+				Violation: violation,
+				Actions:   step.Actions,
+			})
+		}
+	}
+	return nil
+}
+
+// DispatchEscalations runs the actions of due escalation steps and returns one result per action
+func DispatchEscalations(config *Config, escalations []Escalation) []DeliveryResult {
+	var jobs []dispatchJob
+	for _, escalation := range escalations {
+		for i, actionConfig := range escalation.Actions {
+			jobs = append(jobs, dispatchJob{
+				level:        escalation.Violation.Level,
+				step:         escalation.Step,
+				index:        i,
+				actionConfig: actionConfig,
+				violations:   []ThresholdViolation{escalation.Violation},
+			})
+		}
+	}
+	return runDispatchJobs(config, jobs)
+}
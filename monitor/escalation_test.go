@@ -0,0 +1,181 @@
+package monitor
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// TestCollectEscalations tests that each step runs once after its delay
+func TestCollectEscalations(t *testing.T) {
+	chat := []map[string]interface{}{{"type": "stdout"}}
+	primary := []map[string]interface{}{{"type": "webhook", "url": "http://primary"}}
+	secondary := []map[string]interface{}{{"type": "webhook", "url": "http://secondary"}}
+	config := &Config{
+		Alerts: map[string]AlertLevel{
+			"critical": {Escalation: []EscalationStep{
+				{After: "0m", Actions: chat},
+				{After: "15m", Actions: primary},
+				{After: "30m", Actions: secondary},
+			}},
+		},
+	}
+	sm := &StateManager{
+		StateFile: filepath.Join(t.TempDir(), "state.json"),
+		States:    make(map[string]*ViolationState),
+	}
+
+	violation := ThresholdViolation{Metric: "cpu", Level: "critical", Value: 99}
+	warning := ThresholdViolation{Metric: "memory", Level: "warning", Value: 85}
+	state := sm.GetOrCreateFor(violation)
+	sm.GetOrCreateFor(warning).MarkAlerted()
+
+	// Not notified yet: no escalation
+	start := time.Now()
+	if err := collectEscalations(config, []ThresholdViolation{violation}, sm, start); err != nil {
+		t.Fatalf("collectEscalations() error = %v", err)
+	}
+	if got := sm.TakeEscalations(); len(got) != 0 {
+		t.Fatalf("escalations before the first alert = %+v", got)
+	}
+
+	state.MarkAlerted()
+	if err := collectEscalations(config, []ThresholdViolation{violation}, sm, start); err != nil {
+		t.Fatalf("collectEscalations() error = %v", err)
+	}
+	if got := sm.TakeEscalations(); len(got) != 0 {
+		t.Fatalf("escalations before the first notification = %+v", got)
+	}
+
+	state.MarkNotified()
+	first := time.Unix(int64(*state.FirstAlertTime), 0)
+	checks := []struct {
+		at        time.Duration
+		wantSteps []int
+	}{
+		{0, []int{1}},
+		{10 * time.Minute, nil},
+		{15 * time.Minute, []int{2}},
+		{16 * time.Minute, nil},
+		{45 * time.Minute, []int{3}},
+		{60 * time.Minute, nil},
+	}
+	for _, check := range checks {
+		state.MarkAlerted() // repeated alerts do not restart the escalation
+		if err := collectEscalations(config, []ThresholdViolation{violation, warning}, sm, first.Add(check.at)); err != nil {
+			t.Fatalf("collectEscalations() error = %v", err)
+		}
+		got := sm.TakeEscalations()
+		if len(got) != len(check.wantSteps) {
+			t.Fatalf("at %v got %d escalations, want steps %v", check.at, len(got), check.wantSteps)
+		}
+		for i, e := range got {
+			if e.Step != check.wantSteps[i] || e.Violation.Metric != "cpu" {
+				t.Errorf("at %v escalation %d = step %d of %s, want step %d of cpu", check.at, i, e.Step, e.Violation.Metric, check.wantSteps[i])
+			}
+		}
+	}
+
+	// Progress survives a restart through the state file
+	if err := sm.Save(); err != nil {
+		t.Fatal(err)
+	}
+	reloaded := &StateManager{StateFile: sm.StateFile}
+	if err := reloaded.load(); err != nil {
+		t.Fatal(err)
+	}
+	if got := reloaded.States[violation.StateKey()].EscalationStep; got != 3 {
+		t.Errorf("reloaded escalation step = %d, want 3", got)
+	}
+}
+
+// TestCollectEscalationsCatchUp tests that steps missed while the monitor was not running all run
+func TestCollectEscalationsCatchUp(t *testing.T) {
+	actions := []map[string]interface{}{{"type": "stdout"}}
+	config := &Config{
+		Alerts: map[string]AlertLevel{
+			"critical": {Escalation: []EscalationStep{{After: "0s", Actions: actions}, {After: "15m", Actions: actions}}},
+		},
+	}
+	sm := &StateManager{States: make(map[string]*ViolationState)}
+	violation := ThresholdViolation{Metric: "disk", Level: "critical", Mountpoint: "/"}
+	sm.GetOrCreateFor(violation).MarkNotified()
+
+	if err := collectEscalations(config, []ThresholdViolation{violation}, sm, time.Now().Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	if got := sm.TakeEscalations(); len(got) != 2 || got[0].Step != 1 || got[1].Step != 2 {
+		t.Errorf("escalations = %+v, want steps 1 and 2", got)
+	}
+}
+
+// TestDispatchEscalations tests that escalation actions are delivered and reported with their step
+func TestDispatchEscalations(t *testing.T) {
+	var hits atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	escalations := []Escalation{{
+		Step:      2,
+		Violation: ThresholdViolation{Metric: "cpu", Level: "critical", Message: "CPU critical"},
+		Actions:   []map[string]interface{}{{"type": "webhook", "url": server.URL}},
+	}}
+	results := DispatchEscalations(&Config{}, escalations)
+	if len(results) != 1 || results[0].Status != "sent" || results[0].Step != 2 || results[0].Level != "critical" {
+		t.Fatalf("results = %+v", results)
+	}
+	if hits.Load() != 1 {
+		t.Errorf("webhook received %d requests, want 1", hits.Load())
+	}
+	if results := DispatchEscalations(&Config{}, nil); results != nil {
+		t.Errorf("results without escalations = %+v", results)
+	}
+}
+
+// TestEscalationConfigValidation tests loading and validating escalation steps
+func TestEscalationConfigValidation(t *testing.T) {
+	tests := []struct {
+		name    string
+		steps   string
+		wantErr string
+	}{
+		{"valid", "      - after: 0m\n        actions: [{type: stdout}]\n      - after: 15m\n        actions: [{type: webhook, url: http://example.com}]\n", ""},
+		{"missing after", "      - actions: [{type: stdout}]\n", "missing 'after'"},
+		{"invalid after", "      - after: soon\n        actions: [{type: stdout}]\n", "failed to parse duration"},
+		{"out of order", "      - after: 30m\n        actions: [{type: stdout}]\n      - after: 15m\n        actions: [{type: stdout}]\n", "must not come before"},
+		{"missing actions", "      - after: 15m\n", "missing 'actions'"},
+		{"invalid action", "      - after: 15m\n        actions: [{type: webhook}]\n", "requires 'url'"},
+		{"unknown field", "      - after: 15m\n        delay: 5m\n        actions: [{type: stdout}]\n", "unknown field 'delay'"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			yaml := "alerts:\n  critical:\n    escalation:\n" + tt.steps
+			path := filepath.Join(t.TempDir(), "config.yaml")
+			if err := os.WriteFile(path, []byte(yaml), 0644); err != nil {
+				t.Fatal(err)
+			}
+			config, err := LoadConfig(path)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("LoadConfig() error = %v, want error containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("LoadConfig() error = %v", err)
+			}
+			if steps := config.Alerts["critical"].Escalation; len(steps) != 2 || steps[1].After != "15m" {
+				t.Errorf("escalation = %+v", steps)
+			}
+		})
+	}
+}
@@ -0,0 +1,326 @@
+package monitor
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Event log defaults
+const (
+	defaultEventLogMaxSize  = 10 << 20 // bytes
+	defaultEventLogMaxFiles = 5
+)
+
+// eventTimeLayouts are the accepted formats of event query times, besides durations
+var eventTimeLayouts = []string{time.RFC3339, "2006-01-02 15:04", "2006-01-02T15:04", "2006-01-02"}
+
+// eventLogMu serializes writes to event logs
+var eventLogMu sync.Mutex
+
+// RecordingAlertAction is implemented by actions that record a violation together with the
+// results of the other actions it was delivered to. They run after the other actions.
+type RecordingAlertAction interface {
+	AlertAction
+	Record(violation ThresholdViolation, step int, results []DeliveryResult) error
+}
+
+// Event is a line of the event log
+type Event struct {
+	Time       time.Time         `json:"timestamp"`
+	Host       string            `json:"host"`
+	Event      string            `json:"event"` // "alert", "escalation" or "resolved"
+	Metric     string            `json:"metric"`
+	Level      string            `json:"level"`
+	Value      float64           `json:"value"`
+	Threshold  float64           `json:"threshold,omitempty"`
+	Message    string            `json:"message"`
+	Mountpoint string            `json:"mountpoint,omitempty"`
+	Device     string            `json:"device,omitempty"`
+	Labels     map[string]string `json:"labels,omitempty"`
+	Step       int               `json:"escalation_step,omitempty"`
+	Results    []DeliveryResult  `json:"results,omitempty"` // outcome of the other actions
+}
+
+// EventFilter selects events from the event log
+type EventFilter struct {
+	Since  time.Time // zero for no lower bound
+	Until  time.Time // zero for no upper bound
+	Metric string
+	Level  string
+	Limit  int // return only the latest events, 0 for all
+}
+
+// matches reports whether an event passes the filter
+func (f EventFilter) matches(e Event) bool {
+	if !f.Since.IsZero() && e.Time.Before(f.Since) {
+		return false
+	}
+	if !f.Until.IsZero() && e.Time.After(f.Until) {
+		return false
+	}
+	if f.Metric != "" && f.Metric != e.Metric {
+		return false
+	}
+	if f.Level != "" && f.Level != e.Level {
+		return false
+	}
+	return true
+}
+
+// FileAction appends alerts as JSON lines to a log file, rotating it by size
+type FileAction struct {
+	Path     string
+	MaxSize  int64 // rotate before the file grows beyond this
+	MaxFiles int   // rotated files kept as path.1 (newest) to path.N
+}
+
+// NewFileAction creates a new file alert action
+func NewFileAction(config map[string]interface{}) (*FileAction, error) {
+	fa := &FileAction{
+		MaxSize:  defaultEventLogMaxSize,
+		MaxFiles: defaultEventLogMaxFiles,
+	}
+
+	path, ok := config["path"].(string)
+	if !ok || path == "" {
+		return nil, fmt.Errorf("file action requires 'path' field")
+	}
+	fa.Path = os.ExpandEnv(path)
+
+	switch maxSize := config["max_size"].(type) {
+	case nil:
+	case string:
+		size, err := parseSize(maxSize, "bytes")
+		if err != nil {
+			return nil, fmt.Errorf("file action 'max_size': %w", err)
+		}
+		fa.MaxSize = int64(size)
+	default:
+		size, ok := actionNumber(config, "max_size")
+		if !ok {
+			return nil, fmt.Errorf("file action 'max_size' must be a size such as \"10MiB\"")
+		}
+		fa.MaxSize = int64(size)
+	}
+	if fa.MaxSize <= 0 {
+		return nil, fmt.Errorf("file action 'max_size' must be positive")
+	}
+
+	if maxFiles, ok := actionNumber(config, "max_files"); ok {
+		if maxFiles < 0 {
+			return nil, fmt.Errorf("file action 'max_files' must not be negative")
+		}
+		fa.MaxFiles = int(maxFiles)
+	}
+
+	return fa, nil
+}
+
+// Execute records a violation without delivery results, e.g. on a spool retry
+func (fa *FileAction) Execute(violation ThresholdViolation) error {
+	return fa.Record(violation, 0, nil)
+}
+
+// Record records a violation with the results of the other actions it was delivered to
+func (fa *FileAction) Record(violation ThresholdViolation, step int, results []DeliveryResult) error {
+	event := newEvent(violation, "alert")
+	if step > 0 {
+		event.Event = "escalation"
+		event.Step = step
+	}
+	event.Results = results
+	if err := fa.append(event); err != nil {
+		return err
+	}
+	log.Printf("Event logged to %s: %s/%s", fa.Path, violation.Metric, violation.Level)
+	return nil
+}
+
+// Resolve records that a violation resolved
+func (fa *FileAction) Resolve(violation ThresholdViolation) error {
+	return fa.append(newEvent(violation, "resolved"))
+}
+
+// newEvent builds the event of a violation
+func newEvent(violation ThresholdViolation, kind string) Event {
+	hostname, _ := os.Hostname()
+	return Event{
+		Time:       time.Now(),
+		Host:       hostname,
+		Event:      kind,
+		Metric:     violation.Metric,
+		Level:      violation.Level,
+		Value:      violation.Value,
+		Threshold:  violation.Threshold,
+		Message:    violation.Message,
+		Mountpoint: violation.Mountpoint,
+		Device:     violation.Device,
+		Labels:     violation.Labels,
+	}
+}
+
+// append writes an event as a JSON line, rotating the log first if it would grow too large
+func (fa *FileAction) append(event Event) error {
+	line, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to marshal event: %w", err)
+	}
+	line = append(line, '\n')
+
+	eventLogMu.Lock()
+	defer eventLogMu.Unlock()
+
+	if info, err := os.Stat(fa.Path); err == nil && info.Size() > 0 && info.Size()+int64(len(line)) > fa.MaxSize {
+		if err := fa.rotate(); err != nil {
+			return err
+		}
+	}
+
+	f, err := os.OpenFile(fa.Path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open event log: %w", err)
+	}
+	defer f.Close()
+	if _, err := f.Write(line); err != nil {
+		return fmt.Errorf("failed to write event log: %w", err)
+	}
+	return nil
+}
+
+// rotate shifts path.N-1 to path.N, ..., path to path.1 and drops the oldest file
+func (fa *FileAction) rotate() error {
+	if fa.MaxFiles == 0 {
+		return os.Remove(fa.Path)
+	}
+	os.Remove(rotatedEventLog(fa.Path, fa.MaxFiles))
+	for i := fa.MaxFiles - 1; i >= 1; i-- {
+		if err := os.Rename(rotatedEventLog(fa.Path, i), rotatedEventLog(fa.Path, i+1)); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to rotate event log: %w", err)
+		}
+	}
+	if err := os.Rename(fa.Path, rotatedEventLog(fa.Path, 1)); err != nil {
+		return fmt.Errorf("failed to rotate event log: %w", err)
+	}
+	return nil
+}
+
+// rotatedEventLog returns the name of the i-th rotated event log
+func rotatedEventLog(path string, i int) string {
+	return fmt.Sprintf("%s.%d", path, i)
+}
+
+// ReadEvents returns the events of the logs at paths, including their rotated files,
+// that pass the filter, oldest first
+func ReadEvents(paths []string, filter EventFilter) ([]Event, error) {
+	var events []Event
+	for _, path := range paths {
+		// Rotated files are read first, oldest (highest number) first
+		var files []string
+		for i := 1; ; i++ {
+			if _, err := os.Stat(rotatedEventLog(path, i)); err != nil {
+				break
+			}
+			files = append([]string{rotatedEventLog(path, i)}, files...)
+		}
+		files = append(files, path)
+
+		for _, file := range files {
+			fileEvents, err := readEventFile(file, filter)
+			if err != nil {
+				return nil, err
+			}
+			events = append(events, fileEvents...)
+		}
+	}
+
+	sort.SliceStable(events, func(i, j int) bool { return events[i].Time.Before(events[j].Time) })
+	if filter.Limit > 0 && len(events) > filter.Limit {
+		events = events[len(events)-filter.Limit:]
+	}
+	return events, nil
+}
+
+// readEventFile reads the events of one file that pass the filter; unparsable lines are skipped
+func readEventFile(path string, filter EventFilter) ([]Event, error) {
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to open event log: %w", err)
+	}
+	defer f.Close()
+
+	var events []Event
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 16<<20)
+	for scanner.Scan() {
+		var event Event
+		if err := json.Unmarshal(scanner.Bytes(), &event); err != nil {
+			log.Printf("Skipping invalid line in %s: %v", path, err)
+			continue
+		}
+		if filter.matches(event) {
+			events = append(events, event)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read event log: %w", err)
+	}
+	return events, nil
+}
+
+// EventLogs returns the paths of the event logs written by file actions anywhere in the config
+func (c *Config) EventLogs() []string {
+	var actionSets [][]map[string]interface{}
+	levels := make([]string, 0, len(c.Alerts))
+	for level := range c.Alerts {
+		levels = append(levels, level)
+	}
+	sort.Strings(levels)
+	for _, level := range levels {
+		actionSets = append(actionSets, c.Alerts[level].Actions)
+		for _, step := range c.Alerts[level].Escalation {
+			actionSets = append(actionSets, step.Actions)
+		}
+	}
+	for _, receiver := range c.Receivers {
+		actionSets = append(actionSets, receiver.Actions)
+	}
+
+	var paths []string
+	seen := make(map[string]bool)
+	for _, actions := range actionSets {
+		for _, actionConfig := range actions {
+			if actionConfig["type"] != "file" {
+				continue
+			}
+			if path, ok := actionConfig["path"].(string); ok && !seen[os.ExpandEnv(path)] {
+				seen[os.ExpandEnv(path)] = true
+				paths = append(paths, os.ExpandEnv(path))
+			}
+		}
+	}
+	return paths
+}
+
+// ParseEventTime parses an event query time: a time such as "2006-01-02 15:04" or
+// RFC 3339, or a duration such as "24h" meaning that long before now
+func ParseEventTime(s string, now time.Time) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	if d, err := time.ParseDuration(s); err == nil {
+		return now.Add(-d), nil
+	}
+	for _, layout := range eventTimeLayouts {
+		if t, err := time.ParseInLocation(layout, s, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid time '%s' (use a duration such as 24h, YYYY-MM-DD HH:MM or RFC 3339)", s)
+}
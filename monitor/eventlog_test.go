@@ -0,0 +1,225 @@
+package monitor
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestFileActionRecordsDeliveries tests that the file action logs the results of the other actions
+func TestFileActionRecordsDeliveries(t *testing.T) {
+	broken := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer broken.Close()
+
+	path := filepath.Join(t.TempDir(), "events.jsonl")
+	config := &Config{
+		Alerts: map[string]AlertLevel{
+			"critical": {
+				Actions: []map[string]interface{}{
+					{"type": "file", "path": path},
+					{"type": "webhook", "url": broken.URL},
+					{"type": "stdout"},
+				},
+			},
+		},
+	}
+	critical := []ThresholdViolation{{Metric: "disk", Level: "critical", Message: "disk usage: 97%", Value: 97, Threshold: 95, Mountpoint: "/"}}
+
+	results := DispatchViolations(config, nil, critical)
+	if len(results) != 3 || results[0].Status != "sent" {
+		t.Fatalf("results = %+v", results)
+	}
+
+	events, err := ReadEvents([]string{path}, EventFilter{})
+	if err != nil {
+		t.Fatalf("ReadEvents() error = %v", err)
+	}
+	if len(events) != 1 {
+		t.Fatalf("got %d events, want 1", len(events))
+	}
+	e := events[0]
+	if e.Event != "alert" || e.Metric != "disk" || e.Level != "critical" || e.Mountpoint != "/" || e.Threshold != 95 {
+		t.Errorf("event = %+v", e)
+	}
+	if len(e.Results) != 2 {
+		t.Fatalf("got %d results, want 2: %+v", len(e.Results), e.Results)
+	}
+	if e.Results[0].Action != "webhook" || e.Results[0].Status != "failed" || e.Results[0].Error == "" {
+		t.Errorf("webhook result = %+v", e.Results[0])
+	}
+	if e.Results[1].Action != "stdout" || e.Results[1].Status != "sent" {
+		t.Errorf("stdout result = %+v", e.Results[1])
+	}
+
+	action, err := NewFileAction(map[string]interface{}{"path": path})
+	if err != nil {
+		t.Fatalf("NewFileAction() error = %v", err)
+	}
+	if err := action.Resolve(critical[0]); err != nil {
+		t.Fatalf("Resolve() error = %v", err)
+	}
+	events, _ = ReadEvents([]string{path}, EventFilter{})
+	if len(events) != 2 || events[1].Event != "resolved" || events[1].Results != nil {
+		t.Errorf("events after resolve = %+v", events)
+	}
+}
+
+// TestFileActionRotation tests size-based rotation and reading across rotated files
+func TestFileActionRotation(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "events.jsonl")
+	action, err := NewFileAction(map[string]interface{}{"path": path, "max_size": "1KiB", "max_files": 2})
+	if err != nil {
+		t.Fatalf("NewFileAction() error = %v", err)
+	}
+
+	for i := 0; i < 30; i++ {
+		v := ThresholdViolation{Metric: "cpu", Level: "warning", Message: strings.Repeat("x", 100), Value: float64(i)}
+		if err := action.Execute(v); err != nil {
+			t.Fatalf("Execute() error = %v", err)
+		}
+	}
+
+	for _, name := range []string{path, path + ".1", path + ".2"} {
+		info, err := os.Stat(name)
+		if err != nil {
+			t.Fatalf("expected %s: %v", name, err)
+		}
+		if info.Size() > 1024 {
+			t.Errorf("%s is %d bytes, want at most 1024", name, info.Size())
+		}
+	}
+	if _, err := os.Stat(path + ".3"); !os.IsNotExist(err) {
+		t.Errorf("expected no %s.3", path)
+	}
+
+	events, err := ReadEvents([]string{path}, EventFilter{})
+	if err != nil {
+		t.Fatalf("ReadEvents() error = %v", err)
+	}
+	if len(events) == 0 || events[len(events)-1].Value != 29 {
+		t.Fatalf("latest event missing: %+v", events)
+	}
+	for i := 1; i < len(events); i++ {
+		if events[i].Value != events[i-1].Value+1 {
+			t.Errorf("events out of order at %d: %v after %v", i, events[i].Value, events[i-1].Value)
+		}
+	}
+}
+
+// TestReadEventsFilter tests filtering events by time, metric and level, and the limit
+func TestReadEventsFilter(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "events.jsonl")
+	base := time.Date(2024, 5, 1, 8, 0, 0, 0, time.UTC)
+	lines := []string{
+		`{"timestamp":"2024-05-01T08:00:00Z","event":"alert","metric":"cpu","level":"warning","value":85}`,
+		`{"timestamp":"2024-05-01T09:00:00Z","event":"alert","metric":"disk","level":"critical","value":97}`,
+		`not json`,
+		`{"timestamp":"2024-05-01T10:00:00Z","event":"alert","metric":"cpu","level":"critical","value":96}`,
+		`{"timestamp":"2024-05-01T11:00:00Z","event":"resolved","metric":"cpu","level":"critical","value":50}`,
+	}
+	if err := os.WriteFile(path, []byte(strings.Join(lines, "\n")+"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		filter EventFilter
+		want   []float64
+	}{
+		{"all", EventFilter{}, []float64{85, 97, 96, 50}},
+		{"since", EventFilter{Since: base.Add(90 * time.Minute)}, []float64{96, 50}},
+		{"until", EventFilter{Until: base.Add(time.Hour)}, []float64{85, 97}},
+		{"metric", EventFilter{Metric: "cpu"}, []float64{85, 96, 50}},
+		{"level", EventFilter{Level: "critical", Metric: "cpu"}, []float64{96, 50}},
+		{"limit", EventFilter{Limit: 2}, []float64{96, 50}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			events, err := ReadEvents([]string{path, filepath.Join(t.TempDir(), "missing.jsonl")}, tt.filter)
+			if err != nil {
+				t.Fatalf("ReadEvents() error = %v", err)
+			}
+			var got []float64
+			for _, e := range events {
+				got = append(got, e.Value)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("got values %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("got values %v, want %v", got, tt.want)
+					break
+				}
+			}
+		})
+	}
+}
+
+// TestParseEventTime tests the accepted event query time formats
+func TestParseEventTime(t *testing.T) {
+	now := time.Date(2024, 5, 2, 12, 0, 0, 0, time.Local)
+	tests := []struct {
+		input   string
+		want    time.Time
+		wantErr bool
+	}{
+		{"24h", now.Add(-24 * time.Hour), false},
+		{"90m", now.Add(-90 * time.Minute), false},
+		{"2024-05-01", time.Date(2024, 5, 1, 0, 0, 0, 0, time.Local), false},
+		{"2024-05-01 08:30", time.Date(2024, 5, 1, 8, 30, 0, 0, time.Local), false},
+		{"2024-05-01T08:30:00Z", time.Date(2024, 5, 1, 8, 30, 0, 0, time.UTC), false},
+		{"yesterday", time.Time{}, true},
+	}
+	for _, tt := range tests {
+		got, err := ParseEventTime(tt.input, now)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseEventTime(%q) error = %v, wantErr %v", tt.input, err, tt.wantErr)
+			continue
+		}
+		if !got.Equal(tt.want) {
+			t.Errorf("ParseEventTime(%q) = %v, want %v", tt.input, got, tt.want)
+		}
+	}
+}
+
+// TestNewFileActionErrors tests invalid file action configurations
+func TestNewFileActionErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		config map[string]interface{}
+	}{
+		{"missing path", map[string]interface{}{}},
+		{"invalid size", map[string]interface{}{"path": "/tmp/events.jsonl", "max_size": "lots"}},
+		{"zero size", map[string]interface{}{"path": "/tmp/events.jsonl", "max_size": 0}},
+		{"negative files", map[string]interface{}{"path": "/tmp/events.jsonl", "max_files": -1}},
+	}
+	for _, tt := range tests {
+		if _, err := NewFileAction(tt.config); err == nil {
+			t.Errorf("%s: expected error", tt.name)
+		}
+	}
+}
+
+// TestConfigEventLogs tests collecting file action paths from levels, escalation and receivers
+func TestConfigEventLogs(t *testing.T) {
+	config := &Config{
+		Alerts: map[string]AlertLevel{
+			"critical": {
+				Actions:    []map[string]interface{}{{"type": "file", "path": "/var/log/a.jsonl"}, {"type": "stdout"}},
+				Escalation: []EscalationStep{{Actions: []map[string]interface{}{{"type": "file", "path": "/var/log/a.jsonl"}}}},
+			},
+		},
+		Receivers: []Receiver{{Name: "ops", Actions: []map[string]interface{}{{"type": "file", "path": "/var/log/b.jsonl"}}}},
+	}
+	got := config.EventLogs()
+	if len(got) != 2 || got[0] != "/var/log/a.jsonl" || got[1] != "/var/log/b.jsonl" {
+		t.Errorf("EventLogs() = %v", got)
+	}
+}
@@ -0,0 +1,378 @@
+package monitor
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Expression is a parsed boolean/arithmetic expression over metric variables.
+//
+// Supported syntax:
+//   - numbers (an optional trailing "%" is ignored: "90%" == 90)
+//   - variables such as cpu, load1, memory_free
+//   - arithmetic: + - * / and parentheses
+//   - comparisons: < <= > >= == !=
+//   - logic: && || ! (or the keywords and, or, not)
+//
+// Comparisons and logic operators yield 1 (true) or 0 (false); an expression
+// matches when it evaluates to a non-zero value.
+type Expression struct {
+	Source string
+	root   exprNode
+}
+
+// exprNode is a node of the expression tree
+type exprNode interface {
+	eval(vars map[string]float64) (float64, error)
+}
+
+type numberNode float64
+
+func (n numberNode) eval(vars map[string]float64) (float64, error) {
+	return float64(n), nil
+}
+
+type varNode string
+
+func (n varNode) eval(vars map[string]float64) (float64, error) {
+	value, ok := vars[string(n)]
+	if !ok {
+		return 0, fmt.Errorf("unknown variable '%s'", string(n))
+	}
+	return value, nil
+}
+
+type unaryNode struct {
+	op      string
+	operand exprNode
+}
+
+func (n unaryNode) eval(vars map[string]float64) (float64, error) {
+	value, err := n.operand.eval(vars)
+	if err != nil {
+		return 0, err
+	}
+	if n.op == "-" {
+		return -value, nil
+	}
+	return boolValue(value == 0), nil
+}
+
+type binaryNode struct {
+	op          string
+	left, right exprNode
+}
+
+func (n binaryNode) eval(vars map[string]float64) (float64, error) {
+	left, err := n.left.eval(vars)
+	if err != nil {
+		return 0, err
+	}
+
+	// Short-circuit logic operators
+	switch n.op {
+	case "&&":
+		if left == 0 {
+			return 0, nil
+		}
+	case "||":
+		if left != 0 {
+			return 1, nil
+		}
+	}
+
+	right, err := n.right.eval(vars)
+	if err != nil {
+		return 0, err
+	}
+
+	switch n.op {
+	case "&&", "||":
+		return boolValue(right != 0), nil
+	case "+":
+		return left + right, nil
+	case "-":
+		return left - right, nil
+	case "*":
+		return left * right, nil
+	case "/":
+		if right == 0 {
+			return 0, fmt.Errorf("division by zero")
+		}
+		return left / right, nil
+	case "<":
+		return boolValue(left < right), nil
+	case "<=":
+		return boolValue(left <= right), nil
+	case ">":
+		return boolValue(left > right), nil
+	case ">=":
+		return boolValue(left >= right), nil
+	case "==":
+		return boolValue(left == right), nil
+	case "!=":
+		return boolValue(left != right), nil
+	}
+	return 0, fmt.Errorf("unknown operator '%s'", n.op)
+}
+
+// boolValue converts a boolean to 1 or 0
+func boolValue(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// ParseExpression parses an expression string
+func ParseExpression(source string) (*Expression, error) {
+	tokens, err := tokenizeExpression(source)
+	if err != nil {
+		return nil, fmt.Errorf("invalid expression '%s': %w", source, err)
+	}
+
+	p := &exprParser{tokens: tokens}
+	root, err := p.parseOr()
+	if err != nil {
+		return nil, fmt.Errorf("invalid expression '%s': %w", source, err)
+	}
+	if p.pos < len(p.tokens) {
+		return nil, fmt.Errorf("invalid expression '%s': unexpected '%s'", source, p.tokens[p.pos].text)
+	}
+
+	return &Expression{Source: source, root: root}, nil
+}
+
+// Eval evaluates the expression with the given variables
+func (e *Expression) Eval(vars map[string]float64) (float64, error) {
+	return e.root.eval(vars)
+}
+
+// Match evaluates the expression and reports whether it is true (non-zero)
+func (e *Expression) Match(vars map[string]float64) (bool, error) {
+	value, err := e.Eval(vars)
+	if err != nil {
+		return false, err
+	}
+	return value != 0, nil
+}
+
+// Variables returns the variable names the expression refers to
+func (e *Expression) Variables() []string {
+	var names []string
+	seen := make(map[string]bool)
+	var walk func(n exprNode)
+	walk = func(n exprNode) {
+		switch node := n.(type) {
+		case varNode:
+			if !seen[string(node)] {
+				seen[string(node)] = true
+				names = append(names, string(node))
+			}
+		case unaryNode:
+			walk(node.operand)
+		case binaryNode:
+			walk(node.left)
+			walk(node.right)
+		}
+	}
+	walk(e.root)
+	return names
+}
+
+// exprToken is a lexical token of an expression
+type exprToken struct {
+	kind string // "num", "ident" or "op"
+	text string
+	num  float64
+}
+
+// tokenizeExpression splits an expression into tokens
+func tokenizeExpression(source string) ([]exprToken, error) {
+	var tokens []exprToken
+	runes := []rune(source)
+
+	for i := 0; i < len(runes); {
+		c := runes[i]
+		switch {
+		case unicode.IsSpace(c):
+			i++
+		case unicode.IsDigit(c) || c == '.':
+			start := i
+			for i < len(runes) && (unicode.IsDigit(runes[i]) || runes[i] == '.') {
+				i++
+			}
+			text := string(runes[start:i])
+			num, err := strconv.ParseFloat(text, 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid number '%s'", text)
+			}
+			if i < len(runes) && runes[i] == '%' {
+				i++
+			}
+			tokens = append(tokens, exprToken{kind: "num", text: text, num: num})
+		case unicode.IsLetter(c) || c == '_':
+			start := i
+			for i < len(runes) && (unicode.IsLetter(runes[i]) || unicode.IsDigit(runes[i]) || runes[i] == '_') {
+				i++
+			}
+			text := string(runes[start:i])
+			switch strings.ToLower(text) {
+			case "and":
+				tokens = append(tokens, exprToken{kind: "op", text: "&&"})
+			case "or":
+				tokens = append(tokens, exprToken{kind: "op", text: "||"})
+			case "not":
+				tokens = append(tokens, exprToken{kind: "op", text: "!"})
+			default:
+				tokens = append(tokens, exprToken{kind: "ident", text: text})
+			}
+		default:
+			if i+1 < len(runes) {
+				two := string(runes[i : i+2])
+				switch two {
+				case "&&", "||", "<=", ">=", "==", "!=":
+					tokens = append(tokens, exprToken{kind: "op", text: two})
+					i += 2
+					continue
+				}
+			}
+			switch c {
+			case '+', '-', '*', '/', '<', '>', '!', '(', ')':
+				tokens = append(tokens, exprToken{kind: "op", text: string(c)})
+				i++
+			default:
+				return nil, fmt.Errorf("unexpected character '%c'", c)
+			}
+		}
+	}
+
+	return tokens, nil
+}
+
+// exprParser is a recursive-descent parser over expression tokens
+type exprParser struct {
+	tokens []exprToken
+	pos    int
+}
+
+// peekOp returns the next token if it is one of the given operators
+func (p *exprParser) peekOp(ops ...string) (string, bool) {
+	if p.pos >= len(p.tokens) || p.tokens[p.pos].kind != "op" {
+		return "", false
+	}
+	for _, op := range ops {
+		if p.tokens[p.pos].text == op {
+			return op, true
+		}
+	}
+	return "", false
+}
+
+// parseBinary parses a left-associative chain of operators
+func (p *exprParser) parseBinary(next func() (exprNode, error), ops ...string) (exprNode, error) {
+	left, err := next()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		op, ok := p.peekOp(ops...)
+		if !ok {
+			return left, nil
+		}
+		p.pos++
+		right, err := next()
+		if err != nil {
+			return nil, err
+		}
+		left = binaryNode{op: op, left: left, right: right}
+	}
+}
+
+func (p *exprParser) parseOr() (exprNode, error) {
+	return p.parseBinary(p.parseAnd, "||")
+}
+
+func (p *exprParser) parseAnd() (exprNode, error) {
+	return p.parseBinary(p.parseNot, "&&")
+}
+
+func (p *exprParser) parseNot() (exprNode, error) {
+	if _, ok := p.peekOp("!"); ok {
+		p.pos++
+		operand, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		return unaryNode{op: "!", operand: operand}, nil
+	}
+	return p.parseComparison()
+}
+
+func (p *exprParser) parseComparison() (exprNode, error) {
+	left, err := p.parseSum()
+	if err != nil {
+		return nil, err
+	}
+	op, ok := p.peekOp("<", "<=", ">", ">=", "==", "!=")
+	if !ok {
+		return left, nil
+	}
+	p.pos++
+	right, err := p.parseSum()
+	if err != nil {
+		return nil, err
+	}
+	return binaryNode{op: op, left: left, right: right}, nil
+}
+
+func (p *exprParser) parseSum() (exprNode, error) {
+	return p.parseBinary(p.parseProduct, "+", "-")
+}
+
+func (p *exprParser) parseProduct() (exprNode, error) {
+	return p.parseBinary(p.parseUnary, "*", "/")
+}
+
+func (p *exprParser) parseUnary() (exprNode, error) {
+	if _, ok := p.peekOp("-"); ok {
+		p.pos++
+		operand, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return unaryNode{op: "-", operand: operand}, nil
+	}
+	return p.parsePrimary()
+}
+
+func (p *exprParser) parsePrimary() (exprNode, error) {
+	if p.pos >= len(p.tokens) {
+		return nil, fmt.Errorf("unexpected end of expression")
+	}
+
+	tok := p.tokens[p.pos]
+	p.pos++
+	switch tok.kind {
+	case "num":
+		return numberNode(tok.num), nil
+	case "ident":
+		return varNode(tok.text), nil
+	}
+
+	if tok.text == "(" {
+		inner, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := p.peekOp(")"); !ok {
+			return nil, fmt.Errorf("missing ')'")
+		}
+		p.pos++
+		return inner, nil
+	}
+
+	return nil, fmt.Errorf("unexpected '%s'", tok.text)
+}
@@ -0,0 +1,72 @@
+package monitor
+
+import "testing"
+
+// TestExpressionEval tests evaluating expressions over variables
+func TestExpressionEval(t *testing.T) {
+	vars := map[string]float64{"cpu": 95, "load1": 9, "cores": 4, "memory_free": 8, "swap_used": 60}
+
+	tests := []struct {
+		name    string
+		expr    string
+		want    float64
+		wantErr bool
+	}{
+		{name: "arithmetic precedence", expr: "1 + 2 * 3", want: 7},
+		{name: "parentheses", expr: "(1 + 2) * 3", want: 9},
+		{name: "unary minus", expr: "-cpu + 100", want: 5},
+		{name: "percent suffix", expr: "cpu > 90%", want: 1},
+		{name: "composite and", expr: "cpu > 90 && load1 > 2 * cores", want: 1},
+		{name: "composite keywords", expr: "memory_free < 10 and swap_used > 50", want: 1},
+		{name: "or short-circuits", expr: "cpu > 90 || unknown > 1", want: 1},
+		{name: "not", expr: "not cpu > 90", want: 0},
+		{name: "comparison false", expr: "load1 >= 3 * cores", want: 0},
+		{name: "equality", expr: "cores == 4 && cores != 5", want: 1},
+		{name: "division", expr: "load1 / cores", want: 2.25},
+		{name: "division by zero", expr: "cpu / 0", wantErr: true},
+		{name: "unknown variable", expr: "iowait > 5", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			expr, err := ParseExpression(tt.expr)
+			if err != nil {
+				t.Fatalf("ParseExpression() error = %v", err)
+			}
+			got, err := expr.Eval(vars)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Eval() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && got != tt.want {
+				t.Errorf("Eval() = %f, want %f", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestParseExpressionErrors tests rejecting malformed expressions
+func TestParseExpressionErrors(t *testing.T) {
+	for _, source := range []string{"", "cpu >", "(cpu > 1", "cpu > 1)", "cpu $ 1", "cpu 1", "1..2 > 0"} {
+		if _, err := ParseExpression(source); err == nil {
+			t.Errorf("ParseExpression(%q) expected error", source)
+		}
+	}
+}
+
+// TestExpressionVariables tests listing the variables an expression uses
+func TestExpressionVariables(t *testing.T) {
+	expr, err := ParseExpression("load1 > 2 * cores && cpu > 90 && load1 > 1")
+	if err != nil {
+		t.Fatalf("ParseExpression() error = %v", err)
+	}
+	got := expr.Variables()
+	want := []string{"load1", "cores", "cpu"}
+	if len(got) != len(want) {
+		t.Fatalf("Variables() = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("Variables()[%d] = %s, want %s", i, got[i], want[i])
+		}
+	}
+}
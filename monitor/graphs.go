@@ -89,7 +89,8 @@ func GenerateAllGraphs(rrdPath string, config *Config) error {
 		graphConfig := DefaultGraphConfig(metric, rrdPath)
 
 		// Get thresholds from config
-		if metricConfig, ok := config.GetMetricConfig(metric); ok {
+		// Only percentage thresholds fit the 0-100 graph scale
+		if metricConfig, ok := config.GetMetricConfig(metric); ok && metricConfig.Mode != "anomaly" && isPercentageUnit(metricConfig.Unit) {
 			graphConfig.WarningThresh = metricConfig.Thresholds["warning"]
 			graphConfig.CriticalThresh = metricConfig.Thresholds["critical"]
 		}
@@ -0,0 +1,314 @@
+package monitor
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// GroupFile stores alert groups and the pending digest between checks
+const GroupFile = "/tmp/tfc-monitor-groups.json"
+
+// Grouping defaults
+const (
+	defaultGroupWait     = 30 * time.Second
+	defaultGroupInterval = 5 * time.Minute
+	defaultDigestTime    = "09:00"
+)
+
+// GroupingConfig configures batching of related violations into one notification
+type GroupingConfig struct {
+	GroupBy       []string     `yaml:"group_by"`       // "host", "level", "metric", "mountpoint", "device" or label names
+	GroupWait     string       `yaml:"group_wait"`     // Delay before the first notification of a new group (default: "30s")
+	GroupInterval string       `yaml:"group_interval"` // Minimum time between notifications of a group (default: "5m")
+	Digest        DigestConfig `yaml:"digest"`
+}
+
+// DigestConfig configures the daily digest of warnings
+type DigestConfig struct {
+	Enabled bool   `yaml:"enabled"`
+	Time    string `yaml:"time"` // Local time of day "HH:MM" the digest is sent (default: "09:00")
+}
+
+// AlertGroup collects violations that share the values of the group_by labels
+type AlertGroup struct {
+	Key       string               `json:"key"`
+	Pending   []ThresholdViolation `json:"pending"`         // violations not yet notified
+	Stats     *SystemStats         `json:"stats,omitempty"` // latest stats snapshot of the pending violations
+	FirstSeen time.Time            `json:"first_seen"`
+	LastSent  *time.Time           `json:"last_sent,omitempty"`
+}
+
+// Notification is a set of violations to deliver together
+type Notification struct {
+	Group    string // group key, "digest" for the warning digest; empty without grouping
+	Warning  []ThresholdViolation
+	Critical []ThresholdViolation
+}
+
+// newNotification splits violations by level into a notification
+func newNotification(group string, violations []ThresholdViolation) Notification {
+	n := Notification{Group: group}
+	for _, v := range violations {
+		if v.Level == "critical" {
+			n.Critical = append(n.Critical, v)
+		} else {
+			n.Warning = append(n.Warning, v)
+		}
+	}
+	return n
+}
+
+// groupState is persisted in GroupFile
+type groupState struct {
+	Groups     map[string]*AlertGroup `json:"groups"`
+	Digest     []ThresholdViolation   `json:"digest"`
+	LastDigest time.Time              `json:"last_digest"`
+}
+
+// enabled reports whether grouping or the digest is configured
+func (gc GroupingConfig) enabled() bool {
+	return len(gc.GroupBy) > 0 || gc.Digest.Enabled
+}
+
+// durations returns group_wait and group_interval
+func (gc GroupingConfig) durations() (time.Duration, time.Duration, error) {
+	wait, interval := defaultGroupWait, defaultGroupInterval
+	if gc.GroupWait != "" {
+		d, err := parseDuration(gc.GroupWait)
+		if err != nil {
+			return 0, 0, fmt.Errorf("grouping group_wait: %w", err)
+		}
+		wait = d
+	}
+	if gc.GroupInterval != "" {
+		d, err := parseDuration(gc.GroupInterval)
+		if err != nil {
+			return 0, 0, fmt.Errorf("grouping group_interval: %w", err)
+		}
+		interval = d
+	}
+	if wait < 0 || interval < 0 {
+		return 0, 0, fmt.Errorf("grouping durations must not be negative")
+	}
+	return wait, interval, nil
+}
+
+// digestTime returns the digest time of day in minutes since midnight
+func (dc DigestConfig) digestTime() (int, error) {
+	if dc.Time == "" {
+		return parseClock(defaultDigestTime)
+	}
+	minutes, err := parseClock(dc.Time)
+	if err != nil {
+		return 0, fmt.Errorf("digest time: %w", err)
+	}
+	return minutes, nil
+}
+
+// validate checks the grouping settings
+func (gc GroupingConfig) validate() error {
+	for _, label := range gc.GroupBy {
+		if label == "" {
+			return fmt.Errorf("grouping group_by entries must not be empty")
+		}
+	}
+	if _, _, err := gc.durations(); err != nil {
+		return err
+	}
+	_, err := gc.Digest.digestTime()
+	return err
+}
+
+// groupKey returns the key of the group a violation belongs to
+func groupKey(v ThresholdViolation, groupBy []string, hostname string) string {
+	labels := v.routingLabels()
+	labels["host"] = hostname
+
+	parts := make([]string, len(groupBy))
+	for i, name := range groupBy {
+		parts[i] = fmt.Sprintf("%s=%s", name, labels[name])
+	}
+	return strings.Join(parts, ",")
+}
+
+// addViolation adds v to list, replacing an earlier violation with the same state key
+func addViolation(list []ThresholdViolation, v ThresholdViolation) []ThresholdViolation {
+	for i, existing := range list {
+		if existing.StateKey() == v.StateKey() {
+			list[i] = v
+			return list
+		}
+	}
+	return append(list, v)
+}
+
+// GroupViolations holds violations back according to the grouping config and returns
+// the notifications that are due: one per group whose group_wait or group_interval
+// elapsed, and the warning digest once a day. Held violations that are no longer active
+// in stateManager are dropped, and those in the returned notifications are marked as
+// notified. Without grouping, all violations are returned as one notification. Call it
+// every check, also without new violations.
+func GroupViolations(config *Config, stateManager *StateManager, warningViolations []ThresholdViolation, criticalViolations []ThresholdViolation) ([]Notification, error) {
+	if !config.Grouping.enabled() {
+		if len(warningViolations) == 0 && len(criticalViolations) == 0 {
+			return nil, nil
+		}
+		return []Notification{{Warning: warningViolations, Critical: criticalViolations}}, nil
+	}
+	notifications, err := groupViolations(config.Grouping, GroupFile, stateManager, warningViolations, criticalViolations, time.Now())
+	if err != nil || len(notifications) == 0 || stateManager == nil {
+		return notifications, err
+	}
+	if err := stateManager.Save(); err != nil {
+		return nil, err
+	}
+	return notifications, nil
+}
+
+// groupViolations implements GroupViolations with the state in path, without saving
+// stateManager; a nil stateManager keeps all held violations
+func groupViolations(gc GroupingConfig, path string, stateManager *StateManager, warningViolations []ThresholdViolation, criticalViolations []ThresholdViolation, now time.Time) ([]Notification, error) {
+	wait, interval, err := gc.durations()
+	if err != nil {
+		return nil, err
+	}
+	state, err := loadGroupState(path)
+	if err != nil {
+		return nil, err
+	}
+
+	// A violation that resolved while held must not be sent after its resolved update
+	if stateManager != nil {
+		for _, group := range state.Groups {
+			group.Pending = stateManager.activeViolations(group.Pending)
+		}
+		state.Digest = stateManager.activeViolations(state.Digest)
+	}
+
+	var notifications []Notification
+	var ungrouped []ThresholdViolation
+	incoming := append(append([]ThresholdViolation{}, criticalViolations...), warningViolations...)
+	hostname, _ := os.Hostname()
+
+	for _, v := range incoming {
+		// Warnings wait for the digest when it is enabled
+		if gc.Digest.Enabled && v.Level == "warning" {
+			state.Digest = addViolation(state.Digest, v)
+			continue
+		}
+		if len(gc.GroupBy) == 0 {
+			ungrouped = append(ungrouped, v)
+			continue
+		}
+
+		key := groupKey(v, gc.GroupBy, hostname)
+		group, ok := state.Groups[key]
+		if !ok {
+			group = &AlertGroup{Key: key, FirstSeen: now}
+			state.Groups[key] = group
+		}
+		group.Pending = addViolation(group.Pending, v)
+		if v.Stats != nil {
+			group.Stats = v.Stats
+		}
+	}
+
+	if len(ungrouped) > 0 {
+		notifications = append(notifications, newNotification("", ungrouped))
+	}
+
+	// Flush groups that are due, and forget idle ones
+	keys := make([]string, 0, len(state.Groups))
+	for key := range state.Groups {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		group := state.Groups[key]
+		if len(group.Pending) == 0 {
+			if group.LastSent == nil || now.Sub(*group.LastSent) >= interval {
+				delete(state.Groups, key)
+			}
+			continue
+		}
+
+		ready := group.LastSent == nil && now.Sub(group.FirstSeen) >= wait
+		ready = ready || (group.LastSent != nil && now.Sub(*group.LastSent) >= interval)
+		if !ready {
+			continue
+		}
+
+		log.Printf("Alert group %s: notifying %d violations", key, len(group.Pending))
+		for i := range group.Pending {
+			group.Pending[i].Stats = group.Stats
+		}
+		notifications = append(notifications, newNotification(key, group.Pending))
+		sent := now
+		group.LastSent = &sent
+		group.Pending = nil
+		group.Stats = nil
+	}
+
+	// Send the digest once a day at the configured time
+	if gc.Digest.Enabled && len(state.Digest) > 0 {
+		minutes, err := gc.Digest.digestTime()
+		if err != nil {
+			return nil, err
+		}
+		today := time.Date(now.Year(), now.Month(), now.Day(), minutes/60, minutes%60, 0, 0, now.Location())
+		if !now.Before(today) && state.LastDigest.Before(today) {
+			log.Printf("Sending warning digest with %d violations", len(state.Digest))
+			notifications = append(notifications, newNotification("digest", state.Digest))
+			state.Digest = nil
+			state.LastDigest = now
+		}
+	}
+
+	if err := state.save(path); err != nil {
+		return nil, err
+	}
+	if stateManager != nil {
+		for _, n := range notifications {
+			stateManager.markNotified(n.Warning)
+			stateManager.markNotified(n.Critical)
+		}
+	}
+	return notifications, nil
+}
+
+// loadGroupState reads the group state, returning an empty state if the file does not exist
+func loadGroupState(path string) (*groupState, error) {
+	state := &groupState{Groups: make(map[string]*AlertGroup)}
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return state, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to read group file: %w", err)
+	}
+
+	if err := json.Unmarshal(data, state); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal group file: %w", err)
+	}
+	if state.Groups == nil {
+		state.Groups = make(map[string]*AlertGroup)
+	}
+	return state, nil
+}
+
+// save writes the group state
+func (gs *groupState) save(path string) error {
+	data, err := json.MarshalIndent(gs, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal group state: %w", err)
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write group file: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,221 @@
+package monitor
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// TestGroupViolationsWaitAndInterval tests group_wait, group_interval and batching by group
+func TestGroupViolationsWaitAndInterval(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "groups.json")
+	gc := GroupingConfig{GroupBy: []string{"level"}, GroupWait: "30s", GroupInterval: "5m"}
+	start := time.Date(2024, 1, 15, 12, 0, 0, 0, time.Local)
+
+	cpu := ThresholdViolation{Metric: "cpu", Level: "critical", Message: "CPU 99%"}
+	memory := ThresholdViolation{Metric: "memory", Level: "critical", Message: "Memory 97%"}
+	disk := ThresholdViolation{Metric: "disk", Level: "critical", Mountpoint: "/", Message: "Disk 95%"}
+
+	// A new group waits for group_wait
+	notifications, err := groupViolations(gc, path, nil, nil, []ThresholdViolation{cpu}, start)
+	if err != nil || len(notifications) != 0 {
+		t.Fatalf("first check = %v, %v, want no notification during group_wait", notifications, err)
+	}
+
+	// Related violations join the group and are sent together once group_wait elapsed
+	notifications, err = groupViolations(gc, path, nil, nil, []ThresholdViolation{memory, cpu}, start.Add(30*time.Second))
+	if err != nil {
+		t.Fatalf("groupViolations() error = %v", err)
+	}
+	if len(notifications) != 1 || len(notifications[0].Critical) != 2 || notifications[0].Group != "level=critical" {
+		t.Fatalf("notifications = %+v, want one group with cpu and memory", notifications)
+	}
+
+	// New violations within group_interval are held back
+	notifications, _ = groupViolations(gc, path, nil, nil, []ThresholdViolation{disk}, start.Add(2*time.Minute))
+	if len(notifications) != 0 {
+		t.Fatalf("notifications = %+v, want none within group_interval", notifications)
+	}
+
+	// and sent after group_interval, even without new violations
+	notifications, _ = groupViolations(gc, path, nil, nil, nil, start.Add(5*time.Minute+30*time.Second))
+	if len(notifications) != 1 || len(notifications[0].Critical) != 1 || notifications[0].Critical[0].Metric != "disk" {
+		t.Fatalf("notifications = %+v, want disk after group_interval", notifications)
+	}
+
+	// An idle group is forgotten, so the next violation waits for group_wait again
+	notifications, _ = groupViolations(gc, path, nil, nil, nil, start.Add(11*time.Minute))
+	if len(notifications) != 0 {
+		t.Fatalf("notifications = %+v, want none", notifications)
+	}
+	state, _ := loadGroupState(path)
+	if len(state.Groups) != 0 {
+		t.Errorf("idle groups were not removed: %v", state.Groups)
+	}
+}
+
+// TestGroupViolationsGroupBy tests that violations are split by the group_by labels
+func TestGroupViolationsGroupBy(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "groups.json")
+	gc := GroupingConfig{GroupBy: []string{"metric"}, GroupWait: "0s"}
+	now := time.Now()
+
+	violations := []ThresholdViolation{
+		{Metric: "disk", Level: "warning", Mountpoint: "/"},
+		{Metric: "disk", Level: "warning", Mountpoint: "/var"},
+		{Metric: "cpu", Level: "warning"},
+	}
+	notifications, err := groupViolations(gc, path, nil, violations, nil, now)
+	if err != nil {
+		t.Fatalf("groupViolations() error = %v", err)
+	}
+
+	got := map[string]int{}
+	for _, n := range notifications {
+		got[n.Group] = len(n.Warning)
+	}
+	if got["metric=disk"] != 2 || got["metric=cpu"] != 1 || len(got) != 2 {
+		t.Errorf("groups = %v, want disk with 2 and cpu with 1", got)
+	}
+}
+
+// TestGroupViolationsDigest tests the daily warning digest
+func TestGroupViolationsDigest(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "groups.json")
+	gc := GroupingConfig{Digest: DigestConfig{Enabled: true, Time: "09:00"}}
+	morning := time.Date(2024, 1, 15, 8, 0, 0, 0, time.Local)
+
+	warning := ThresholdViolation{Metric: "memory", Level: "warning"}
+	critical := ThresholdViolation{Metric: "cpu", Level: "critical"}
+
+	// Criticals are sent immediately, warnings wait for the digest
+	notifications, err := groupViolations(gc, path, nil, []ThresholdViolation{warning}, []ThresholdViolation{critical}, morning)
+	if err != nil {
+		t.Fatalf("groupViolations() error = %v", err)
+	}
+	if len(notifications) != 1 || len(notifications[0].Critical) != 1 || len(notifications[0].Warning) != 0 {
+		t.Fatalf("notifications = %+v, want only the critical", notifications)
+	}
+
+	// The same warning again is not duplicated in the digest
+	groupViolations(gc, path, nil, []ThresholdViolation{warning}, nil, morning.Add(30*time.Minute))
+
+	// The digest is sent at the configured time
+	notifications, _ = groupViolations(gc, path, nil, nil, nil, morning.Add(time.Hour))
+	if len(notifications) != 1 || notifications[0].Group != "digest" || len(notifications[0].Warning) != 1 {
+		t.Fatalf("notifications = %+v, want digest with one warning", notifications)
+	}
+
+	// and only once a day
+	groupViolations(gc, path, nil, []ThresholdViolation{warning}, nil, morning.Add(2*time.Hour))
+	notifications, _ = groupViolations(gc, path, nil, nil, nil, morning.Add(3*time.Hour))
+	if len(notifications) != 0 {
+		t.Errorf("notifications = %+v, want no second digest on the same day", notifications)
+	}
+	notifications, _ = groupViolations(gc, path, nil, nil, nil, morning.Add(25*time.Hour))
+	if len(notifications) != 1 || notifications[0].Group != "digest" {
+		t.Errorf("notifications = %+v, want the next day's digest", notifications)
+	}
+}
+
+// TestGroupViolationsDropsResolved tests that held violations are dropped once they resolve
+func TestGroupViolationsDropsResolved(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "groups.json")
+	gc := GroupingConfig{GroupBy: []string{"level"}, GroupWait: "30s", Digest: DigestConfig{Enabled: true, Time: "09:00"}}
+	morning := time.Date(2024, 1, 15, 8, 0, 0, 0, time.Local)
+
+	warning := ThresholdViolation{Metric: "memory", Level: "warning"}
+	cpu := ThresholdViolation{Metric: "cpu", Level: "critical"}
+	disk := ThresholdViolation{Metric: "disk", Level: "critical", Mountpoint: "/"}
+	sm := &StateManager{States: map[string]*ViolationState{
+		warning.StateKey(): {HasAlerted: true},
+		cpu.StateKey():     {HasAlerted: true},
+		disk.StateKey():    {HasAlerted: true},
+	}}
+	groupViolations(gc, path, sm, []ThresholdViolation{warning}, []ThresholdViolation{cpu, disk}, morning)
+
+	// memory and cpu resolve while held
+	delete(sm.States, warning.StateKey())
+	delete(sm.States, cpu.StateKey())
+	notifications, err := groupViolations(gc, path, sm, nil, nil, morning.Add(time.Hour))
+	if err != nil {
+		t.Fatalf("groupViolations() error = %v", err)
+	}
+	if len(notifications) != 1 || len(notifications[0].Critical) != 1 || notifications[0].Critical[0].Metric != "disk" {
+		t.Fatalf("notifications = %+v, want only disk and no digest", notifications)
+	}
+}
+
+// TestGroupViolationsDisabled tests that violations pass through without grouping
+func TestGroupViolationsDisabled(t *testing.T) {
+	config := &Config{}
+	notifications, err := GroupViolations(config, nil, []ThresholdViolation{{Metric: "cpu", Level: "warning"}}, nil)
+	if err != nil || len(notifications) != 1 || len(notifications[0].Warning) != 1 {
+		t.Errorf("GroupViolations() = %+v, %v", notifications, err)
+	}
+	if notifications, _ := GroupViolations(config, nil, nil, nil); notifications != nil {
+		t.Errorf("GroupViolations() = %+v, want nil without violations", notifications)
+	}
+}
+
+// TestGroupViolationsNotified tests that only violations sent in a notification resolve
+// and escalate: a warning held for the digest that clears before it is sent does neither
+func TestGroupViolationsNotified(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "groups.json")
+	stdout := []map[string]interface{}{{"type": "stdout"}}
+	config := &Config{
+		Grouping: GroupingConfig{GroupBy: []string{"level"}, GroupWait: "30s", Digest: DigestConfig{Enabled: true, Time: "09:00"}},
+		Alerts: map[string]AlertLevel{
+			"warning":  {Escalation: []EscalationStep{{After: "0s", Actions: stdout}}},
+			"critical": {Escalation: []EscalationStep{{After: "0s", Actions: stdout}}},
+		},
+	}
+	sm := &StateManager{
+		StateFile: filepath.Join(t.TempDir(), "state.json"),
+		States:    make(map[string]*ViolationState),
+	}
+	morning := time.Date(2024, 1, 15, 8, 0, 0, 0, time.Local)
+	warning := ThresholdViolation{Metric: "memory", Level: "warning"}
+	cpu := ThresholdViolation{Metric: "cpu", Level: "critical"}
+	violations := []ThresholdViolation{warning, cpu}
+
+	throttled, err := applyThrottling(config, violations, sm)
+	if err != nil || len(throttled) != 2 {
+		t.Fatalf("applyThrottling() = %v, %v", throttled, err)
+	}
+	if notifications, _ := groupViolations(config.Grouping, path, sm, []ThresholdViolation{warning}, []ThresholdViolation{cpu}, morning); len(notifications) != 0 {
+		t.Fatalf("notifications = %+v, want all held", notifications)
+	}
+	if err := collectEscalations(config, violations, sm, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	if escalations := sm.TakeEscalations(); len(escalations) != 0 {
+		t.Errorf("escalations = %+v, want none before the first notification", escalations)
+	}
+
+	// The group of cpu is sent; the warning waits for the digest
+	notifications, _ := groupViolations(config.Grouping, path, sm, nil, nil, morning.Add(time.Minute))
+	if len(notifications) != 1 || len(notifications[0].Critical) != 1 {
+		t.Fatalf("notifications = %+v, want the critical group", notifications)
+	}
+	if !sm.States[cpu.StateKey()].Notified || sm.States[warning.StateKey()].Notified {
+		t.Errorf("states = %+v, want only cpu notified", sm.States)
+	}
+	if err := collectEscalations(config, violations, sm, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	if escalations := sm.TakeEscalations(); len(escalations) != 1 || escalations[0].Violation.Metric != "cpu" {
+		t.Errorf("escalations = %+v, want only cpu", escalations)
+	}
+
+	// Both clear before the digest: only cpu gets a resolved update, and no digest is sent
+	if err := clearResolvedViolations(nil, sm); err != nil {
+		t.Fatal(err)
+	}
+	if resolved := sm.TakeResolved(); len(resolved) != 1 || resolved[0].Metric != "cpu" {
+		t.Errorf("resolved = %+v, want only cpu", resolved)
+	}
+	if notifications, _ := groupViolations(config.Grouping, path, sm, nil, nil, morning.Add(2*time.Hour)); len(notifications) != 0 {
+		t.Errorf("notifications = %+v, want no digest", notifications)
+	}
+}
@@ -0,0 +1,181 @@
+package monitor
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"log"
+	"log/syslog"
+	"net"
+	"sort"
+	"strings"
+	"time"
+)
+
+// DefaultJournaldSocket is the native protocol socket of systemd-journald
+const DefaultJournaldSocket = "/run/systemd/journal/socket"
+
+// JournaldAction writes alerts to the systemd journal with structured fields
+type JournaldAction struct {
+	Socket   string
+	Tag      string            // SYSLOG_IDENTIFIER
+	Priority string            // Priority name; derived from the violation's level if empty
+	Fields   map[string]string // Extra fields added to every entry
+}
+
+// NewJournaldAction creates a new journald alert action
+func NewJournaldAction(config map[string]interface{}) (*JournaldAction, error) {
+	ja := &JournaldAction{
+		Socket: DefaultJournaldSocket,
+		Tag:    "tfc-system-monitor",
+	}
+
+	if socket, ok := config["socket"].(string); ok && socket != "" {
+		ja.Socket = socket
+	}
+	if tag, ok := config["tag"].(string); ok && tag != "" {
+		ja.Tag = tag
+	}
+	if priority, ok := config["priority"].(string); ok {
+		if _, exists := priorityMap[priority]; !exists {
+			return nil, fmt.Errorf("invalid journald priority '%s'", priority)
+		}
+		ja.Priority = priority
+	}
+
+	fields, err := actionStringMap(config, "fields")
+	if err != nil {
+		return nil, err
+	}
+	for name := range fields {
+		if !validJournalField(name) {
+			return nil, fmt.Errorf("invalid journald field name '%s' (use A-Z, 0-9 and _, not starting with _)", name)
+		}
+	}
+	ja.Fields = fields
+
+	return ja, nil
+}
+
+// Execute writes a violation to the journal
+func (ja *JournaldAction) Execute(violation ThresholdViolation) error {
+	message := fmt.Sprintf("[%s] %s: %s", strings.ToUpper(violation.Level), violation.Metric, violation.Message)
+	if err := ja.send(ja.entry(violation, message, ja.priority(violation.Level), "firing")); err != nil {
+		return err
+	}
+	log.Printf("Journald alert sent: %s", message)
+	return nil
+}
+
+// Resolve writes to the journal that a violation resolved
+func (ja *JournaldAction) Resolve(violation ThresholdViolation) error {
+	message := fmt.Sprintf("[RESOLVED] %s: %s", violation.Metric, violation.Message)
+	return ja.send(ja.entry(violation, message, syslog.LOG_INFO, "resolved"))
+}
+
+// priority returns the journal priority of a violation level
+func (ja *JournaldAction) priority(level string) syslog.Priority {
+	if ja.Priority != "" {
+		level = ja.Priority
+	}
+	if priority, ok := priorityMap[level]; ok {
+		return priority
+	}
+	return syslog.LOG_NOTICE
+}
+
+// entry builds the journal fields of a violation
+func (ja *JournaldAction) entry(violation ThresholdViolation, message string, priority syslog.Priority, status string) map[string]string {
+	fields := map[string]string{
+		"MESSAGE":           message,
+		"PRIORITY":          fmt.Sprintf("%d", priority),
+		"SYSLOG_IDENTIFIER": ja.Tag,
+		"METRIC":            violation.Metric,
+		"LEVEL":             violation.Level,
+		"VALUE":             fmt.Sprintf("%g", violation.Value),
+		"ALERT_STATUS":      status,
+	}
+	if violation.Threshold != 0 {
+		fields["THRESHOLD"] = fmt.Sprintf("%g", violation.Threshold)
+	}
+	if violation.Mountpoint != "" {
+		fields["MOUNTPOINT"] = violation.Mountpoint
+	}
+	if violation.Device != "" {
+		fields["DEVICE"] = violation.Device
+	}
+	for name, value := range violation.Labels {
+		fields["LABEL_"+journalFieldName(name)] = value
+	}
+	for name, value := range ja.Fields {
+		fields[name] = value
+	}
+	return fields
+}
+
+// send writes an entry to the journal socket
+func (ja *JournaldAction) send(fields map[string]string) error {
+	conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: ja.Socket, Net: "unixgram"})
+	if err != nil {
+		return fmt.Errorf("failed to connect to journald: %w", err)
+	}
+	defer conn.Close()
+
+	conn.SetWriteDeadline(time.Now().Add(5 * time.Second))
+	if _, err := conn.Write(encodeJournalEntry(fields)); err != nil {
+		return fmt.Errorf("failed to send journald alert: %w", err)
+	}
+	return nil
+}
+
+// encodeJournalEntry encodes fields in the journal native protocol. Values containing
+// newlines are written as the field name, a newline, their little-endian 64-bit length
+// and the raw value.
+func encodeJournalEntry(fields map[string]string) []byte {
+	names := make([]string, 0, len(fields))
+	for name := range fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var buf bytes.Buffer
+	for _, name := range names {
+		value := fields[name]
+		buf.WriteString(name)
+		if strings.Contains(value, "\n") {
+			buf.WriteByte('\n')
+			binary.Write(&buf, binary.LittleEndian, uint64(len(value)))
+		} else {
+			buf.WriteByte('=')
+		}
+		buf.WriteString(value)
+		buf.WriteByte('\n')
+	}
+	return buf.Bytes()
+}
+
+// validJournalField reports whether name is a valid journal field name
+func validJournalField(name string) bool {
+	if name == "" || len(name) > 64 || name[0] == '_' || (name[0] >= '0' && name[0] <= '9') {
+		return false
+	}
+	for _, r := range name {
+		if !(r >= 'A' && r <= 'Z') && !(r >= '0' && r <= '9') && r != '_' {
+			return false
+		}
+	}
+	return true
+}
+
+// journalFieldName turns a label name into a journal field name suffix
+func journalFieldName(name string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z':
+			return r - 'a' + 'A'
+		case (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9'):
+			return r
+		}
+		return '_'
+	}, name)
+}
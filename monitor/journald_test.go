@@ -0,0 +1,191 @@
+package monitor
+
+import (
+	"bytes"
+	"encoding/binary"
+	"net"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// newJournalSocket starts a local stand-in for the journald socket
+func newJournalSocket(t *testing.T) (string, func() map[string]string) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "journal.sock")
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: path, Net: "unixgram"})
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	return path, func() map[string]string {
+		buf := make([]byte, 64*1024)
+		conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+		n, err := conn.Read(buf)
+		if err != nil {
+			t.Fatalf("failed to read journal entry: %v", err)
+		}
+		return decodeJournalEntry(t, buf[:n])
+	}
+}
+
+// decodeJournalEntry parses an entry in the journal native protocol
+func decodeJournalEntry(t *testing.T, data []byte) map[string]string {
+	t.Helper()
+	fields := make(map[string]string)
+	for len(data) > 0 {
+		nl := bytes.IndexByte(data, '\n')
+		if nl < 0 {
+			t.Fatalf("unterminated field: %q", data)
+		}
+		line := data[:nl]
+		if eq := bytes.IndexByte(line, '='); eq >= 0 {
+			fields[string(line[:eq])] = string(line[eq+1:])
+			data = data[nl+1:]
+			continue
+		}
+		size := binary.LittleEndian.Uint64(data[nl+1 : nl+9])
+		fields[string(line)] = string(data[nl+9 : nl+9+int(size)])
+		data = data[nl+9+int(size)+1:]
+	}
+	return fields
+}
+
+// TestJournaldAction tests the structured fields and priority of journal entries
+func TestJournaldAction(t *testing.T) {
+	socket, read := newJournalSocket(t)
+	action, err := CreateAction(map[string]interface{}{
+		"type":   "journald",
+		"socket": socket,
+		"tag":    "web-monitor",
+		"fields": map[interface{}]interface{}{"TEAM": "ops"},
+	})
+	if err != nil {
+		t.Fatalf("CreateAction() error = %v", err)
+	}
+
+	violation := ThresholdViolation{
+		Metric: "disk", Level: "critical", Value: 97.5, Threshold: 95, Mountpoint: "/var",
+		Message: "disk usage: 97.5%\nlargest: /var/log", Labels: map[string]string{"service-tier": "db"},
+	}
+	if err := action.Execute(violation); err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	fields := read()
+	want := map[string]string{
+		"MESSAGE":            "[CRITICAL] disk: disk usage: 97.5%\nlargest: /var/log",
+		"PRIORITY":           "2",
+		"SYSLOG_IDENTIFIER":  "web-monitor",
+		"METRIC":             "disk",
+		"LEVEL":              "critical",
+		"VALUE":              "97.5",
+		"THRESHOLD":          "95",
+		"MOUNTPOINT":         "/var",
+		"LABEL_SERVICE_TIER": "db",
+		"ALERT_STATUS":       "firing",
+		"TEAM":               "ops",
+	}
+	for name, value := range want {
+		if fields[name] != value {
+			t.Errorf("%s = %q, want %q", name, fields[name], value)
+		}
+	}
+	if _, ok := fields["DEVICE"]; ok {
+		t.Errorf("unexpected empty DEVICE field")
+	}
+
+	if err := action.Execute(ThresholdViolation{Metric: "cpu", Level: "warning", Value: 85}); err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if fields := read(); fields["PRIORITY"] != "4" {
+		t.Errorf("warning PRIORITY = %q, want 4", fields["PRIORITY"])
+	}
+
+	if err := action.(ResolvingAlertAction).Resolve(violation); err != nil {
+		t.Fatalf("Resolve() error = %v", err)
+	}
+	if fields := read(); fields["PRIORITY"] != "6" || fields["ALERT_STATUS"] != "resolved" {
+		t.Errorf("resolved entry = %v", fields)
+	}
+}
+
+// TestJournaldActionPriority tests overriding the priority
+func TestJournaldActionPriority(t *testing.T) {
+	socket, read := newJournalSocket(t)
+	action, err := NewJournaldAction(map[string]interface{}{"socket": socket, "priority": "alert"})
+	if err != nil {
+		t.Fatalf("NewJournaldAction() error = %v", err)
+	}
+	if err := action.Execute(ThresholdViolation{Metric: "cpu", Level: "warning", Value: 85}); err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if fields := read(); fields["PRIORITY"] != "1" || fields["SYSLOG_IDENTIFIER"] != "tfc-system-monitor" {
+		t.Errorf("entry = %v", fields)
+	}
+}
+
+// TestJournaldActionErrors tests invalid journald configurations and a missing socket
+func TestJournaldActionErrors(t *testing.T) {
+	for _, config := range []map[string]interface{}{
+		{"priority": "loud"},
+		{"fields": map[string]interface{}{"team": "ops"}},
+		{"fields": map[string]interface{}{"_PID": "1"}},
+	} {
+		if _, err := NewJournaldAction(config); err == nil {
+			t.Errorf("NewJournaldAction(%v) expected error", config)
+		}
+	}
+
+	action, _ := NewJournaldAction(map[string]interface{}{"socket": filepath.Join(t.TempDir(), "missing.sock")})
+	if err := action.Execute(ThresholdViolation{Metric: "cpu", Level: "warning"}); err == nil {
+		t.Error("Execute() expected error for missing socket")
+	}
+}
+
+// TestLoggerActionArgs tests the logger priority, tag and id
+func TestLoggerActionArgs(t *testing.T) {
+	violation := ThresholdViolation{Metric: "cpu", Level: "critical", Message: "CPU usage: 97%"}
+	tests := []struct {
+		name   string
+		config map[string]interface{}
+		want   []string
+	}{
+		{
+			name:   "defaults",
+			config: map[string]interface{}{},
+			want:   []string{"-e", "-p", "user.crit", "-t", "ALERT", "--id=451", "-s", "[CRITICAL] cpu: CPU usage: 97%"},
+		},
+		{
+			name:   "configured",
+			config: map[string]interface{}{"level": "warning", "tag": "tfc-monitor", "id": 1234},
+			want:   []string{"-e", "-p", "user.warning", "-t", "tfc-monitor", "--id=1234", "-s", "[CRITICAL] cpu: CPU usage: 97%"},
+		},
+		{
+			name:   "without id",
+			config: map[string]interface{}{"id": ""},
+			want:   []string{"-e", "-p", "user.crit", "-t", "ALERT", "-s", "[CRITICAL] cpu: CPU usage: 97%"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			action, err := NewLoggerAction(tt.config)
+			if err != nil {
+				t.Fatalf("NewLoggerAction() error = %v", err)
+			}
+			got := action.args(violation)
+			if len(got) != len(tt.want) {
+				t.Fatalf("args = %q, want %q", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Fatalf("args = %q, want %q", got, tt.want)
+				}
+			}
+		})
+	}
+
+	if _, err := NewLoggerAction(map[string]interface{}{"level": "urgent"}); err == nil {
+		t.Error("NewLoggerAction() expected error for invalid level")
+	}
+}
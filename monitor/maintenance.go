@@ -0,0 +1,214 @@
+package monitor
+
+import (
+	"fmt"
+	"log"
+	"time"
+)
+
+// maxMaintenanceDuration bounds recurring windows, which are found by searching back
+// for their start
+const maxMaintenanceDuration = 7 * 24 * time.Hour
+
+// maintenanceTimeLayouts are the accepted formats of one-off window times
+var maintenanceTimeLayouts = []string{time.RFC3339, "2006-01-02 15:04", "2006-01-02T15:04"}
+
+// MaintenanceWindow holds notifications of matching violations while it is active.
+// A window is either a one-off time range (start and end or duration) or recurring:
+// it starts whenever a cron expression matches and lasts for duration.
+type MaintenanceWindow struct {
+	Name     string            `yaml:"name"`     // Name shown in logs and the status output
+	Start    string            `yaml:"start"`    // One-off start, "2006-01-02 15:04" or RFC 3339
+	End      string            `yaml:"end"`      // One-off end (exclusive)
+	Cron     string            `yaml:"cron"`     // Recurring start, a 5-field cron expression
+	Duration string            `yaml:"duration"` // Length of recurring windows; alternative to end
+	Timezone string            `yaml:"timezone"` // IANA timezone (default: local time)
+	Metrics  []string          `yaml:"metrics"`  // Metrics the window applies to (default: all)
+	Labels   map[string]string `yaml:"labels"`   // Labels violations must have, as used for routing
+
+	schedule *CronSchedule // Cron parsed when the config is validated
+}
+
+// location returns the window's timezone
+func (w MaintenanceWindow) location() (*time.Location, error) {
+	if w.Timezone == "" {
+		return time.Local, nil
+	}
+	return time.LoadLocation(w.Timezone)
+}
+
+// parseTime parses a one-off window time in the window's timezone
+func (w MaintenanceWindow) parseTime(s string) (time.Time, error) {
+	loc, err := w.location()
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid timezone '%s': %w", w.Timezone, err)
+	}
+	for _, layout := range maintenanceTimeLayouts {
+		if t, err := time.ParseInLocation(layout, s, loc); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid time '%s' (use YYYY-MM-DD HH:MM or RFC 3339)", s)
+}
+
+// duration returns the window's duration, or 0 if it has none
+func (w MaintenanceWindow) duration() (time.Duration, error) {
+	if w.Duration == "" {
+		return 0, nil
+	}
+	d, err := parseDuration(w.Duration)
+	if err != nil {
+		return 0, err
+	}
+	if d <= 0 {
+		return 0, fmt.Errorf("duration must be positive")
+	}
+	return d, nil
+}
+
+// Active reports whether the window is active at the given time
+func (w MaintenanceWindow) Active(now time.Time) (bool, error) {
+	d, err := w.duration()
+	if err != nil {
+		return false, err
+	}
+
+	if w.Cron != "" {
+		cron := w.schedule
+		if cron == nil {
+			if cron, err = ParseCron(w.Cron); err != nil {
+				return false, err
+			}
+		}
+		loc, err := w.location()
+		if err != nil {
+			return false, fmt.Errorf("invalid timezone '%s': %w", w.Timezone, err)
+		}
+		// Active if the expression matched a minute within the last duration
+		start, ok := cron.Prev(now.In(loc), now.Add(-d))
+		return ok && now.Sub(start) < d, nil
+	}
+
+	start, err := w.parseTime(w.Start)
+	if err != nil {
+		return false, err
+	}
+	end := start.Add(d)
+	if w.End != "" {
+		if end, err = w.parseTime(w.End); err != nil {
+			return false, err
+		}
+	}
+	return !now.Before(start) && now.Before(end), nil
+}
+
+// matches reports whether the window applies to a violation
+func (w MaintenanceWindow) matches(v ThresholdViolation) bool {
+	if len(w.Metrics) > 0 && !containsString(w.Metrics, v.Metric) {
+		return false
+	}
+	labels := v.routingLabels()
+	for name, value := range w.Labels {
+		if labels[name] != value {
+			return false
+		}
+	}
+	return true
+}
+
+// maintenanceWindow returns the name of an active maintenance window that applies to a violation
+func (c *Config) maintenanceWindow(v ThresholdViolation, now time.Time) (string, bool) {
+	for _, window := range c.Maintenance {
+		if !window.matches(v) {
+			continue
+		}
+		active, err := window.Active(now)
+		if err != nil {
+			log.Printf("Maintenance window %s: %v", window.Name, err)
+			continue
+		}
+		if active {
+			return window.Name, true
+		}
+	}
+	return "", false
+}
+
+// ActiveMaintenance returns the names of the maintenance windows active at the given time
+func ActiveMaintenance(config *Config, now time.Time) []string {
+	var active []string
+	for _, window := range config.Maintenance {
+		if ok, err := window.Active(now); err == nil && ok {
+			active = append(active, window.Name)
+		}
+	}
+	return active
+}
+
+// validateMaintenance validates the maintenance windows
+func validateMaintenance(windows []MaintenanceWindow) error {
+	seen := make(map[string]bool)
+	for i := range windows {
+		window := &windows[i]
+		if window.Name == "" {
+			return fmt.Errorf("maintenance window %d missing 'name' field", i)
+		}
+		where := fmt.Sprintf("maintenance window '%s'", window.Name)
+		if seen[window.Name] {
+			return fmt.Errorf("duplicate %s", where)
+		}
+		seen[window.Name] = true
+
+		if _, err := window.location(); err != nil {
+			return fmt.Errorf("%s has invalid timezone '%s': %w", where, window.Timezone, err)
+		}
+		d, err := window.duration()
+		if err != nil {
+			return fmt.Errorf("%s: %w", where, err)
+		}
+
+		if window.Cron != "" {
+			if window.Start != "" || window.End != "" {
+				return fmt.Errorf("%s must use either 'cron' or 'start'/'end', not both", where)
+			}
+			if window.schedule, err = ParseCron(window.Cron); err != nil {
+				return fmt.Errorf("%s: %w", where, err)
+			}
+			if d == 0 {
+				return fmt.Errorf("%s with 'cron' missing 'duration' field", where)
+			}
+			if d > maxMaintenanceDuration {
+				return fmt.Errorf("%s duration must not exceed %v", where, maxMaintenanceDuration)
+			}
+		} else {
+			if window.Start == "" {
+				return fmt.Errorf("%s missing 'start' or 'cron' field", where)
+			}
+			start, err := window.parseTime(window.Start)
+			if err != nil {
+				return fmt.Errorf("%s: %w", where, err)
+			}
+			switch {
+			case window.End != "" && d != 0:
+				return fmt.Errorf("%s must use either 'end' or 'duration', not both", where)
+			case window.End != "":
+				end, err := window.parseTime(window.End)
+				if err != nil {
+					return fmt.Errorf("%s: %w", where, err)
+				}
+				if !end.After(start) {
+					return fmt.Errorf("%s must end after it starts", where)
+				}
+			case d == 0:
+				return fmt.Errorf("%s missing 'end' or 'duration' field", where)
+			}
+		}
+
+		for _, metric := range window.Metrics {
+			if metric == "" {
+				return fmt.Errorf("%s metrics must not be empty", where)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,196 @@
+package monitor
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestMaintenanceWindowActive tests one-off and recurring windows
+func TestMaintenanceWindowActive(t *testing.T) {
+	at := func(s string) time.Time {
+		parsed, err := time.ParseInLocation("2006-01-02 15:04", s, time.Local)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return parsed
+	}
+
+	tests := []struct {
+		name   string
+		window MaintenanceWindow
+		now    string
+		want   bool
+	}{
+		{"one-off before", MaintenanceWindow{Start: "2024-06-01 22:00", End: "2024-06-02 02:00"}, "2024-06-01 21:59", false},
+		{"one-off start", MaintenanceWindow{Start: "2024-06-01 22:00", End: "2024-06-02 02:00"}, "2024-06-01 22:00", true},
+		{"one-off past midnight", MaintenanceWindow{Start: "2024-06-01 22:00", End: "2024-06-02 02:00"}, "2024-06-02 01:59", true},
+		{"one-off end", MaintenanceWindow{Start: "2024-06-01 22:00", End: "2024-06-02 02:00"}, "2024-06-02 02:00", false},
+		{"one-off duration", MaintenanceWindow{Start: "2024-06-01 22:00", Duration: "30m"}, "2024-06-01 22:29", true},
+		{"one-off duration ended", MaintenanceWindow{Start: "2024-06-01 22:00", Duration: "30m"}, "2024-06-01 22:30", false},
+		{"cron start", MaintenanceWindow{Cron: "0 3 * * sun", Duration: "2h"}, "2024-06-02 03:00", true},
+		{"cron within", MaintenanceWindow{Cron: "0 3 * * sun", Duration: "2h"}, "2024-06-02 04:59", true},
+		{"cron ended", MaintenanceWindow{Cron: "0 3 * * sun", Duration: "2h"}, "2024-06-02 05:00", false},
+		{"cron other day", MaintenanceWindow{Cron: "0 3 * * sun", Duration: "2h"}, "2024-06-03 03:30", false},
+		{"cron past midnight", MaintenanceWindow{Cron: "30 23 * * *", Duration: "1h"}, "2024-06-03 00:15", true},
+	}
+
+	for _, tt := range tests {
+		got, err := tt.window.Active(at(tt.now))
+		if err != nil {
+			t.Fatalf("%s: Active() error = %v", tt.name, err)
+		}
+		if got != tt.want {
+			t.Errorf("%s: Active(%s) = %v, want %v", tt.name, tt.now, got, tt.want)
+		}
+	}
+}
+
+// TestMaintenanceWindowScope tests scoping windows to metrics and labels
+func TestMaintenanceWindowScope(t *testing.T) {
+	now := time.Now()
+	config := &Config{
+		Maintenance: []MaintenanceWindow{
+			{Name: "db-migration", Start: now.Add(-time.Hour).Format(time.RFC3339), Duration: "2h",
+				Metrics: []string{"cpu", "memory"}, Labels: map[string]string{"service": "db"}},
+			{Name: "var-resize", Start: now.Add(-time.Hour).Format(time.RFC3339), Duration: "2h",
+				Labels: map[string]string{"mountpoint": "/var"}},
+			{Name: "later", Start: now.Add(time.Hour).Format(time.RFC3339), Duration: "2h"},
+		},
+	}
+
+	tests := []struct {
+		violation ThresholdViolation
+		want      string
+	}{
+		{ThresholdViolation{Metric: "cpu", Level: "warning", Labels: map[string]string{"service": "db"}}, "db-migration"},
+		{ThresholdViolation{Metric: "cpu", Level: "warning", Labels: map[string]string{"service": "web"}}, ""},
+		{ThresholdViolation{Metric: "disk", Level: "warning", Mountpoint: "/var"}, "var-resize"},
+		{ThresholdViolation{Metric: "disk", Level: "warning", Mountpoint: "/"}, ""},
+	}
+	for _, tt := range tests {
+		got, _ := config.maintenanceWindow(tt.violation, now)
+		if got != tt.want {
+			t.Errorf("maintenanceWindow(%s %s) = %q, want %q", tt.violation.Metric, tt.violation.Mountpoint, got, tt.want)
+		}
+	}
+
+	if active := ActiveMaintenance(config, now); len(active) != 2 {
+		t.Errorf("ActiveMaintenance() = %v, want db-migration and var-resize", active)
+	}
+}
+
+// TestApplyThrottlingMaintenance tests that notifications are held during a window and
+// reported when it ends
+func TestApplyThrottlingMaintenance(t *testing.T) {
+	now := time.Now()
+	window := MaintenanceWindow{Name: "kernel-upgrade", Start: now.Add(-time.Hour).Format(time.RFC3339), Duration: "2h"}
+	config := &Config{Maintenance: []MaintenanceWindow{window}}
+	sm := &StateManager{
+		StateFile: filepath.Join(t.TempDir(), "state.json"),
+		States:    make(map[string]*ViolationState),
+	}
+	violation := ThresholdViolation{Metric: "cpu", Level: "critical", Value: 99}
+
+	throttled, err := applyThrottling(config, []ThresholdViolation{violation}, sm)
+	if err != nil {
+		t.Fatalf("applyThrottling() error = %v", err)
+	}
+	if len(throttled) != 0 {
+		t.Fatalf("applyThrottling() = %v, want notification held", throttled)
+	}
+	state := sm.States[violation.StateKey()]
+	if state == nil || state.HasAlerted || state.HeldByMaintenance != "kernel-upgrade" {
+		t.Fatalf("state = %+v, want tracked and held by kernel-upgrade", state)
+	}
+
+	// The window ended: the violation is reported once, then throttled as usual
+	window.Start = now.Add(-3 * time.Hour).Format(time.RFC3339)
+	config.Maintenance = []MaintenanceWindow{window}
+	if throttled, _ := applyThrottling(config, []ThresholdViolation{violation}, sm); len(throttled) != 1 {
+		t.Fatalf("applyThrottling() after window = %v, want the held violation", throttled)
+	}
+	if state.HeldByMaintenance != "" || !state.HasAlerted {
+		t.Errorf("state after window = %+v, want reported", state)
+	}
+	if throttled, _ := applyThrottling(config, []ThresholdViolation{violation}, sm); len(throttled) != 0 {
+		t.Errorf("applyThrottling() = %v, want no repeat", throttled)
+	}
+}
+
+// TestMaintenanceConfigValidation tests loading and validating maintenance windows
+func TestMaintenanceConfigValidation(t *testing.T) {
+	tests := []struct {
+		name    string
+		window  string
+		wantErr string
+	}{
+		{"valid one-off", "  - name: upgrade\n    start: 2024-06-01 22:00\n    end: 2024-06-02 02:00\n    metrics: [cpu]\n", ""},
+		{"valid cron", "  - name: backup\n    cron: \"0 3 * * sun\"\n    duration: 2h\n    timezone: Europe/Berlin\n    labels: {team: storage}\n", ""},
+		{"missing name", "  - start: 2024-06-01 22:00\n    duration: 1h\n", "missing 'name'"},
+		{"missing start", "  - name: x\n    duration: 1h\n", "missing 'start' or 'cron'"},
+		{"missing end", "  - name: x\n    start: 2024-06-01 22:00\n", "missing 'end' or 'duration'"},
+		{"end and duration", "  - name: x\n    start: 2024-06-01 22:00\n    end: 2024-06-01 23:00\n    duration: 1h\n", "not both"},
+		{"end before start", "  - name: x\n    start: 2024-06-01 22:00\n    end: 2024-06-01 21:00\n", "must end after"},
+		{"invalid time", "  - name: x\n    start: tonight\n    duration: 1h\n", "invalid time"},
+		{"cron without duration", "  - name: x\n    cron: \"0 3 * * *\"\n", "missing 'duration'"},
+		{"cron and start", "  - name: x\n    cron: \"0 3 * * *\"\n    start: 2024-06-01 22:00\n    duration: 1h\n", "not both"},
+		{"invalid cron", "  - name: x\n    cron: \"0 25 * * *\"\n    duration: 1h\n", "out of range"},
+		{"unknown field", "  - name: x\n    start: 2024-06-01 22:00\n    duration: 1h\n    metric: cpu\n", "unknown field 'metric'"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "config.yaml")
+			if err := os.WriteFile(path, []byte("maintenance:\n"+tt.window), 0644); err != nil {
+				t.Fatal(err)
+			}
+			config, err := LoadConfig(path)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("LoadConfig() error = %v, want error containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("LoadConfig() error = %v", err)
+			}
+			if len(config.Maintenance) != 1 {
+				t.Errorf("maintenance = %+v", config.Maintenance)
+			}
+		})
+	}
+}
+
+// TestApplyThrottlingMaintenanceAlerted tests that violations alerted before a window are
+// not reported again when it ends
+func TestApplyThrottlingMaintenanceAlerted(t *testing.T) {
+	now := time.Now()
+	config := &Config{}
+	sm := &StateManager{
+		StateFile: filepath.Join(t.TempDir(), "state.json"),
+		States:    make(map[string]*ViolationState),
+	}
+	violation := ThresholdViolation{Metric: "cpu", Level: "critical", Value: 99}
+
+	if throttled, _ := applyThrottling(config, []ThresholdViolation{violation}, sm); len(throttled) != 1 {
+		t.Fatalf("applyThrottling() = %v, want the violation", throttled)
+	}
+
+	window := MaintenanceWindow{Name: "kernel-upgrade", Start: now.Add(-time.Hour).Format(time.RFC3339), Duration: "2h"}
+	config.Maintenance = []MaintenanceWindow{window}
+	if throttled, _ := applyThrottling(config, []ThresholdViolation{violation}, sm); len(throttled) != 0 {
+		t.Fatalf("applyThrottling() during window = %v, want none", throttled)
+	}
+	if state := sm.States[violation.StateKey()]; state.HeldByMaintenance != "" {
+		t.Errorf("state = %+v, want alerted violation not held", state)
+	}
+
+	window.Start = now.Add(-3 * time.Hour).Format(time.RFC3339)
+	config.Maintenance = []MaintenanceWindow{window}
+	if throttled, _ := applyThrottling(config, []ThresholdViolation{violation}, sm); len(throttled) != 0 {
+		t.Errorf("applyThrottling() after window = %v, want no repeat", throttled)
+	}
+}
@@ -0,0 +1,590 @@
+package monitor
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// Default MQTT topics; {host}, {metric} and {level} are replaced when publishing
+const (
+	DefaultMQTTAlertTopic   = "tfc-monitor/{host}/alerts/{metric}"
+	DefaultMQTTMetricsTopic = "tfc-monitor/{host}/metrics"
+	DefaultMQTTStatusTopic  = "tfc-monitor/{host}/status"
+)
+
+// MQTT control packet types (MQTT 3.1.1)
+const (
+	mqttConnect    = 1
+	mqttConnAck    = 2
+	mqttPublish    = 3
+	mqttPubAck     = 4
+	mqttPubRec     = 5
+	mqttPubRel     = 6
+	mqttPubComp    = 7
+	mqttDisconnect = 14
+)
+
+// mqttConnAckErrors describes the CONNACK return codes that refuse a connection
+var mqttConnAckErrors = map[byte]string{
+	1: "unacceptable protocol version",
+	2: "client identifier rejected",
+	3: "server unavailable",
+	4: "bad user name or password",
+	5: "not authorized",
+}
+
+// MQTTConfig configures publishing metrics and the check status to an MQTT broker
+// after each collection cycle
+type MQTTConfig struct {
+	Enabled            bool   `yaml:"enabled"`
+	Broker             string `yaml:"broker"`               // tcp://host:1883, or ssl://host:8883 for TLS
+	ClientID           string `yaml:"client_id"`            // default: tfc-monitor-<host>-<random>
+	Username           string `yaml:"username"`             // Environment variables are expanded
+	Password           string `yaml:"password"`             // Environment variables are expanded
+	CAFile             string `yaml:"ca_file"`              // CA certificates to verify the broker with
+	InsecureSkipVerify bool   `yaml:"insecure_skip_verify"` // Skip broker certificate verification
+	QoS                int    `yaml:"qos"`                  // 0 (default), 1 or 2
+	Retain             bool   `yaml:"retain"`               // Retain metrics messages; status messages are always retained
+	Timeout            string `yaml:"timeout"`              // default: "10s"
+	MetricsTopic       string `yaml:"metrics_topic"`        // default: DefaultMQTTMetricsTopic
+	StatusTopic        string `yaml:"status_topic"`         // default: DefaultMQTTStatusTopic
+}
+
+// MQTTMetrics is published to the metrics topic
+type MQTTMetrics struct {
+	Host  string       `json:"host"`
+	Time  time.Time    `json:"time"`
+	Stats *SystemStats `json:"stats"`
+}
+
+// MQTTStatus is published, retained, to the status topic
+type MQTTStatus struct {
+	Host   string    `json:"host"`
+	Time   time.Time `json:"time"`
+	Status string    `json:"status"` // "OK", "WARN" or "CRITICAL"
+	Info   []string  `json:"info"`
+}
+
+// broker returns the connection settings of the publisher
+func (c MQTTConfig) broker() (mqttBroker, error) {
+	b := mqttBroker{
+		URL:                c.Broker,
+		ClientID:           c.ClientID,
+		Username:           os.ExpandEnv(c.Username),
+		Password:           os.ExpandEnv(c.Password),
+		CAFile:             c.CAFile,
+		InsecureSkipVerify: c.InsecureSkipVerify,
+		Timeout:            10 * time.Second,
+	}
+	if c.Timeout != "" {
+		timeout, err := parseDuration(c.Timeout)
+		if err != nil {
+			return b, fmt.Errorf("invalid mqtt timeout: %w", err)
+		}
+		b.Timeout = timeout
+	}
+	return b, b.validate()
+}
+
+// validate validates the publisher settings
+func (c MQTTConfig) validate() error {
+	if !c.Enabled {
+		return nil
+	}
+	if _, err := c.broker(); err != nil {
+		return fmt.Errorf("mqtt config: %w", err)
+	}
+	if c.QoS < 0 || c.QoS > 2 {
+		return fmt.Errorf("mqtt config 'qos' must be 0, 1 or 2")
+	}
+	for _, topic := range []string{c.MetricsTopic, c.StatusTopic} {
+		if err := validateMQTTTopic(topic); err != nil {
+			return fmt.Errorf("mqtt config: %w", err)
+		}
+	}
+	return nil
+}
+
+// PublishMQTT publishes the stats of a collection cycle and the resulting status
+func PublishMQTT(config MQTTConfig, stats *SystemStats, status string, info []string) error {
+	if !config.Enabled {
+		return nil
+	}
+	broker, err := config.broker()
+	if err != nil {
+		return err
+	}
+
+	hostname, _ := os.Hostname()
+	now := time.Now()
+	metrics, err := json.Marshal(MQTTMetrics{Host: hostname, Time: now, Stats: stats})
+	if err != nil {
+		return fmt.Errorf("failed to marshal metrics: %w", err)
+	}
+	if info == nil {
+		info = []string{}
+	}
+	statusPayload, err := json.Marshal(MQTTStatus{Host: hostname, Time: now, Status: status, Info: info})
+	if err != nil {
+		return fmt.Errorf("failed to marshal status: %w", err)
+	}
+
+	metricsTopic := config.MetricsTopic
+	if metricsTopic == "" {
+		metricsTopic = DefaultMQTTMetricsTopic
+	}
+	statusTopic := config.StatusTopic
+	if statusTopic == "" {
+		statusTopic = DefaultMQTTStatusTopic
+	}
+
+	qos := byte(config.QoS)
+	return broker.publish(
+		mqttMessage{Topic: mqttTopic(metricsTopic, hostname, nil), Payload: metrics, QoS: qos, Retain: config.Retain},
+		mqttMessage{Topic: mqttTopic(statusTopic, hostname, nil), Payload: statusPayload, QoS: qos, Retain: true},
+	)
+}
+
+// MQTTAction publishes alerts to an MQTT topic
+type MQTTAction struct {
+	Broker mqttBroker
+	Topic  string // may contain {host}, {metric} and {level}
+	QoS    byte
+	Retain bool // the broker keeps the last message, so the topic shows the current state
+}
+
+// MQTTAlert is the payload of alert messages
+type MQTTAlert struct {
+	Status     string            `json:"status"` // "firing" or "resolved"
+	Host       string            `json:"host"`
+	Time       time.Time         `json:"time"`
+	Metric     string            `json:"metric"`
+	Level      string            `json:"level"`
+	Message    string            `json:"message"`
+	Value      float64           `json:"value"`
+	Threshold  float64           `json:"threshold,omitempty"`
+	Mountpoint string            `json:"mountpoint,omitempty"`
+	Device     string            `json:"device,omitempty"`
+	Labels     map[string]string `json:"labels,omitempty"`
+}
+
+// NewMQTTAction creates a new MQTT alert action
+func NewMQTTAction(config map[string]interface{}) (*MQTTAction, error) {
+	ma := &MQTTAction{
+		Topic:  DefaultMQTTAlertTopic,
+		Broker: mqttBroker{Timeout: 10 * time.Second},
+	}
+
+	broker, ok := config["broker"].(string)
+	if !ok || broker == "" {
+		return nil, fmt.Errorf("mqtt action requires 'broker' field")
+	}
+	ma.Broker.URL = broker
+
+	if topic, ok := config["topic"].(string); ok {
+		if err := validateMQTTTopic(topic); err != nil {
+			return nil, fmt.Errorf("mqtt action %w", err)
+		}
+		ma.Topic = topic
+	}
+	if qos, ok := actionNumber(config, "qos"); ok {
+		if qos != 0 && qos != 1 && qos != 2 {
+			return nil, fmt.Errorf("mqtt action 'qos' must be 0, 1 or 2")
+		}
+		ma.QoS = byte(qos)
+	}
+	if retain, ok := config["retain"].(bool); ok {
+		ma.Retain = retain
+	}
+	// A warning retained on its own topic would never be replaced by the critical
+	// alert or the resolved update, which are published to other topics
+	if ma.Retain && strings.Contains(ma.Topic, "{level}") {
+		return nil, fmt.Errorf("mqtt action 'topic' must not contain {level} with 'retain'")
+	}
+
+	if clientID, ok := config["client_id"].(string); ok {
+		ma.Broker.ClientID = clientID
+	}
+	if username, ok := config["username"].(string); ok {
+		ma.Broker.Username = os.ExpandEnv(username)
+	}
+	if password, ok := config["password"].(string); ok {
+		ma.Broker.Password = os.ExpandEnv(password)
+	}
+	if caFile, ok := config["ca_file"].(string); ok {
+		ma.Broker.CAFile = caFile
+	}
+	if insecure, ok := config["insecure_skip_verify"].(bool); ok {
+		ma.Broker.InsecureSkipVerify = insecure
+	}
+	if timeout, ok := actionNumber(config, "timeout"); ok {
+		ma.Broker.Timeout = time.Duration(timeout) * time.Second
+	}
+
+	if err := ma.Broker.validate(); err != nil {
+		return nil, fmt.Errorf("mqtt action %w", err)
+	}
+	return ma, nil
+}
+
+// setContext makes publishing stop when ctx is cancelled
+func (ma *MQTTAction) setContext(ctx context.Context) {
+	ma.Broker.ctx = ctx
+}
+
+// Execute publishes a violation
+func (ma *MQTTAction) Execute(violation ThresholdViolation) error {
+	topic, err := ma.publish(violation, "firing")
+	if err != nil {
+		return err
+	}
+	log.Printf("MQTT alert published to %s: %s/%s", topic, violation.Metric, violation.Level)
+	return nil
+}
+
+// Resolve publishes that a violation resolved; with retain, it replaces the retained alert
+func (ma *MQTTAction) Resolve(violation ThresholdViolation) error {
+	topic, err := ma.publish(violation, "resolved")
+	if err != nil {
+		return err
+	}
+	log.Printf("MQTT resolved update published to %s: %s/%s", topic, violation.Metric, violation.Level)
+	return nil
+}
+
+// publish publishes an alert message and returns its topic
+func (ma *MQTTAction) publish(violation ThresholdViolation, status string) (string, error) {
+	hostname, _ := os.Hostname()
+	payload, err := json.Marshal(MQTTAlert{
+		Status:     status,
+		Host:       hostname,
+		Time:       time.Now(),
+		Metric:     violation.Metric,
+		Level:      violation.Level,
+		Message:    violation.Message,
+		Value:      violation.Value,
+		Threshold:  violation.Threshold,
+		Mountpoint: violation.Mountpoint,
+		Device:     violation.Device,
+		Labels:     violation.Labels,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal mqtt payload: %w", err)
+	}
+
+	topic := mqttTopic(ma.Topic, hostname, &violation)
+	if err := ma.Broker.publish(mqttMessage{Topic: topic, Payload: payload, QoS: ma.QoS, Retain: ma.Retain}); err != nil {
+		return "", err
+	}
+	return topic, nil
+}
+
+// mqttTopic replaces the placeholders of a topic; {metric} and {level} need a violation
+func mqttTopic(topic string, hostname string, violation *ThresholdViolation) string {
+	replacements := []string{"{host}", hostname}
+	if violation != nil {
+		replacements = append(replacements, "{metric}", violation.Metric, "{level}", violation.Level)
+	}
+	return strings.NewReplacer(replacements...).Replace(topic)
+}
+
+// validateMQTTTopic checks a topic to publish to; "" selects the default
+func validateMQTTTopic(topic string) error {
+	if strings.ContainsAny(topic, "+#") {
+		return fmt.Errorf("topic '%s' must not contain wildcards", topic)
+	}
+	return nil
+}
+
+// mqttBroker holds the settings to connect to an MQTT broker
+type mqttBroker struct {
+	URL                string
+	ClientID           string
+	Username           string
+	Password           string
+	CAFile             string
+	InsecureSkipVerify bool
+	Timeout            time.Duration
+
+	ctx context.Context // aborts the session when cancelled
+}
+
+// mqttMessage is a message to publish
+type mqttMessage struct {
+	Topic   string
+	Payload []byte
+	QoS     byte
+	Retain  bool
+}
+
+// address returns the broker's host:port and whether to use TLS
+func (b mqttBroker) address() (string, bool, error) {
+	u, err := url.Parse(b.URL)
+	if err != nil || u.Host == "" {
+		return "", false, fmt.Errorf("invalid broker '%s', expected e.g. tcp://host:1883", b.URL)
+	}
+	var useTLS bool
+	port := "1883"
+	switch u.Scheme {
+	case "tcp", "mqtt":
+	case "ssl", "tls", "mqtts":
+		useTLS = true
+		port = "8883"
+	default:
+		return "", false, fmt.Errorf("broker scheme must be tcp, mqtt, ssl, tls or mqtts, got '%s'", u.Scheme)
+	}
+	if u.Port() != "" {
+		port = u.Port()
+	}
+	return net.JoinHostPort(u.Hostname(), port), useTLS, nil
+}
+
+// validate checks the broker settings
+func (b mqttBroker) validate() error {
+	if _, _, err := b.address(); err != nil {
+		return err
+	}
+	if b.Password != "" && b.Username == "" {
+		return fmt.Errorf("'password' requires 'username'")
+	}
+	return nil
+}
+
+// publish connects to the broker, publishes the messages and disconnects
+func (b mqttBroker) publish(messages ...mqttMessage) error {
+	client, err := b.connect()
+	if err != nil {
+		return err
+	}
+	defer client.close()
+
+	for _, msg := range messages {
+		if err := client.publish(msg); err != nil {
+			return fmt.Errorf("failed to publish to %s: %w", msg.Topic, err)
+		}
+	}
+	if err := client.writePacket(mqttDisconnect<<4, nil); err != nil {
+		return fmt.Errorf("failed to disconnect from %s: %w", b.URL, err)
+	}
+	return nil
+}
+
+// connect opens a session with the broker
+func (b mqttBroker) connect() (*mqttClient, error) {
+	addr, useTLS, err := b.address()
+	if err != nil {
+		return nil, err
+	}
+
+	dialer := &net.Dialer{Timeout: b.Timeout}
+	ctx := actionContext(b.ctx)
+	var conn net.Conn
+	if useTLS {
+		tlsConfig := &tls.Config{InsecureSkipVerify: b.InsecureSkipVerify}
+		if b.CAFile != "" {
+			pem, err := os.ReadFile(b.CAFile)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read mqtt CA file: %w", err)
+			}
+			tlsConfig.RootCAs = x509.NewCertPool()
+			if !tlsConfig.RootCAs.AppendCertsFromPEM(pem) {
+				return nil, fmt.Errorf("no certificates found in mqtt CA file %s", b.CAFile)
+			}
+		}
+		conn, err = (&tls.Dialer{NetDialer: dialer, Config: tlsConfig}).DialContext(ctx, "tcp", addr)
+	} else {
+		conn, err = dialer.DialContext(ctx, "tcp", addr)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to mqtt broker %s: %w", addr, err)
+	}
+	conn.SetDeadline(time.Now().Add(b.Timeout))
+
+	client := &mqttClient{conn: conn, r: bufio.NewReader(conn), stop: context.AfterFunc(ctx, func() { conn.Close() })}
+	if err := client.handshake(b); err != nil {
+		client.close()
+		return nil, fmt.Errorf("mqtt broker %s: %w", addr, err)
+	}
+	return client, nil
+}
+
+// mqttClient is a minimal MQTT 3.1.1 client that publishes with QoS 0, 1 or 2
+type mqttClient struct {
+	conn     net.Conn
+	r        *bufio.Reader
+	stop     func() bool // stops closing the connection on cancellation
+	packetID uint16
+}
+
+// close closes the connection
+func (c *mqttClient) close() {
+	c.stop()
+	c.conn.Close()
+}
+
+// handshake sends CONNECT and waits for the broker's CONNACK
+func (c *mqttClient) handshake(b mqttBroker) error {
+	clientID := b.ClientID
+	if clientID == "" {
+		// A random suffix keeps concurrent actions from taking over each other's session
+		hostname, _ := os.Hostname()
+		suffix := make([]byte, 4)
+		rand.Read(suffix)
+		clientID = "tfc-monitor-" + hostname + "-" + hex.EncodeToString(suffix)
+	}
+
+	flags := byte(0x02) // clean session
+	var payload []byte
+	payload = appendMQTTString(payload, clientID)
+	if b.Username != "" {
+		flags |= 0x80
+		payload = appendMQTTString(payload, b.Username)
+	}
+	if b.Password != "" {
+		flags |= 0x40
+		payload = appendMQTTString(payload, b.Password)
+	}
+
+	body := appendMQTTString(nil, "MQTT")
+	body = append(body, 4, flags, 0, 60) // protocol level 4, keep alive 60s
+	body = append(body, payload...)
+	if err := c.writePacket(mqttConnect<<4, body); err != nil {
+		return fmt.Errorf("failed to send CONNECT: %w", err)
+	}
+
+	packetType, resp, err := c.readPacket()
+	if err != nil {
+		return fmt.Errorf("failed to read CONNACK: %w", err)
+	}
+	if packetType != mqttConnAck || len(resp) != 2 {
+		return fmt.Errorf("expected CONNACK, got packet type %d", packetType)
+	}
+	if resp[1] != 0 {
+		if reason, ok := mqttConnAckErrors[resp[1]]; ok {
+			return fmt.Errorf("connection refused: %s", reason)
+		}
+		return fmt.Errorf("connection refused with code %d", resp[1])
+	}
+	return nil
+}
+
+// publish publishes a message and completes the QoS 1 or 2 handshake
+func (c *mqttClient) publish(msg mqttMessage) error {
+	header := byte(mqttPublish<<4) | msg.QoS<<1
+	if msg.Retain {
+		header |= 0x01
+	}
+	body := appendMQTTString(nil, msg.Topic)
+	if msg.QoS > 0 {
+		c.packetID++
+		body = binary.BigEndian.AppendUint16(body, c.packetID)
+	}
+	body = append(body, msg.Payload...)
+	if err := c.writePacket(header, body); err != nil {
+		return err
+	}
+
+	switch msg.QoS {
+	case 1:
+		return c.expect(mqttPubAck)
+	case 2:
+		if err := c.expect(mqttPubRec); err != nil {
+			return err
+		}
+		if err := c.writePacket(mqttPubRel<<4|0x02, binary.BigEndian.AppendUint16(nil, c.packetID)); err != nil {
+			return err
+		}
+		return c.expect(mqttPubComp)
+	}
+	return nil
+}
+
+// expect reads the acknowledgement of the current packet
+func (c *mqttClient) expect(packetType byte) error {
+	got, body, err := c.readPacket()
+	if err != nil {
+		return err
+	}
+	if got != packetType || len(body) != 2 || binary.BigEndian.Uint16(body) != c.packetID {
+		return fmt.Errorf("unexpected packet type %d, waiting for %d", got, packetType)
+	}
+	return nil
+}
+
+// writePacket writes a control packet
+func (c *mqttClient) writePacket(header byte, body []byte) error {
+	packet := append([]byte{header}, mqttRemainingLength(len(body))...)
+	_, err := c.conn.Write(append(packet, body...))
+	return err
+}
+
+// readPacket reads a control packet and returns its type and body
+func (c *mqttClient) readPacket() (byte, []byte, error) {
+	header, body, err := readMQTTPacket(c.r)
+	return header >> 4, body, err
+}
+
+// readMQTTPacket reads a control packet and returns its fixed header byte and body
+func readMQTTPacket(r *bufio.Reader) (byte, []byte, error) {
+	header, err := r.ReadByte()
+	if err != nil {
+		return 0, nil, err
+	}
+	length, multiplier := 0, 1
+	for i := 0; ; i++ {
+		if i == 4 {
+			return 0, nil, errors.New("malformed remaining length")
+		}
+		b, err := r.ReadByte()
+		if err != nil {
+			return 0, nil, err
+		}
+		length += int(b&0x7f) * multiplier
+		multiplier *= 128
+		if b&0x80 == 0 {
+			break
+		}
+	}
+	body := make([]byte, length)
+	if _, err := io.ReadFull(r, body); err != nil {
+		return 0, nil, err
+	}
+	return header, body, nil
+}
+
+// mqttRemainingLength encodes the remaining length of a packet
+func mqttRemainingLength(n int) []byte {
+	var encoded []byte
+	for {
+		b := byte(n % 128)
+		n /= 128
+		if n > 0 {
+			b |= 0x80
+		}
+		encoded = append(encoded, b)
+		if n == 0 {
+			return encoded
+		}
+	}
+}
+
+// appendMQTTString appends a length-prefixed UTF-8 string
+func appendMQTTString(b []byte, s string) []byte {
+	b = binary.BigEndian.AppendUint16(b, uint16(len(s)))
+	return append(b, s...)
+}
@@ -0,0 +1,281 @@
+package monitor
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/json"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// brokerMessage is a message received by the test broker
+type brokerMessage struct {
+	Topic   string
+	Payload map[string]interface{}
+	QoS     byte
+	Retain  bool
+}
+
+// testBroker is an embedded MQTT broker stand-in that accepts sessions, acknowledges
+// publishes and records them
+type testBroker struct {
+	addr     string
+	username string // required username, if set
+	password string
+
+	mu       sync.Mutex
+	messages []brokerMessage
+	clients  []string
+}
+
+// newTestBroker starts a test broker on a local port
+func newTestBroker(t *testing.T) *testBroker {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	b := &testBroker{addr: "tcp://" + ln.Addr().String()}
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go b.serve(t, conn)
+		}
+	}()
+	return b
+}
+
+// serve handles one client session
+func (b *testBroker) serve(t *testing.T, conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	write := func(header byte, body []byte) {
+		conn.Write(append(append([]byte{header}, mqttRemainingLength(len(body))...), body...))
+	}
+	readString := func(body []byte) (string, []byte) {
+		n := binary.BigEndian.Uint16(body)
+		return string(body[2 : 2+n]), body[2+n:]
+	}
+
+	for {
+		header, body, err := readMQTTPacket(r)
+		if err != nil {
+			return
+		}
+		switch header >> 4 {
+		case mqttConnect:
+			protocol, rest := readString(body)
+			flags := rest[1]
+			clientID, rest := readString(rest[4:])
+			var username, password string
+			if flags&0x80 != 0 {
+				username, rest = readString(rest)
+			}
+			if flags&0x40 != 0 {
+				password, _ = readString(rest)
+			}
+			b.mu.Lock()
+			b.clients = append(b.clients, clientID)
+			b.mu.Unlock()
+			code := byte(0)
+			if protocol != "MQTT" {
+				code = 1
+			} else if b.username != "" && (username != b.username || password != b.password) {
+				code = 4
+			}
+			write(mqttConnAck<<4, []byte{0, code})
+		case mqttPublish:
+			qos := header >> 1 & 0x03
+			topic, rest := readString(body)
+			var id []byte
+			if qos > 0 {
+				id, rest = rest[:2], rest[2:]
+			}
+			msg := brokerMessage{Topic: topic, QoS: qos, Retain: header&0x01 != 0}
+			if err := json.Unmarshal(rest, &msg.Payload); err != nil {
+				t.Errorf("invalid JSON payload: %v", err)
+			}
+			b.mu.Lock()
+			b.messages = append(b.messages, msg)
+			b.mu.Unlock()
+			switch qos {
+			case 1:
+				write(mqttPubAck<<4, id)
+			case 2:
+				write(mqttPubRec<<4, id)
+			}
+		case mqttPubRel:
+			write(mqttPubComp<<4, body)
+		case mqttDisconnect:
+			return
+		}
+	}
+}
+
+// received returns the messages received so far
+func (b *testBroker) received() []brokerMessage {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return append([]brokerMessage{}, b.messages...)
+}
+
+// TestMQTTAction tests publishing an alert and its resolution with QoS 1 and retain
+func TestMQTTAction(t *testing.T) {
+	broker := newTestBroker(t)
+	broker.username, broker.password = "monitor", "secret"
+	t.Setenv("MQTT_PASSWORD", "secret")
+
+	action, err := CreateAction(map[string]interface{}{
+		"type":     "mqtt",
+		"broker":   broker.addr,
+		"topic":    "edge/{host}/{metric}",
+		"qos":      1,
+		"retain":   true,
+		"username": "monitor",
+		"password": "${MQTT_PASSWORD}",
+	})
+	if err != nil {
+		t.Fatalf("CreateAction() error = %v", err)
+	}
+	violation := chatTestViolation
+	violation.Threshold = 90
+	if err := action.Execute(violation); err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if err := action.(ResolvingAlertAction).Resolve(violation); err != nil {
+		t.Fatalf("Resolve() error = %v", err)
+	}
+
+	messages := broker.received()
+	if len(messages) != 2 {
+		t.Fatalf("got %d messages, want 2", len(messages))
+	}
+	alert, resolved := messages[0], messages[1]
+	if !strings.HasPrefix(alert.Topic, "edge/") || !strings.HasSuffix(alert.Topic, "/disk") {
+		t.Errorf("topic = %s", alert.Topic)
+	}
+	if alert.QoS != 1 || !alert.Retain {
+		t.Errorf("QoS = %d, retain = %v, want 1, true", alert.QoS, alert.Retain)
+	}
+	if alert.Payload["status"] != "firing" || alert.Payload["threshold"] != 90.0 || alert.Payload["mountpoint"] != "/" {
+		t.Errorf("payload = %v", alert.Payload)
+	}
+	if resolved.Topic != alert.Topic || resolved.Payload["status"] != "resolved" {
+		t.Errorf("resolved message = %+v", resolved)
+	}
+	if broker.clients[0] == broker.clients[1] || !strings.HasPrefix(broker.clients[0], "tfc-monitor-") {
+		t.Errorf("client IDs = %v", broker.clients)
+	}
+}
+
+// TestMQTTActionRefused tests that a refused connection is reported
+func TestMQTTActionRefused(t *testing.T) {
+	broker := newTestBroker(t)
+	broker.username, broker.password = "monitor", "secret"
+
+	action, err := NewMQTTAction(map[string]interface{}{"broker": broker.addr, "username": "monitor", "password": "wrong"})
+	if err != nil {
+		t.Fatalf("NewMQTTAction() error = %v", err)
+	}
+	if err := action.Execute(chatTestViolation); err == nil || !strings.Contains(err.Error(), "bad user name or password") {
+		t.Errorf("Execute() error = %v, want bad user name or password", err)
+	}
+}
+
+// TestPublishMQTT tests publishing metrics and the retained status with QoS 2
+func TestPublishMQTT(t *testing.T) {
+	broker := newTestBroker(t)
+
+	config := MQTTConfig{
+		Enabled:      true,
+		Broker:       broker.addr,
+		ClientID:     "edge-01",
+		QoS:          2,
+		MetricsTopic: "fleet/{host}/metrics",
+	}
+	stats := &SystemStats{LoadInfo: LoadInfo{Load1: 0.5}}
+	if err := PublishMQTT(config, stats, "WARN", []string{"cpu: cpu usage is 85%"}); err != nil {
+		t.Fatalf("PublishMQTT() error = %v", err)
+	}
+
+	messages := broker.received()
+	if len(messages) != 2 {
+		t.Fatalf("got %d messages, want 2", len(messages))
+	}
+	metrics, status := messages[0], messages[1]
+	if !strings.HasPrefix(metrics.Topic, "fleet/") || metrics.Retain || metrics.QoS != 2 {
+		t.Errorf("metrics message = %s qos %d retain %v", metrics.Topic, metrics.QoS, metrics.Retain)
+	}
+	if jsonPath(metrics.Payload, "stats", "load_info", "load1") != 0.5 {
+		t.Errorf("metrics payload = %v", metrics.Payload)
+	}
+	if !strings.HasSuffix(status.Topic, "/status") || !status.Retain {
+		t.Errorf("status message = %s retain %v", status.Topic, status.Retain)
+	}
+	if status.Payload["status"] != "WARN" || jsonPath(status.Payload, "info", 0) != "cpu: cpu usage is 85%" {
+		t.Errorf("status payload = %v", status.Payload)
+	}
+	if broker.clients[0] != "edge-01" {
+		t.Errorf("client ID = %s", broker.clients[0])
+	}
+
+	// Disabled publishers do nothing
+	if err := PublishMQTT(MQTTConfig{Broker: "tcp://127.0.0.1:1"}, stats, "OK", nil); err != nil {
+		t.Errorf("PublishMQTT() disabled error = %v", err)
+	}
+}
+
+// TestMQTTConfigValidation tests broker, QoS and topic validation
+func TestMQTTConfigValidation(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  MQTTConfig
+		wantErr string
+	}{
+		{"valid", MQTTConfig{Enabled: true, Broker: "ssl://broker:8883", QoS: 1}, ""},
+		{"missing broker", MQTTConfig{Enabled: true}, "invalid broker"},
+		{"bad scheme", MQTTConfig{Enabled: true, Broker: "http://broker"}, "scheme"},
+		{"bad qos", MQTTConfig{Enabled: true, Broker: "tcp://broker", QoS: 3}, "qos"},
+		{"wildcard topic", MQTTConfig{Enabled: true, Broker: "tcp://broker", StatusTopic: "a/#"}, "wildcards"},
+		{"password without username", MQTTConfig{Enabled: true, Broker: "tcp://broker", Password: "x"}, "requires 'username'"},
+		{"disabled", MQTTConfig{QoS: 7}, ""},
+	}
+	for _, tt := range tests {
+		err := tt.config.validate()
+		if tt.wantErr == "" {
+			if err != nil {
+				t.Errorf("%s: validate() error = %v", tt.name, err)
+			}
+		} else if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("%s: validate() error = %v, want error containing %q", tt.name, err, tt.wantErr)
+		}
+	}
+
+	if _, err := CreateAction(map[string]interface{}{"type": "mqtt", "broker": "tcp://b", "qos": 5}); err == nil {
+		t.Error("CreateAction() accepted qos 5")
+	}
+	if _, err := CreateAction(map[string]interface{}{"type": "mqtt"}); err == nil || !strings.Contains(err.Error(), "'broker'") {
+		t.Errorf("CreateAction() error = %v, want missing broker", err)
+	}
+	if _, err := CreateAction(map[string]interface{}{"type": "mqtt", "broker": "tcp://b", "topic": "alerts/{metric}/{level}", "retain": true}); err == nil || !strings.Contains(err.Error(), "{level}") {
+		t.Errorf("CreateAction() error = %v, want {level} rejected with retain", err)
+	}
+}
+
+// TestMQTTRemainingLength tests the variable length encoding
+func TestMQTTRemainingLength(t *testing.T) {
+	for _, n := range []int{0, 127, 128, 16383, 16384, 2097152} {
+		encoded := mqttRemainingLength(n)
+		_, body, err := readMQTTPacket(bufio.NewReader(strings.NewReader(string(append(append([]byte{0x30}, encoded...), make([]byte, n)...)))))
+		if err != nil || len(body) != n {
+			t.Errorf("length %d: decoded %d, %v", n, len(body), err)
+		}
+	}
+}
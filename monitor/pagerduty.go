@@ -0,0 +1,125 @@
+package monitor
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"time"
+)
+
+// DefaultPagerDutyURL is the PagerDuty Events API v2 endpoint
+const DefaultPagerDutyURL = "https://events.pagerduty.com/v2/enqueue"
+
+// pagerDutySeverities maps violation levels to PagerDuty severities
+var pagerDutySeverities = map[string]string{
+	"critical": "critical",
+	"warning":  "warning",
+}
+
+// PagerDutyAction sends trigger and resolve events to the PagerDuty Events API v2
+type PagerDutyAction struct {
+	jsonWebhook
+	RoutingKey string
+	Source     string
+}
+
+// NewPagerDutyAction creates a new PagerDuty alert action
+func NewPagerDutyAction(config map[string]interface{}) (*PagerDutyAction, error) {
+	config = withDefaultURL(config, DefaultPagerDutyURL)
+
+	jw, err := newJSONWebhook("pagerduty", config)
+	if err != nil {
+		return nil, err
+	}
+	pa := &PagerDutyAction{jsonWebhook: jw}
+
+	routingKey, ok := config["routing_key"].(string)
+	if !ok || routingKey == "" {
+		return nil, fmt.Errorf("pagerduty action requires 'routing_key' field")
+	}
+	pa.RoutingKey = os.ExpandEnv(routingKey)
+
+	if source, ok := config["source"].(string); ok {
+		pa.Source = source
+	} else {
+		pa.Source, _ = os.Hostname()
+	}
+
+	return pa, nil
+}
+
+// DedupKey returns the stable PagerDuty dedup key of a violation. The level is not
+// part of the key, so a warning that escalates to critical updates the same incident.
+func (pa *PagerDutyAction) DedupKey(violation ThresholdViolation) string {
+	key := fmt.Sprintf("tfc-system-monitor/%s/%s", pa.Source, violation.Metric)
+	if violation.Mountpoint != "" {
+		key += "/" + violation.Mountpoint
+	}
+	return key
+}
+
+// Execute sends a trigger event for a violation
+func (pa *PagerDutyAction) Execute(violation ThresholdViolation) error {
+	severity, ok := pagerDutySeverities[violation.Level]
+	if !ok {
+		severity = "info"
+	}
+
+	summary := fmt.Sprintf("%s: %s", pa.Source, violation.Message)
+	if len(summary) > 1024 {
+		summary = summary[:1024]
+	}
+
+	details := map[string]interface{}{
+		"metric":  violation.Metric,
+		"level":   violation.Level,
+		"value":   violation.Value,
+		"message": violation.Message,
+	}
+	if violation.Mountpoint != "" {
+		details["mountpoint"] = violation.Mountpoint
+		details["device"] = violation.Device
+	}
+
+	payload := map[string]interface{}{
+		"summary":        summary,
+		"source":         pa.Source,
+		"severity":       severity,
+		"component":      violation.Metric,
+		"class":          violation.Level,
+		"timestamp":      time.Now().Format(time.RFC3339),
+		"custom_details": details,
+	}
+	if violation.Mountpoint != "" {
+		payload["group"] = violation.Mountpoint
+	}
+
+	event := map[string]interface{}{
+		"routing_key":  pa.RoutingKey,
+		"event_action": "trigger",
+		"dedup_key":    pa.DedupKey(violation),
+		"client":       "tfc-system-monitor",
+		"payload":      payload,
+	}
+
+	if err := pa.post(event); err != nil {
+		return err
+	}
+	log.Printf("PagerDuty trigger sent: %s", pa.DedupKey(violation))
+	return nil
+}
+
+// Resolve sends a resolve event for a violation that cleared
+func (pa *PagerDutyAction) Resolve(violation ThresholdViolation) error {
+	event := map[string]interface{}{
+		"routing_key":  pa.RoutingKey,
+		"event_action": "resolve",
+		"dedup_key":    pa.DedupKey(violation),
+	}
+
+	if err := pa.post(event); err != nil {
+		return err
+	}
+	log.Printf("PagerDuty resolve sent: %s", pa.DedupKey(violation))
+	return nil
+}
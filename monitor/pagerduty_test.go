@@ -0,0 +1,106 @@
+package monitor
+
+import (
+	"testing"
+)
+
+// TestPagerDutyTrigger tests the trigger event payload and severity mapping
+func TestPagerDutyTrigger(t *testing.T) {
+	rec := &chatRecorder{}
+	server := newChatServer(t, rec)
+
+	action, err := CreateAction(map[string]interface{}{
+		"type":        "pagerduty",
+		"routing_key": "R0UT1NG",
+		"url":         server.URL,
+		"source":      "web-01",
+	})
+	if err != nil {
+		t.Fatalf("CreateAction() error = %v", err)
+	}
+	if err := action.Execute(chatTestViolation); err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+
+	p := rec.payloads[0]
+	checks := map[string]interface{}{
+		"routing_key":  "R0UT1NG",
+		"event_action": "trigger",
+		"dedup_key":    "tfc-system-monitor/web-01/disk//",
+	}
+	for key, want := range checks {
+		if p[key] != want {
+			t.Errorf("%s = %v, want %v", key, p[key], want)
+		}
+	}
+	if got := jsonPath(p, "payload", "severity"); got != "critical" {
+		t.Errorf("severity = %v, want critical", got)
+	}
+	if got := jsonPath(p, "payload", "source"); got != "web-01" {
+		t.Errorf("source = %v, want web-01", got)
+	}
+	if got := jsonPath(p, "payload", "group"); got != "/" {
+		t.Errorf("group = %v, want /", got)
+	}
+}
+
+// TestPagerDutyDedupKey tests that the dedup key ignores the level
+func TestPagerDutyDedupKey(t *testing.T) {
+	pa, err := NewPagerDutyAction(map[string]interface{}{"routing_key": "key", "source": "web-01"})
+	if err != nil {
+		t.Fatalf("NewPagerDutyAction() error = %v", err)
+	}
+	if pa.URL != DefaultPagerDutyURL {
+		t.Errorf("URL = %s, want %s", pa.URL, DefaultPagerDutyURL)
+	}
+
+	warning := ThresholdViolation{Metric: "memory", Level: "warning"}
+	critical := ThresholdViolation{Metric: "memory", Level: "critical"}
+	if pa.DedupKey(warning) != pa.DedupKey(critical) {
+		t.Errorf("dedup keys differ: %s vs %s", pa.DedupKey(warning), pa.DedupKey(critical))
+	}
+	if got := pa.DedupKey(warning); got != "tfc-system-monitor/web-01/memory" {
+		t.Errorf("DedupKey() = %s", got)
+	}
+	if pa.DedupKey(ThresholdViolation{Metric: "disk", Mountpoint: "/"}) == pa.DedupKey(ThresholdViolation{Metric: "disk", Mountpoint: "/var"}) {
+		t.Error("dedup keys of different mountpoints should differ")
+	}
+}
+
+// TestPagerDutyResolve tests that resolved violations send a resolve event
+func TestPagerDutyResolve(t *testing.T) {
+	rec := &chatRecorder{}
+	server := newChatServer(t, rec)
+
+	config := &Config{
+		Alerts: map[string]AlertLevel{
+			"warning": {
+				Actions: []map[string]interface{}{
+					{"type": "pagerduty", "routing_key": "key", "url": server.URL, "source": "web-01"},
+				},
+			},
+		},
+	}
+	resolved := []ThresholdViolation{{Metric: "cpu", Level: "warning", Message: "CPU usage is 85.00%", Value: 85}}
+	if err := ProcessResolved(config, resolved); err != nil {
+		t.Fatalf("ProcessResolved() error = %v", err)
+	}
+
+	if len(rec.payloads) != 1 {
+		t.Fatalf("got %d payloads, want 1", len(rec.payloads))
+	}
+	p := rec.payloads[0]
+	if p["event_action"] != "resolve" || p["dedup_key"] != "tfc-system-monitor/web-01/cpu" {
+		t.Errorf("payload = %v", p)
+	}
+}
+
+// TestPagerDutyRequiresRoutingKey tests that routing_key is required
+func TestPagerDutyRequiresRoutingKey(t *testing.T) {
+	if _, err := NewPagerDutyAction(map[string]interface{}{}); err == nil {
+		t.Error("expected error for missing routing_key")
+	}
+	if err := validateActions("critical", []map[string]interface{}{{"type": "pagerduty", "routing_key": ""}}); err == nil {
+		t.Error("validateActions() expected error for empty routing_key")
+	}
+}
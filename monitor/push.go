@@ -0,0 +1,258 @@
+package monitor
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"time"
+)
+
+// Default push notification endpoints
+const (
+	DefaultNtfyURL     = "https://ntfy.sh"
+	DefaultPushoverURL = "https://api.pushover.net/1/messages.json"
+)
+
+// Default priorities per violation level
+var (
+	ntfyPriorities     = map[string]int{"critical": 5, "warning": 4} // 1 (min) to 5 (max)
+	gotifyPriorities   = map[string]int{"critical": 8, "warning": 5} // 0 to 10
+	pushoverPriorities = map[string]int{"critical": 1, "warning": 0} // -2 to 2
+)
+
+// ntfyTags are added to ntfy notifications per level; ntfy shows them as emojis
+var ntfyTags = map[string]string{
+	"critical": "rotating_light",
+	"warning":  "warning",
+}
+
+// actionPriorities returns the priority of each level: the defaults, overridden by the
+// action's "priority" field, which is either one number for all levels or a map by level
+func actionPriorities(config map[string]interface{}, defaults map[string]int, min int, max int) (map[string]int, error) {
+	priorities := make(map[string]int, len(defaults))
+	for level, p := range defaults {
+		priorities[level] = p
+	}
+
+	set := func(level string, value interface{}) error {
+		var p int
+		switch v := value.(type) {
+		case int:
+			p = v
+		case float64:
+			p = int(v)
+		default:
+			return fmt.Errorf("priority must be a number")
+		}
+		if p < min || p > max {
+			return fmt.Errorf("priority %d out of range %d to %d", p, min, max)
+		}
+		priorities[level] = p
+		return nil
+	}
+
+	switch v := config["priority"].(type) {
+	case nil:
+	case map[interface{}]interface{}:
+		for k, value := range v {
+			level, _ := k.(string)
+			if _, ok := defaults[level]; !ok {
+				return nil, fmt.Errorf("unknown priority level '%v'", k)
+			}
+			if err := set(level, value); err != nil {
+				return nil, err
+			}
+		}
+	case map[string]interface{}:
+		for level, value := range v {
+			if _, ok := defaults[level]; !ok {
+				return nil, fmt.Errorf("unknown priority level '%s'", level)
+			}
+			if err := set(level, value); err != nil {
+				return nil, err
+			}
+		}
+	default:
+		for level := range defaults {
+			if err := set(level, v); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return priorities, nil
+}
+
+// pushTitle returns the notification title of a violation
+func pushTitle(violation ThresholdViolation) string {
+	hostname, _ := os.Hostname()
+	subject := violation.Metric
+	if violation.Mountpoint != "" {
+		subject += " " + violation.Mountpoint
+	}
+	return fmt.Sprintf("[%s] %s: %s", strings.ToUpper(violation.Level), hostname, subject)
+}
+
+// NtfyAction publishes notifications to an ntfy topic
+type NtfyAction struct {
+	jsonWebhook
+	Topic      string
+	Tags       []string
+	Priorities map[string]int
+}
+
+// NewNtfyAction creates a new ntfy alert action
+func NewNtfyAction(config map[string]interface{}) (*NtfyAction, error) {
+	jw, err := newJSONWebhook("ntfy", withDefaultURL(config, DefaultNtfyURL))
+	if err != nil {
+		return nil, err
+	}
+	na := &NtfyAction{jsonWebhook: jw}
+
+	topic, ok := config["topic"].(string)
+	if !ok || topic == "" {
+		return nil, fmt.Errorf("ntfy action requires 'topic' field")
+	}
+	na.Topic = topic
+	na.Tags = actionStrings(config, "tags")
+
+	if token, ok := config["token"].(string); ok && token != "" {
+		na.Headers = map[string]string{"Authorization": "Bearer " + os.ExpandEnv(token)}
+	}
+
+	if na.Priorities, err = actionPriorities(config, ntfyPriorities, 1, 5); err != nil {
+		return nil, fmt.Errorf("ntfy action: %w", err)
+	}
+
+	return na, nil
+}
+
+// Execute publishes a notification for a violation
+func (na *NtfyAction) Execute(violation ThresholdViolation) error {
+	tags := append([]string{}, na.Tags...)
+	if tag, ok := ntfyTags[violation.Level]; ok {
+		tags = append(tags, tag)
+	}
+
+	payload := map[string]interface{}{
+		"topic":    na.Topic,
+		"title":    pushTitle(violation),
+		"message":  violation.Message,
+		"priority": na.Priorities[violation.Level],
+		"tags":     tags,
+	}
+	if err := na.post(payload); err != nil {
+		return err
+	}
+	log.Printf("ntfy notification sent to %s: %s", na.Topic, violation.Message)
+	return nil
+}
+
+// GotifyAction sends notifications to a Gotify server
+type GotifyAction struct {
+	jsonWebhook
+	Priorities map[string]int
+}
+
+// NewGotifyAction creates a new Gotify alert action
+func NewGotifyAction(config map[string]interface{}) (*GotifyAction, error) {
+	jw, err := newJSONWebhook("gotify", config)
+	if err != nil {
+		return nil, err
+	}
+	jw.URL = strings.TrimRight(jw.URL, "/") + "/message"
+	ga := &GotifyAction{jsonWebhook: jw}
+
+	token, ok := config["token"].(string)
+	if !ok || token == "" {
+		return nil, fmt.Errorf("gotify action requires 'token' field")
+	}
+	ga.Headers = map[string]string{"X-Gotify-Key": os.ExpandEnv(token)}
+
+	if ga.Priorities, err = actionPriorities(config, gotifyPriorities, 0, 10); err != nil {
+		return nil, fmt.Errorf("gotify action: %w", err)
+	}
+
+	return ga, nil
+}
+
+// Execute sends a notification for a violation
+func (ga *GotifyAction) Execute(violation ThresholdViolation) error {
+	payload := map[string]interface{}{
+		"title":    pushTitle(violation),
+		"message":  violation.Message,
+		"priority": ga.Priorities[violation.Level],
+	}
+	if err := ga.post(payload); err != nil {
+		return err
+	}
+	log.Printf("Gotify notification sent: %s", violation.Message)
+	return nil
+}
+
+// PushoverAction sends notifications through the Pushover API
+type PushoverAction struct {
+	jsonWebhook
+	Token      string
+	User       string
+	Sound      string
+	Priorities map[string]int
+}
+
+// NewPushoverAction creates a new Pushover alert action
+func NewPushoverAction(config map[string]interface{}) (*PushoverAction, error) {
+	jw, err := newJSONWebhook("pushover", withDefaultURL(config, DefaultPushoverURL))
+	if err != nil {
+		return nil, err
+	}
+	pa := &PushoverAction{jsonWebhook: jw}
+
+	token, ok := config["token"].(string)
+	if !ok || token == "" {
+		return nil, fmt.Errorf("pushover action requires 'token' field")
+	}
+	pa.Token = os.ExpandEnv(token)
+
+	user, ok := config["user"].(string)
+	if !ok || user == "" {
+		return nil, fmt.Errorf("pushover action requires 'user' field")
+	}
+	pa.User = os.ExpandEnv(user)
+
+	if sound, ok := config["sound"].(string); ok {
+		pa.Sound = sound
+	}
+
+	if pa.Priorities, err = actionPriorities(config, pushoverPriorities, -2, 2); err != nil {
+		return nil, fmt.Errorf("pushover action: %w", err)
+	}
+
+	return pa, nil
+}
+
+// Execute sends a notification for a violation
+func (pa *PushoverAction) Execute(violation ThresholdViolation) error {
+	priority := pa.Priorities[violation.Level]
+	payload := map[string]interface{}{
+		"token":     pa.Token,
+		"user":      pa.User,
+		"title":     pushTitle(violation),
+		"message":   violation.Message,
+		"priority":  priority,
+		"timestamp": time.Now().Unix(),
+	}
+	if pa.Sound != "" {
+		payload["sound"] = pa.Sound
+	}
+	// Emergency notifications repeat until acknowledged in the app
+	if priority == 2 {
+		payload["retry"] = 60
+		payload["expire"] = 3600
+	}
+
+	if err := pa.post(payload); err != nil {
+		return err
+	}
+	log.Printf("Pushover notification sent: %s", violation.Message)
+	return nil
+}
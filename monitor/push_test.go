@@ -0,0 +1,188 @@
+package monitor
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// pushRequest is a request received by the push notification stand-in
+type pushRequest struct {
+	path    string
+	headers http.Header
+	payload map[string]interface{}
+}
+
+// newPushServer starts a local stand-in for a push notification service
+func newPushServer(t *testing.T) (*httptest.Server, func() []pushRequest) {
+	t.Helper()
+	var mu sync.Mutex
+	var requests []pushRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		var payload map[string]interface{}
+		if err := json.Unmarshal(body, &payload); err != nil {
+			t.Errorf("invalid JSON payload: %v", err)
+		}
+		mu.Lock()
+		requests = append(requests, pushRequest{path: r.URL.Path, headers: r.Header, payload: payload})
+		mu.Unlock()
+		w.WriteHeader(http.StatusOK)
+	}))
+	t.Cleanup(server.Close)
+	return server, func() []pushRequest {
+		mu.Lock()
+		defer mu.Unlock()
+		return requests
+	}
+}
+
+// TestNtfyAction tests the ntfy payload, priority mapping and authentication
+func TestNtfyAction(t *testing.T) {
+	server, requests := newPushServer(t)
+	t.Setenv("NTFY_TOKEN", "tk_secret")
+
+	action, err := CreateAction(map[string]interface{}{
+		"type":  "ntfy",
+		"url":   server.URL,
+		"topic": "ops-alerts",
+		"tags":  []interface{}{"server"},
+		"token": "${NTFY_TOKEN}",
+	})
+	if err != nil {
+		t.Fatalf("CreateAction() error = %v", err)
+	}
+	if err := action.Execute(chatTestViolation); err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if err := action.Execute(ThresholdViolation{Metric: "cpu", Level: "warning", Message: "cpu usage: 85%"}); err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+
+	reqs := requests()
+	if len(reqs) != 2 {
+		t.Fatalf("got %d requests, want 2", len(reqs))
+	}
+	critical, warning := reqs[0].payload, reqs[1].payload
+	if critical["topic"] != "ops-alerts" || critical["message"] != chatTestViolation.Message {
+		t.Errorf("payload = %v", critical)
+	}
+	if critical["priority"] != 5.0 || warning["priority"] != 4.0 {
+		t.Errorf("priorities = %v/%v, want 5/4", critical["priority"], warning["priority"])
+	}
+	if title, _ := critical["title"].(string); !strings.HasPrefix(title, "[CRITICAL]") || !strings.HasSuffix(title, "disk /") {
+		t.Errorf("title = %q", title)
+	}
+	if jsonPath(critical, "tags", 0) != "server" || jsonPath(critical, "tags", 1) != "rotating_light" {
+		t.Errorf("tags = %v", critical["tags"])
+	}
+	if got := reqs[0].headers.Get("Authorization"); got != "Bearer tk_secret" {
+		t.Errorf("Authorization = %q", got)
+	}
+}
+
+// TestGotifyAction tests the Gotify endpoint, token header and priority override
+func TestGotifyAction(t *testing.T) {
+	server, requests := newPushServer(t)
+
+	action, err := CreateAction(map[string]interface{}{
+		"type":     "gotify",
+		"url":      server.URL + "/",
+		"token":    "AppToken",
+		"priority": map[interface{}]interface{}{"critical": 10},
+	})
+	if err != nil {
+		t.Fatalf("CreateAction() error = %v", err)
+	}
+	if err := action.Execute(chatTestViolation); err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if err := action.Execute(ThresholdViolation{Metric: "cpu", Level: "warning"}); err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+
+	reqs := requests()
+	if reqs[0].path != "/message" {
+		t.Errorf("path = %s, want /message", reqs[0].path)
+	}
+	if got := reqs[0].headers.Get("X-Gotify-Key"); got != "AppToken" {
+		t.Errorf("X-Gotify-Key = %q", got)
+	}
+	if reqs[0].payload["priority"] != 10.0 || reqs[1].payload["priority"] != 5.0 {
+		t.Errorf("priorities = %v/%v, want 10/5", reqs[0].payload["priority"], reqs[1].payload["priority"])
+	}
+}
+
+// TestPushoverAction tests the Pushover payload and emergency priority
+func TestPushoverAction(t *testing.T) {
+	server, requests := newPushServer(t)
+
+	action, err := CreateAction(map[string]interface{}{
+		"type":     "pushover",
+		"url":      server.URL,
+		"token":    "app-key",
+		"user":     "user-key",
+		"sound":    "siren",
+		"priority": map[interface{}]interface{}{"critical": 2},
+	})
+	if err != nil {
+		t.Fatalf("CreateAction() error = %v", err)
+	}
+	if err := action.Execute(chatTestViolation); err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if err := action.Execute(ThresholdViolation{Metric: "cpu", Level: "warning"}); err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+
+	critical, warning := requests()[0].payload, requests()[1].payload
+	checks := map[string]interface{}{"token": "app-key", "user": "user-key", "sound": "siren", "priority": 2.0}
+	for key, want := range checks {
+		if critical[key] != want {
+			t.Errorf("%s = %v, want %v", key, critical[key], want)
+		}
+	}
+	if critical["retry"] == nil || critical["expire"] == nil {
+		t.Errorf("emergency payload missing retry/expire: %v", critical)
+	}
+	if warning["priority"] != 0.0 || warning["retry"] != nil {
+		t.Errorf("warning payload = %v", warning)
+	}
+
+	pa, _ := NewPushoverAction(map[string]interface{}{"token": "a", "user": "u"})
+	if pa.URL != DefaultPushoverURL {
+		t.Errorf("URL = %s, want %s", pa.URL, DefaultPushoverURL)
+	}
+}
+
+// TestPushActionValidation tests required fields and priority ranges
+func TestPushActionValidation(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  map[string]interface{}
+		wantErr string
+	}{
+		{"ntfy without topic", map[string]interface{}{"type": "ntfy"}, "'topic'"},
+		{"ntfy priority out of range", map[string]interface{}{"type": "ntfy", "topic": "t", "priority": 6}, "out of range"},
+		{"ntfy unknown priority level", map[string]interface{}{"type": "ntfy", "topic": "t", "priority": map[interface{}]interface{}{"info": 1}}, "unknown priority level"},
+		{"gotify without url", map[string]interface{}{"type": "gotify", "token": "t"}, "'url'"},
+		{"gotify without token", map[string]interface{}{"type": "gotify", "url": "http://gotify"}, "'token'"},
+		{"pushover without user", map[string]interface{}{"type": "pushover", "token": "t"}, "'user'"},
+		{"pushover priority not a number", map[string]interface{}{"type": "pushover", "token": "t", "user": "u", "priority": "high"}, "must be a number"},
+	}
+	for _, tt := range tests {
+		if _, err := CreateAction(tt.config); err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("%s: CreateAction() error = %v, want error containing %q", tt.name, err, tt.wantErr)
+		}
+	}
+
+	// A single priority applies to all levels
+	na, err := NewNtfyAction(map[string]interface{}{"topic": "t", "priority": 3})
+	if err != nil || na.Priorities["critical"] != 3 || na.Priorities["warning"] != 3 || na.URL != DefaultNtfyURL {
+		t.Errorf("NewNtfyAction() = %+v, %v", na, err)
+	}
+}
@@ -0,0 +1,231 @@
+package monitor
+
+import (
+	"fmt"
+	"regexp"
+)
+
+// Receiver is a named set of alert actions that routes deliver to
+type Receiver struct {
+	Name    string                   `yaml:"name"`
+	Actions []map[string]interface{} `yaml:"actions"`
+}
+
+// Route selects receivers for violations by their labels. Child routes are evaluated in
+// order; the first that matches handles the violation unless it sets continue. A
+// violation that matches no child goes to the route's own receiver.
+type Route struct {
+	Receiver string            `yaml:"receiver"` // Inherited from the parent route when empty
+	Match    map[string]string `yaml:"match"`    // Labels that must equal the given values
+	MatchRE  map[string]string `yaml:"match_re"` // Labels that must match the given regular expressions
+	Continue bool              `yaml:"continue"` // Keep evaluating sibling routes after a match
+	Routes   []Route           `yaml:"routes"`
+
+	matchRE map[string]*regexp.Regexp // MatchRE anchored and compiled when the config is validated
+}
+
+// alertBatch is a set of violations to deliver through the actions of one receiver
+type alertBatch struct {
+	receiver   string // empty when alerts are selected by level only
+	level      string
+	actions    []map[string]interface{}
+	violations []ThresholdViolation
+}
+
+// routingLabels returns the labels routes match on: the violation's custom labels plus
+// metric, level, mountpoint and device
+func (v ThresholdViolation) routingLabels() map[string]string {
+	labels := make(map[string]string, len(v.Labels)+4)
+	for k, val := range v.Labels {
+		labels[k] = val
+	}
+	labels["metric"] = v.Metric
+	labels["level"] = v.Level
+	if v.Mountpoint != "" {
+		labels["mountpoint"] = v.Mountpoint
+		labels["device"] = v.Device
+	}
+	return labels
+}
+
+// matches reports whether labels satisfy the route's matchers
+func (r *Route) matches(labels map[string]string) bool {
+	for name, value := range r.Match {
+		if labels[name] != value {
+			return false
+		}
+	}
+	for name := range r.MatchRE {
+		re, ok := r.matchRE[name]
+		if !ok || !re.MatchString(labels[name]) {
+			return false
+		}
+	}
+	return true
+}
+
+// compile anchors and compiles the route's match_re patterns; children are compiled separately
+func (r *Route) compile() error {
+	r.matchRE = make(map[string]*regexp.Regexp, len(r.MatchRE))
+	for name, pattern := range r.MatchRE {
+		re, err := regexp.Compile("^(?:" + pattern + ")$")
+		if err != nil {
+			return fmt.Errorf("invalid match_re for '%s': %w", name, err)
+		}
+		r.matchRE[name] = re
+	}
+	return nil
+}
+
+// receivers returns the receivers a violation with labels is routed to
+func (r *Route) receivers(labels map[string]string, inherited string) []string {
+	receiver := r.Receiver
+	if receiver == "" {
+		receiver = inherited
+	}
+
+	var matched []string
+	for i := range r.Routes {
+		child := &r.Routes[i]
+		if !child.matches(labels) {
+			continue
+		}
+		for _, name := range child.receivers(labels, receiver) {
+			if !containsString(matched, name) {
+				matched = append(matched, name)
+			}
+		}
+		if !child.Continue {
+			break
+		}
+	}
+
+	if len(matched) == 0 {
+		return []string{receiver}
+	}
+	return matched
+}
+
+// containsString reports whether list contains s
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+// GetReceiver returns the receiver with the given name
+func (c *Config) GetReceiver(name string) (Receiver, bool) {
+	for _, receiver := range c.Receivers {
+		if receiver.Name == name {
+			return receiver, true
+		}
+	}
+	return Receiver{}, false
+}
+
+// routeViolations groups violations of a level by the receivers they are routed to.
+// Without a route, all violations go to the actions of their level.
+func (c *Config) routeViolations(level string, violations []ThresholdViolation) []alertBatch {
+	if len(violations) == 0 {
+		return nil
+	}
+	if c.Route == nil {
+		return []alertBatch{{level: level, actions: c.GetAlertActions(level), violations: violations}}
+	}
+
+	var order []string
+	byReceiver := make(map[string][]ThresholdViolation)
+	for _, v := range violations {
+		for _, name := range c.Route.receivers(v.routingLabels(), "") {
+			if _, ok := byReceiver[name]; !ok {
+				order = append(order, name)
+			}
+			byReceiver[name] = append(byReceiver[name], v)
+		}
+	}
+
+	var batches []alertBatch
+	for _, name := range order {
+		receiver, _ := c.GetReceiver(name)
+		batches = append(batches, alertBatch{
+			receiver:   name,
+			level:      level,
+			actions:    receiver.Actions,
+			violations: byReceiver[name],
+		})
+	}
+	return batches
+}
+
+// violationLabels returns the custom labels of a violation: the global labels, overridden
+// by those of its rule, or of its metric and matching disk override
+func (c *Config) violationLabels(v ThresholdViolation) map[string]string {
+	labels := make(map[string]string)
+	merge := func(src map[string]string) {
+		for k, val := range src {
+			labels[k] = val
+		}
+	}
+
+	merge(c.Labels)
+	if rule, ok := c.GetRule(v.Metric); ok {
+		merge(rule.Labels)
+	} else if mc, ok := c.Metrics[v.Metric]; ok {
+		merge(mc.Labels)
+		if v.Mountpoint != "" {
+			partition := PartitionInfo{Device: v.Device, Mountpoint: v.Mountpoint}
+			if override, ok := matchDiskOverride(mc.Overrides, partition); ok {
+				merge(override.Labels)
+			}
+		}
+	}
+
+	if len(labels) == 0 {
+		return nil
+	}
+	return labels
+}
+
+// validateRouting validates receivers and the routing tree
+func validateRouting(config *Config) error {
+	seen := make(map[string]bool)
+	for i, receiver := range config.Receivers {
+		if receiver.Name == "" {
+			return fmt.Errorf("receiver %d missing 'name' field", i)
+		}
+		if seen[receiver.Name] {
+			return fmt.Errorf("duplicate receiver '%s'", receiver.Name)
+		}
+		seen[receiver.Name] = true
+		if err := validateActions(fmt.Sprintf("receiver '%s'", receiver.Name), receiver.Actions); err != nil {
+			return err
+		}
+	}
+
+	if config.Route == nil {
+		return nil
+	}
+	if config.Route.Receiver == "" {
+		return fmt.Errorf("route missing default 'receiver' field")
+	}
+	return validateRoute(config.Route, seen, "route")
+}
+
+// validateRoute validates a route and its children
+func validateRoute(route *Route, receivers map[string]bool, where string) error {
+	if route.Receiver != "" && !receivers[route.Receiver] {
+		return fmt.Errorf("%s references unknown receiver '%s'", where, route.Receiver)
+	}
+	if err := route.compile(); err != nil {
+		return fmt.Errorf("%s has %w", where, err)
+	}
+	for i := range route.Routes {
+		if err := validateRoute(&route.Routes[i], receivers, fmt.Sprintf("%s.routes[%d]", where, i)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
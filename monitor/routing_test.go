@@ -0,0 +1,190 @@
+package monitor
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// testRoute sends disk alerts to the storage team, CPU alerts to the app team, critical
+// alerts additionally to on-call, and everything else to ops
+var testRoute = &Route{
+	Receiver: "ops",
+	Routes: []Route{
+		{Receiver: "oncall", Match: map[string]string{"level": "critical"}, Continue: true},
+		{Receiver: "storage", Match: map[string]string{"metric": "disk"}},
+		{Receiver: "app", MatchRE: map[string]string{"metric": "cpu|load_.*"}},
+		{Match: map[string]string{"team": "db"}, Routes: []Route{
+			{Receiver: "dba", Match: map[string]string{"env": "prod"}},
+		}},
+	},
+}
+
+// compileTestRoute validates testRoute, which compiles its match_re patterns
+func compileTestRoute(t *testing.T) {
+	t.Helper()
+	receivers := map[string]bool{"ops": true, "oncall": true, "storage": true, "app": true, "dba": true}
+	if err := validateRoute(testRoute, receivers, "route"); err != nil {
+		t.Fatalf("validateRoute() error = %v", err)
+	}
+}
+
+// TestRouteReceivers tests matching, continue semantics, inheritance and the default receiver
+func TestRouteReceivers(t *testing.T) {
+	compileTestRoute(t)
+	tests := []struct {
+		name      string
+		violation ThresholdViolation
+		want      []string
+	}{
+		{"disk to storage", ThresholdViolation{Metric: "disk", Level: "warning", Mountpoint: "/"}, []string{"storage"}},
+		{"cpu to app", ThresholdViolation{Metric: "cpu", Level: "warning"}, []string{"app"}},
+		{"regex is anchored", ThresholdViolation{Metric: "cpu_steal", Level: "warning"}, []string{"ops"}},
+		{"regex alternative", ThresholdViolation{Metric: "load_high", Level: "warning"}, []string{"app"}},
+		{"critical continues", ThresholdViolation{Metric: "disk", Level: "critical"}, []string{"oncall", "storage"}},
+		{"critical without other match", ThresholdViolation{Metric: "memory", Level: "critical"}, []string{"oncall"}},
+		{"default receiver", ThresholdViolation{Metric: "memory", Level: "warning"}, []string{"ops"}},
+		{"nested match", ThresholdViolation{Metric: "db_rule", Level: "warning", Labels: map[string]string{"team": "db", "env": "prod"}}, []string{"dba"}},
+		{"inherited receiver", ThresholdViolation{Metric: "db_rule", Level: "warning", Labels: map[string]string{"team": "db"}}, []string{"ops"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := testRoute.receivers(tt.violation.routingLabels(), "")
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("receivers() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestRouteViolations tests grouping violations into receiver batches
+func TestRouteViolations(t *testing.T) {
+	compileTestRoute(t)
+	config := &Config{
+		Receivers: []Receiver{
+			{Name: "ops", Actions: []map[string]interface{}{{"type": "stdout"}}},
+			{Name: "oncall", Actions: []map[string]interface{}{{"type": "logger"}}},
+			{Name: "storage", Actions: []map[string]interface{}{{"type": "webhook", "url": "http://storage"}}},
+			{Name: "app", Actions: []map[string]interface{}{{"type": "webhook", "url": "http://app"}}},
+			{Name: "dba", Actions: []map[string]interface{}{}},
+		},
+		Route: testRoute,
+	}
+
+	violations := []ThresholdViolation{
+		{Metric: "disk", Level: "critical", Mountpoint: "/"},
+		{Metric: "cpu", Level: "critical"},
+	}
+	batches := config.routeViolations("critical", violations)
+
+	got := map[string]int{}
+	for _, b := range batches {
+		got[b.receiver] = len(b.violations)
+		if receiver, _ := config.GetReceiver(b.receiver); !reflect.DeepEqual(b.actions, receiver.Actions) {
+			t.Errorf("batch %s has actions %v", b.receiver, b.actions)
+		}
+	}
+	want := map[string]int{"oncall": 2, "storage": 1, "app": 1}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("batches = %v, want %v", got, want)
+	}
+
+	// Without a route, actions are selected by level
+	config.Route = nil
+	config.Alerts = map[string]AlertLevel{"critical": {Actions: []map[string]interface{}{{"type": "stdout"}}}}
+	batches = config.routeViolations("critical", violations)
+	if len(batches) != 1 || batches[0].receiver != "" || len(batches[0].violations) != 2 {
+		t.Errorf("unrouted batches = %+v", batches)
+	}
+}
+
+// TestViolationLabels tests label inheritance from global, metric, override and rule labels
+func TestViolationLabels(t *testing.T) {
+	config := &Config{
+		Labels: map[string]string{"env": "prod", "team": "ops"},
+		Metrics: map[string]MetricConfig{
+			"disk": {
+				Labels: map[string]string{"team": "storage"},
+				Overrides: []DiskOverride{
+					{Mountpoint: "/var/lib/postgresql", Labels: map[string]string{"team": "db"}},
+				},
+			},
+		},
+		Rules: []RuleConfig{{Name: "cpu_saturated", Labels: map[string]string{"team": "app"}}},
+	}
+
+	tests := []struct {
+		violation ThresholdViolation
+		want      map[string]string
+	}{
+		{ThresholdViolation{Metric: "disk", Mountpoint: "/"}, map[string]string{"env": "prod", "team": "storage"}},
+		{ThresholdViolation{Metric: "disk", Mountpoint: "/var/lib/postgresql"}, map[string]string{"env": "prod", "team": "db"}},
+		{ThresholdViolation{Metric: "cpu_saturated"}, map[string]string{"env": "prod", "team": "app"}},
+		{ThresholdViolation{Metric: "memory"}, map[string]string{"env": "prod", "team": "ops"}},
+	}
+	for _, tt := range tests {
+		if got := config.violationLabels(tt.violation); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("violationLabels(%s %s) = %v, want %v", tt.violation.Metric, tt.violation.Mountpoint, got, tt.want)
+		}
+	}
+}
+
+// TestLoadConfigRouting tests loading and validating receivers and routes
+func TestLoadConfigRouting(t *testing.T) {
+	base := `
+metrics:
+  disk:
+    enabled: true
+    thresholds: {warning: 80, critical: 90}
+    labels: {team: storage}
+receivers:
+  - name: ops
+    actions:
+      - type: stdout
+  - name: storage
+    actions:
+      - type: webhook
+        url: https://storage.example.com/hook
+`
+	tests := []struct {
+		name    string
+		route   string
+		wantErr string
+	}{
+		{"valid", "route:\n  receiver: ops\n  routes:\n    - match: {team: storage}\n      match_re: {metric: \"disk|load_.*\"}\n      receiver: storage\n      continue: true\n", ""},
+		{"missing default receiver", "route:\n  routes:\n    - receiver: storage\n", "default 'receiver'"},
+		{"unknown receiver", "route:\n  receiver: ops\n  routes:\n    - receiver: nobody\n", "unknown receiver 'nobody'"},
+		{"invalid regex", "route:\n  receiver: ops\n  routes:\n    - match_re: {metric: \"(\"}\n", "invalid match_re"},
+		{"unknown route field", "route:\n  receiver: ops\n  routes:\n    - recevier: storage\n", "unknown field 'recevier'"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "config.yaml")
+			if err := os.WriteFile(path, []byte(base+tt.route), 0644); err != nil {
+				t.Fatal(err)
+			}
+			config, err := LoadConfig(path)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("LoadConfig() error = %v, want error containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("LoadConfig() error = %v", err)
+			}
+			if len(config.Receivers) != 2 || config.Route.Routes[0].Receiver != "storage" || !config.Route.Routes[0].Continue {
+				t.Errorf("unexpected routing config: %+v", config.Route)
+			}
+			// match_re patterns are compiled when the config is loaded
+			disk := ThresholdViolation{Metric: "disk", Level: "warning", Labels: map[string]string{"team": "storage"}}
+			if got := config.Route.receivers(disk.routingLabels(), ""); !reflect.DeepEqual(got, []string{"storage"}) {
+				t.Errorf("receivers() = %v, want storage", got)
+			}
+		})
+	}
+}
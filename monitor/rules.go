@@ -0,0 +1,226 @@
+package monitor
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"strconv"
+	"text/template"
+)
+
+// RuleConfig defines a custom violation raised when an expression over the collected metrics is true
+type RuleConfig struct {
+	Name     string            `yaml:"name"`     // Unique rule name, used as the violation metric
+	Expr     string            `yaml:"expr"`     // Boolean expression, e.g. "cpu > 90 && load1 > 2 * cores"
+	Level    string            `yaml:"level"`    // "warning" or "critical"
+	Message  string            `yaml:"message"`  // Optional text/template over the variables, e.g. "load {{.load1}}"
+	Value    string            `yaml:"value"`    // Optional expression reported as the violation value
+	Throttle ThrottleConfig    `yaml:"throttle"` // Throttle settings for this rule
+	Labels   map[string]string `yaml:"labels"`   // Added to the rule's violations, for routing
+}
+
+// ruleVariableNames lists the variables available to rule expressions
+var ruleVariableNames = []string{
+	"cpu",                    // total CPU usage (%)
+	"cores",                  // logical cores
+	"physical_cores",         // physical cores
+	"load1",                  // 1 minute load average
+	"load5",                  // 5 minute load average
+	"load15",                 // 15 minute load average
+	"memory_used",            // used memory (%)
+	"memory_free",            // free memory (%)
+	"memory_total_bytes",     // total memory (bytes)
+	"memory_available_bytes", // available memory (bytes)
+	"swap_used",              // used swap (%)
+	"swap_total_bytes",       // total swap (bytes)
+	"swap_used_bytes",        // used swap (bytes)
+	"disk_used_max",          // usage of the fullest partition (%)
+	"disk_free_min",          // free space of the fullest partition (%)
+}
+
+// ruleVariables collects the values rule expressions are evaluated against
+func ruleVariables(config *Config, stats *SystemStats) (map[string]float64, error) {
+	cpuUsage, err := strconv.ParseFloat(stats.CPUInfo.TotalCPUUsage, 64)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse CPU usage: %w", err)
+	}
+	memUsed, err := strconv.ParseFloat(stats.MemoryInfo.VirtualMemory.Percentage, 64)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse memory usage: %w", err)
+	}
+	swapUsed, err := strconv.ParseFloat(stats.MemoryInfo.SwapMemory.Percentage, 64)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse swap usage: %w", err)
+	}
+
+	// The fullest partition, honouring the disk exclude settings
+	diskUsedMax := 0.0
+	diskConfig, _ := config.GetMetricConfig("disk")
+	for _, partition := range stats.DiskInfo.Partitions {
+		if isPartitionExcludedByConfig(partition, diskConfig.Exclude) {
+			continue
+		}
+		percentage, err := strconv.ParseFloat(partition.Percentage, 64)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse disk usage for device %s: %w", partition.Device, err)
+		}
+		if percentage > diskUsedMax {
+			diskUsedMax = percentage
+		}
+	}
+
+	return map[string]float64{
+		"cpu":                    cpuUsage,
+		"cores":                  float64(stats.CPUInfo.TotalCores),
+		"physical_cores":         float64(stats.CPUInfo.PhysicalCores),
+		"load1":                  stats.LoadInfo.Load1,
+		"load5":                  stats.LoadInfo.Load5,
+		"load15":                 stats.LoadInfo.Load15,
+		"memory_used":            memUsed,
+		"memory_free":            100 - memUsed,
+		"memory_total_bytes":     float64(stats.MemoryInfo.VirtualMemory.TotalBytes),
+		"memory_available_bytes": float64(stats.MemoryInfo.VirtualMemory.AvailableBytes),
+		"swap_used":              swapUsed,
+		"swap_total_bytes":       float64(stats.MemoryInfo.SwapMemory.TotalBytes),
+		"swap_used_bytes":        float64(stats.MemoryInfo.SwapMemory.UsedBytes),
+		"disk_used_max":          diskUsedMax,
+		"disk_free_min":          100 - diskUsedMax,
+	}, nil
+}
+
+// checkRules evaluates the configured rules against the collected variables
+func checkRules(config *Config, vars map[string]float64) []ThresholdViolation {
+	var violations []ThresholdViolation
+
+	for _, rule := range config.Rules {
+		expr, err := ParseExpression(rule.Expr)
+		if err != nil {
+			log.Printf("Rule %s: %v", rule.Name, err)
+			continue
+		}
+		matched, err := expr.Match(vars)
+		if err != nil {
+			log.Printf("Rule %s: evaluation failed: %v", rule.Name, err)
+			continue
+		}
+		if !matched {
+			continue
+		}
+
+		value, err := ruleValue(rule, expr, vars)
+		if err != nil {
+			log.Printf("Rule %s: value evaluation failed: %v", rule.Name, err)
+		}
+
+		message, err := ruleMessage(rule, vars)
+		if err != nil {
+			log.Printf("Rule %s: message template failed: %v", rule.Name, err)
+			message = fmt.Sprintf("rule %s matched: %s", rule.Name, rule.Expr)
+		}
+
+		violations = append(violations, ThresholdViolation{
+			Metric:  rule.Name,
+			Level:   rule.Level,
+			Message: message,
+			Value:   value,
+		})
+	}
+
+	return violations
+}
+
+// ruleValue returns the violation value of a matched rule: the value expression if set,
+// otherwise the first variable the condition refers to
+func ruleValue(rule RuleConfig, expr *Expression, vars map[string]float64) (float64, error) {
+	if rule.Value != "" {
+		valueExpr, err := ParseExpression(rule.Value)
+		if err != nil {
+			return 0, err
+		}
+		return valueExpr.Eval(vars)
+	}
+	if names := expr.Variables(); len(names) > 0 {
+		return vars[names[0]], nil
+	}
+	return 0, nil
+}
+
+// ruleMessage renders the message template of a rule
+func ruleMessage(rule RuleConfig, vars map[string]float64) (string, error) {
+	if rule.Message == "" {
+		return fmt.Sprintf("rule %s matched: %s", rule.Name, rule.Expr), nil
+	}
+
+	tmpl, err := template.New(rule.Name).Option("missingkey=error").Parse(rule.Message)
+	if err != nil {
+		return "", err
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, vars); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// validateRules validates the rules section
+func validateRules(rules []RuleConfig) error {
+	known := make(map[string]float64, len(ruleVariableNames))
+	for _, name := range ruleVariableNames {
+		known[name] = 0
+	}
+
+	names := make(map[string]bool)
+	for i, rule := range rules {
+		if rule.Name == "" {
+			return fmt.Errorf("rule %d missing 'name' field", i)
+		}
+		if rule.Name == "disk" || rule.Name == "cpu" || rule.Name == "memory" {
+			return fmt.Errorf("rule name '%s' clashes with a built-in metric", rule.Name)
+		}
+		if names[rule.Name] {
+			return fmt.Errorf("duplicate rule name '%s'", rule.Name)
+		}
+		names[rule.Name] = true
+
+		if rule.Level != "warning" && rule.Level != "critical" {
+			return fmt.Errorf("rule '%s' 'level' must be 'warning' or 'critical'", rule.Name)
+		}
+		if rule.Throttle.MinDurationMinutes < 0 {
+			return fmt.Errorf("rule '%s' 'min_duration_minutes' must be >= 0", rule.Name)
+		}
+
+		if rule.Expr == "" {
+			return fmt.Errorf("rule '%s' missing 'expr' field", rule.Name)
+		}
+		for _, source := range []string{rule.Expr, rule.Value} {
+			if source == "" {
+				continue
+			}
+			expr, err := ParseExpression(source)
+			if err != nil {
+				return fmt.Errorf("rule '%s': %w", rule.Name, err)
+			}
+			for _, name := range expr.Variables() {
+				if _, ok := known[name]; !ok {
+					return fmt.Errorf("rule '%s' uses unknown variable '%s'", rule.Name, name)
+				}
+			}
+		}
+
+		if _, err := ruleMessage(rule, known); err != nil {
+			return fmt.Errorf("rule '%s' has an invalid message template: %w", rule.Name, err)
+		}
+	}
+
+	return nil
+}
+
+// GetRule gets a rule by name
+func (c *Config) GetRule(name string) (RuleConfig, bool) {
+	for _, rule := range c.Rules {
+		if rule.Name == name {
+			return rule, true
+		}
+	}
+	return RuleConfig{}, false
+}
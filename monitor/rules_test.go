@@ -0,0 +1,155 @@
+package monitor
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func rulesTestStats() *SystemStats {
+	return &SystemStats{
+		CPUInfo: CPUInfo{TotalCores: 4, PhysicalCores: 2, TotalCPUUsage: "95.00"},
+		MemoryInfo: MemoryInfo{
+			VirtualMemory: VirtualMemory{Percentage: "92.00", TotalBytes: 16 * gib, AvailableBytes: 1 * gib},
+			SwapMemory:    SwapMemory{Percentage: "60.00"},
+		},
+		DiskInfo: DiskInfo{Partitions: []PartitionInfo{
+			{Device: "/dev/sda1", Mountpoint: "/", Percentage: "70.00"},
+			{Device: "/dev/sdb1", Mountpoint: "/data", Percentage: "85.50"},
+		}},
+		LoadInfo: LoadInfo{Load1: 9, Load5: 6, Load15: 3},
+	}
+}
+
+// TestRuleVariables tests building rule variables from system stats
+func TestRuleVariables(t *testing.T) {
+	config := &Config{Metrics: map[string]MetricConfig{}}
+	vars, err := ruleVariables(config, rulesTestStats())
+	if err != nil {
+		t.Fatalf("ruleVariables() error = %v", err)
+	}
+
+	want := map[string]float64{
+		"cpu": 95, "cores": 4, "physical_cores": 2, "load1": 9,
+		"memory_used": 92, "memory_free": 8, "swap_used": 60,
+		"disk_used_max": 85.5, "disk_free_min": 14.5,
+	}
+	for name, value := range want {
+		if vars[name] != value {
+			t.Errorf("vars[%s] = %f, want %f", name, vars[name], value)
+		}
+	}
+	for _, name := range ruleVariableNames {
+		if _, ok := vars[name]; !ok {
+			t.Errorf("vars missing documented variable %s", name)
+		}
+	}
+}
+
+// TestCheckRules tests raising violations from rule expressions
+func TestCheckRules(t *testing.T) {
+	config := &Config{
+		Rules: []RuleConfig{
+			{Name: "cpu_saturated", Expr: "cpu > 90 && load1 > 2 * cores", Level: "critical",
+				Message: "cpu {{.cpu}}% with load {{.load1}} on {{.cores}} cores", Value: "load1 / cores"},
+			{Name: "memory_pressure", Expr: "memory_free < 10 and swap_used > 50", Level: "warning"},
+			{Name: "disk_full", Expr: "disk_used_max > 95", Level: "critical"},
+			{Name: "broken", Expr: "cpu / 0 > 1", Level: "warning"},
+		},
+	}
+	vars, err := ruleVariables(config, rulesTestStats())
+	if err != nil {
+		t.Fatalf("ruleVariables() error = %v", err)
+	}
+
+	violations := checkRules(config, vars)
+	if len(violations) != 2 {
+		t.Fatalf("checkRules() got %d violations, want 2: %v", len(violations), violations)
+	}
+
+	v := violations[0]
+	if v.Metric != "cpu_saturated" || v.Level != "critical" {
+		t.Errorf("violation = %s/%s, want cpu_saturated/critical", v.Metric, v.Level)
+	}
+	if v.Message != "cpu 95% with load 9 on 4 cores" {
+		t.Errorf("message = %q", v.Message)
+	}
+	if v.Value != 2.25 {
+		t.Errorf("value = %f, want 2.25", v.Value)
+	}
+
+	v = violations[1]
+	if v.Metric != "memory_pressure" || v.Level != "warning" {
+		t.Errorf("violation = %s/%s, want memory_pressure/warning", v.Metric, v.Level)
+	}
+	if v.Message != "rule memory_pressure matched: memory_free < 10 and swap_used > 50" {
+		t.Errorf("default message = %q", v.Message)
+	}
+	if v.Value != 8 {
+		t.Errorf("default value = %f, want 8 (first variable)", v.Value)
+	}
+}
+
+// TestRuleThrottle tests that rules use their own throttle settings
+func TestRuleThrottle(t *testing.T) {
+	config := &Config{
+		Metrics: map[string]MetricConfig{"cpu": {Throttle: ThrottleConfig{MinDurationMinutes: 1}}},
+		Rules: []RuleConfig{
+			{Name: "cpu_saturated", Throttle: ThrottleConfig{MinDurationMinutes: 10, Repeat: true}},
+		},
+	}
+
+	got := config.GetViolationThrottleConfig(ThresholdViolation{Metric: "cpu_saturated", Level: "critical"})
+	if got.MinDurationMinutes != 10 || !got.Repeat {
+		t.Errorf("rule throttle = %+v, want min_duration 10 and repeat", got)
+	}
+	if got := config.GetThrottleConfig("cpu"); got.MinDurationMinutes != 1 {
+		t.Errorf("metric throttle = %+v, want min_duration 1", got)
+	}
+}
+
+// TestLoadConfigRules tests loading and validating the rules section
+func TestLoadConfigRules(t *testing.T) {
+	tests := []struct {
+		name    string
+		yaml    string
+		wantErr bool
+	}{
+		{
+			name: "valid rule",
+			yaml: `
+rules:
+  - name: cpu_saturated
+    expr: "cpu > 90 && load1 > 2 * cores"
+    level: critical
+    message: "cpu {{.cpu}}% load {{.load1}}"
+    throttle:
+      min_duration_minutes: 5
+`,
+		},
+		{name: "unknown field", yaml: "rules:\n  - name: a\n    expr: cpu > 1\n    level: warning\n    severity: high\n", wantErr: true},
+		{name: "unknown variable", yaml: "rules:\n  - name: a\n    expr: iowait > 1\n    level: warning\n", wantErr: true},
+		{name: "syntax error", yaml: "rules:\n  - name: a\n    expr: cpu >\n    level: warning\n", wantErr: true},
+		{name: "bad level", yaml: "rules:\n  - name: a\n    expr: cpu > 1\n    level: info\n", wantErr: true},
+		{name: "builtin name", yaml: "rules:\n  - name: cpu\n    expr: cpu > 1\n    level: warning\n", wantErr: true},
+		{name: "duplicate name", yaml: "rules:\n  - name: a\n    expr: cpu > 1\n    level: warning\n  - name: a\n    expr: cpu > 2\n    level: critical\n", wantErr: true},
+		{name: "bad template variable", yaml: "rules:\n  - name: a\n    expr: cpu > 1\n    level: warning\n    message: \"{{.iowait}}\"\n", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "config.yaml")
+			if err := os.WriteFile(path, []byte(tt.yaml), 0644); err != nil {
+				t.Fatalf("failed to write config: %v", err)
+			}
+
+			config, err := LoadConfig(path)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("LoadConfig() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && (len(config.Rules) != 1 || config.Rules[0].Throttle.MinDurationMinutes != 5) {
+				t.Errorf("LoadConfig() rules = %+v", config.Rules)
+			}
+		})
+	}
+}
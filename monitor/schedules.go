@@ -0,0 +1,196 @@
+package monitor
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"time"
+)
+
+// ThresholdSchedule replaces a metric's thresholds while it is active.
+// A schedule is either a weekday/time range or a cron expression.
+type ThresholdSchedule struct {
+	Name       string             `yaml:"name"`       // Name shown in the status output
+	Days       []string           `yaml:"days"`       // Weekdays the range starts on (e.g., "mon", "sat"); empty means every day
+	Start      string             `yaml:"start"`      // Start time "HH:MM"
+	End        string             `yaml:"end"`        // End time "HH:MM" (exclusive); may be before start to wrap past midnight
+	Cron       string             `yaml:"cron"`       // Alternative to days/start/end: active during every minute the expression matches
+	Timezone   string             `yaml:"timezone"`   // IANA timezone (default: local time)
+	Thresholds map[string]float64 `yaml:"thresholds"` // Thresholds used while active
+}
+
+// parseClock parses "HH:MM" into minutes since midnight
+func parseClock(s string) (int, error) {
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid time '%s' (use HH:MM)", s)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// location returns the schedule's timezone
+func (s ThresholdSchedule) location() (*time.Location, error) {
+	if s.Timezone == "" {
+		return time.Local, nil
+	}
+	return time.LoadLocation(s.Timezone)
+}
+
+// Active reports whether the schedule applies at the given time
+func (s ThresholdSchedule) Active(now time.Time) (bool, error) {
+	loc, err := s.location()
+	if err != nil {
+		return false, fmt.Errorf("invalid timezone '%s': %w", s.Timezone, err)
+	}
+	now = now.In(loc)
+
+	if s.Cron != "" {
+		cron, err := ParseCron(s.Cron)
+		if err != nil {
+			return false, err
+		}
+		return cron.Matches(now), nil
+	}
+
+	start, err := parseClock(s.Start)
+	if err != nil {
+		return false, err
+	}
+	end, err := parseClock(s.End)
+	if err != nil {
+		return false, err
+	}
+
+	minute := now.Hour()*60 + now.Minute()
+	if start <= end {
+		return minute >= start && minute < end && s.onDay(now.Weekday()), nil
+	}
+
+	// The range wraps past midnight: the early part belongs to the previous day's range
+	if minute >= start {
+		return s.onDay(now.Weekday()), nil
+	}
+	if minute < end {
+		return s.onDay((now.Weekday() + 6) % 7), nil
+	}
+	return false, nil
+}
+
+// onDay reports whether the schedule's range starts on a weekday
+func (s ThresholdSchedule) onDay(day time.Weekday) bool {
+	if len(s.Days) == 0 {
+		return true
+	}
+	for _, name := range s.Days {
+		if d, ok := parseWeekday(name); ok && d == day {
+			return true
+		}
+	}
+	return false
+}
+
+// parseWeekday parses a day name such as "mon" or "Monday"
+func parseWeekday(name string) (time.Weekday, bool) {
+	name = strings.ToLower(name)
+	if len(name) < 3 {
+		return 0, false
+	}
+	day, ok := weekdayNames[name[:3]]
+	if !ok || (len(name) > 3 && name != strings.ToLower(time.Weekday(day).String())) {
+		return 0, false
+	}
+	return time.Weekday(day), true
+}
+
+// activeSchedule returns the first schedule of a metric that is active at the given time
+func (mc MetricConfig) activeSchedule(now time.Time) (*ThresholdSchedule, bool) {
+	for i := range mc.Schedules {
+		active, err := mc.Schedules[i].Active(now)
+		if err != nil {
+			log.Printf("Schedule %s: %v", mc.Schedules[i].Name, err)
+			continue
+		}
+		if active {
+			return &mc.Schedules[i], true
+		}
+	}
+	return nil, false
+}
+
+// atTime returns a copy of the config with the thresholds of active schedules applied
+func (c *Config) atTime(now time.Time) *Config {
+	scheduled := *c
+	scheduled.Metrics = make(map[string]MetricConfig, len(c.Metrics))
+	for name, mc := range c.Metrics {
+		if schedule, ok := mc.activeSchedule(now); ok {
+			log.Printf("Schedule %s active for %s", schedule.Name, name)
+			mc.Thresholds = schedule.Thresholds
+		}
+		scheduled.Metrics[name] = mc
+	}
+	return &scheduled
+}
+
+// ActiveSchedules returns the name of the active schedule for each metric that has one
+func ActiveSchedules(config *Config, now time.Time) map[string]string {
+	active := make(map[string]string)
+	for name, mc := range config.Metrics {
+		if !mc.Enabled {
+			continue
+		}
+		if schedule, ok := mc.activeSchedule(now); ok {
+			active[name] = schedule.Name
+		}
+	}
+	return active
+}
+
+// validateSchedule validates a single threshold schedule of a metric
+func validateSchedule(metricName string, index int, schedule ThresholdSchedule) error {
+	where := fmt.Sprintf("metric %s schedule %d", metricName, index)
+	if schedule.Name != "" {
+		where = fmt.Sprintf("metric %s schedule '%s'", metricName, schedule.Name)
+	}
+
+	if schedule.Name == "" {
+		return fmt.Errorf("%s missing 'name' field", where)
+	}
+	if schedule.Thresholds == nil {
+		return fmt.Errorf("%s missing 'thresholds' section", where)
+	}
+	for level, value := range schedule.Thresholds {
+		if value < 0 {
+			return fmt.Errorf("%s threshold %s must be >= 0", where, level)
+		}
+	}
+	if _, err := schedule.location(); err != nil {
+		return fmt.Errorf("%s has invalid timezone '%s': %w", where, schedule.Timezone, err)
+	}
+
+	if schedule.Cron != "" {
+		if schedule.Start != "" || schedule.End != "" || len(schedule.Days) > 0 {
+			return fmt.Errorf("%s must use either 'cron' or 'days'/'start'/'end', not both", where)
+		}
+		if _, err := ParseCron(schedule.Cron); err != nil {
+			return fmt.Errorf("%s: %w", where, err)
+		}
+		return nil
+	}
+
+	if schedule.Start == "" || schedule.End == "" {
+		return fmt.Errorf("%s needs 'start' and 'end' or a 'cron' expression", where)
+	}
+	if _, err := parseClock(schedule.Start); err != nil {
+		return fmt.Errorf("%s: %w", where, err)
+	}
+	if _, err := parseClock(schedule.End); err != nil {
+		return fmt.Errorf("%s: %w", where, err)
+	}
+	for _, day := range schedule.Days {
+		if _, ok := parseWeekday(day); !ok {
+			return fmt.Errorf("%s has invalid day '%s'", where, day)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,138 @@
+package monitor
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// TestScheduleActive tests weekday/time ranges and cron schedules
+func TestScheduleActive(t *testing.T) {
+	// 2024-01-01 is a Monday
+	at := func(day, hour, minute int) time.Time {
+		return time.Date(2024, 1, day, hour, minute, 0, 0, time.UTC)
+	}
+
+	tests := []struct {
+		name     string
+		schedule ThresholdSchedule
+		time     time.Time
+		want     bool
+	}{
+		{name: "inside range", schedule: ThresholdSchedule{Start: "01:00", End: "04:00", Timezone: "UTC"}, time: at(1, 2, 30), want: true},
+		{name: "end is exclusive", schedule: ThresholdSchedule{Start: "01:00", End: "04:00", Timezone: "UTC"}, time: at(1, 4, 0), want: false},
+		{name: "weekday restricted", schedule: ThresholdSchedule{Days: []string{"sat", "sunday"}, Start: "00:00", End: "12:00", Timezone: "UTC"}, time: at(1, 6, 0), want: false},
+		{name: "weekday match", schedule: ThresholdSchedule{Days: []string{"sat", "sunday"}, Start: "00:00", End: "12:00", Timezone: "UTC"}, time: at(7, 6, 0), want: true},
+		{name: "wraps past midnight", schedule: ThresholdSchedule{Days: []string{"fri"}, Start: "22:00", End: "02:00", Timezone: "UTC"}, time: at(6, 1, 0), want: true},
+		{name: "wrap belongs to start day", schedule: ThresholdSchedule{Days: []string{"fri"}, Start: "22:00", End: "02:00", Timezone: "UTC"}, time: at(5, 1, 0), want: false},
+		{name: "timezone", schedule: ThresholdSchedule{Start: "01:00", End: "04:00", Timezone: "America/New_York"}, time: at(1, 7, 0), want: true},
+		{name: "cron", schedule: ThresholdSchedule{Cron: "* 1-3 * * *", Timezone: "UTC"}, time: at(1, 3, 15), want: true},
+		{name: "cron miss", schedule: ThresholdSchedule{Cron: "* 1-3 * * *", Timezone: "UTC"}, time: at(1, 5, 0), want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.schedule.Active(tt.time)
+			if err != nil {
+				t.Fatalf("Active() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Active() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestScheduledCPUThresholds tests that an active schedule replaces the default thresholds
+func TestScheduledCPUThresholds(t *testing.T) {
+	config := &Config{
+		Metrics: map[string]MetricConfig{
+			"cpu": {
+				Enabled:    true,
+				Thresholds: map[string]float64{"warning": 70, "critical": 90},
+				Schedules: []ThresholdSchedule{
+					{Name: "nightly-batch", Start: "01:00", End: "04:00", Timezone: "UTC",
+						Thresholds: map[string]float64{"warning": 99, "critical": 100}},
+				},
+			},
+		},
+	}
+
+	night := time.Date(2024, 1, 1, 2, 0, 0, 0, time.UTC)
+	day := time.Date(2024, 1, 1, 14, 0, 0, 0, time.UTC)
+
+	if v := checkCPUThresholds(config.atTime(night), 95); len(v) != 0 {
+		t.Errorf("checkCPUThresholds() during batch window got %d violations, want 0", len(v))
+	}
+	if v := checkCPUThresholds(config.atTime(day), 95); len(v) != 1 || v[0].Level != "critical" {
+		t.Errorf("checkCPUThresholds() outside batch window = %v, want one critical", v)
+	}
+	if config.Metrics["cpu"].Thresholds["warning"] != 70 {
+		t.Errorf("atTime() modified the original config")
+	}
+
+	if got := ActiveSchedules(config, night); got["cpu"] != "nightly-batch" {
+		t.Errorf("ActiveSchedules() = %v, want cpu: nightly-batch", got)
+	}
+	if got := ActiveSchedules(config, day); len(got) != 0 {
+		t.Errorf("ActiveSchedules() = %v, want none", got)
+	}
+}
+
+// TestLoadConfigSchedules tests loading and validating schedules
+func TestLoadConfigSchedules(t *testing.T) {
+	tests := []struct {
+		name    string
+		yaml    string
+		wantErr bool
+	}{
+		{
+			name: "valid schedules",
+			yaml: `
+metrics:
+  cpu:
+    enabled: true
+    thresholds:
+      warning: 70
+      critical: 90
+    schedules:
+      - name: nightly-batch
+        start: "01:00"
+        end: "04:00"
+        timezone: Europe/Berlin
+        thresholds:
+          warning: 99
+          critical: 100
+      - name: weekend
+        cron: "* * * * sat,sun"
+        thresholds:
+          warning: 90
+          critical: 98
+`,
+		},
+		{name: "missing range", yaml: "metrics:\n  cpu:\n    enabled: true\n    thresholds: {warning: 70}\n    schedules:\n      - name: a\n        thresholds: {warning: 90}\n", wantErr: true},
+		{name: "cron and range", yaml: "metrics:\n  cpu:\n    enabled: true\n    thresholds: {warning: 70}\n    schedules:\n      - name: a\n        cron: \"* * * * *\"\n        start: \"01:00\"\n        end: \"02:00\"\n        thresholds: {warning: 90}\n", wantErr: true},
+		{name: "bad time", yaml: "metrics:\n  cpu:\n    enabled: true\n    thresholds: {warning: 70}\n    schedules:\n      - name: a\n        start: \"25:00\"\n        end: \"02:00\"\n        thresholds: {warning: 90}\n", wantErr: true},
+		{name: "bad timezone", yaml: "metrics:\n  cpu:\n    enabled: true\n    thresholds: {warning: 70}\n    schedules:\n      - name: a\n        start: \"01:00\"\n        end: \"02:00\"\n        timezone: Mars/Olympus\n        thresholds: {warning: 90}\n", wantErr: true},
+		{name: "bad day", yaml: "metrics:\n  cpu:\n    enabled: true\n    thresholds: {warning: 70}\n    schedules:\n      - name: a\n        days: [someday]\n        start: \"01:00\"\n        end: \"02:00\"\n        thresholds: {warning: 90}\n", wantErr: true},
+		{name: "unknown field", yaml: "metrics:\n  cpu:\n    enabled: true\n    thresholds: {warning: 70}\n    schedules:\n      - name: a\n        cron: \"* * * * *\"\n        every: day\n        thresholds: {warning: 90}\n", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "config.yaml")
+			if err := os.WriteFile(path, []byte(tt.yaml), 0644); err != nil {
+				t.Fatalf("failed to write config: %v", err)
+			}
+
+			config, err := LoadConfig(path)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("LoadConfig() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && len(config.Metrics["cpu"].Schedules) != 2 {
+				t.Errorf("LoadConfig() schedules = %+v", config.Metrics["cpu"].Schedules)
+			}
+		})
+	}
+}
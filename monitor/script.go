@@ -0,0 +1,224 @@
+package monitor
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"sort"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// scriptOutputLimit bounds the captured stdout and stderr of a script
+const scriptOutputLimit = 64 * 1024
+
+// ScriptAction executes external script for alert. The script receives the metric,
+// level and message as its last arguments, the violation fields as TFC_* environment
+// variables, and the violation with its stats snapshot as JSON on stdin.
+type ScriptAction struct {
+	Path    string
+	Args    []string
+	Timeout time.Duration
+	WorkDir string            // Working directory (default: the monitor's)
+	Env     map[string]string // Additional environment variables
+
+	ctx context.Context // kills the script when cancelled, see setContext
+}
+
+// ScriptInput is written to the script's stdin as JSON
+type ScriptInput struct {
+	Violation ThresholdViolation `json:"violation"`
+	Host      string             `json:"host"`
+	Time      time.Time          `json:"time"`
+	Stats     *SystemStats       `json:"stats,omitempty"` // snapshot the violation was detected in
+}
+
+// NewScriptAction creates a new script alert action
+func NewScriptAction(config map[string]interface{}) (*ScriptAction, error) {
+	sa := &ScriptAction{
+		Timeout: 30 * time.Second,
+	}
+
+	if path, ok := config["path"].(string); ok {
+		sa.Path = path
+	} else {
+		return nil, fmt.Errorf("script action requires 'path' field")
+	}
+
+	if args, ok := config["args"].([]interface{}); ok {
+		for _, arg := range args {
+			if argStr, ok := arg.(string); ok {
+				sa.Args = append(sa.Args, argStr)
+			}
+		}
+	}
+
+	if timeout, ok := actionNumber(config, "timeout"); ok {
+		sa.Timeout = time.Duration(timeout) * time.Second
+	}
+
+	if workdir, ok := config["workdir"].(string); ok {
+		sa.WorkDir = os.ExpandEnv(workdir)
+	}
+
+	env, err := actionStringMap(config, "env")
+	if err != nil {
+		return nil, err
+	}
+	for name, value := range env {
+		env[name] = os.ExpandEnv(value)
+	}
+	if len(env) > 0 {
+		sa.Env = env
+	}
+
+	return sa, nil
+}
+
+// setContext makes the script get killed when ctx is cancelled
+func (sa *ScriptAction) setContext(ctx context.Context) {
+	sa.ctx = ctx
+}
+
+// Execute executes alert script
+func (sa *ScriptAction) Execute(violation ThresholdViolation) error {
+	args := append(append([]string{}, sa.Args...), violation.Metric, violation.Level, violation.Message)
+
+	hostname, _ := os.Hostname()
+	now := time.Now()
+	input, err := json.Marshal(ScriptInput{
+		Violation: violation,
+		Host:      hostname,
+		Time:      now,
+		Stats:     violation.Stats,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal script input: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(actionContext(sa.ctx), sa.Timeout)
+	defer cancel()
+
+	var stdout, stderr limitedBuffer
+	cmd := exec.CommandContext(ctx, sa.Path, args...)
+	cmd.Dir = sa.WorkDir
+	cmd.Env = append(os.Environ(), sa.environment(violation, hostname, now)...)
+	cmd.Stdin = bytes.NewReader(input)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	// Run the script in its own process group so that a timeout also stops its children
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	cmd.Cancel = func() error {
+		return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+	}
+	cmd.WaitDelay = time.Second
+
+	err = cmd.Run()
+	logScriptOutput(sa.Path, "stdout", stdout.String())
+	logScriptOutput(sa.Path, "stderr", stderr.String())
+
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return fmt.Errorf("script alert timed out after %v", sa.Timeout)
+	}
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return fmt.Errorf("script alert failed: %w: %s", err, lastLine(msg))
+		}
+		return fmt.Errorf("script alert failed: %w", err)
+	}
+	log.Printf("Script alert executed: %s", sa.Path)
+	return nil
+}
+
+// environment returns the TFC_* variables describing a violation, after the configured ones
+func (sa *ScriptAction) environment(violation ThresholdViolation, hostname string, now time.Time) []string {
+	var env []string
+	for name, value := range sa.Env {
+		env = append(env, name+"="+value)
+	}
+	sort.Strings(env)
+
+	env = append(env,
+		"TFC_METRIC="+violation.Metric,
+		"TFC_LEVEL="+violation.Level,
+		"TFC_MESSAGE="+violation.Message,
+		"TFC_VALUE="+strconv.FormatFloat(violation.Value, 'f', -1, 64),
+		"TFC_THRESHOLD="+strconv.FormatFloat(violation.Threshold, 'f', -1, 64),
+		"TFC_DEVICE="+violation.Device,
+		"TFC_MOUNTPOINT="+violation.Mountpoint,
+		"TFC_HOST="+hostname,
+		"TFC_TIME="+now.Format(time.RFC3339),
+	)
+
+	labels := make([]string, 0, len(violation.Labels))
+	for name, value := range violation.Labels {
+		labels = append(labels, "TFC_LABEL_"+envName(name)+"="+value)
+	}
+	sort.Strings(labels)
+	return append(env, labels...)
+}
+
+// envName converts a label name to an environment variable name
+func envName(name string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z':
+			return r - 'a' + 'A'
+		case r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+			return r
+		default:
+			return '_'
+		}
+	}, name)
+}
+
+// logScriptOutput logs the lines a script wrote to a stream
+func logScriptOutput(path string, stream string, output string) {
+	for _, line := range strings.Split(strings.TrimRight(output, "\n"), "\n") {
+		if line != "" {
+			log.Printf("Script %s %s: %s", path, stream, line)
+		}
+	}
+}
+
+// lastLine returns the last line of s
+func lastLine(s string) string {
+	if i := strings.LastIndexByte(s, '\n'); i >= 0 {
+		return s[i+1:]
+	}
+	return s
+}
+
+// limitedBuffer keeps the first scriptOutputLimit bytes written to it and discards the rest
+type limitedBuffer struct {
+	bytes.Buffer
+	truncated bool
+}
+
+// Write implements io.Writer
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	if room := scriptOutputLimit - b.Len(); room < len(p) {
+		b.truncated = true
+		if room > 0 {
+			b.Buffer.Write(p[:room])
+		}
+		return len(p), nil
+	}
+	return b.Buffer.Write(p)
+}
+
+// String returns the captured output, marked if it was truncated
+func (b *limitedBuffer) String() string {
+	if b.truncated {
+		return b.Buffer.String() + "\n[output truncated]"
+	}
+	return b.Buffer.String()
+}
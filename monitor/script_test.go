@@ -0,0 +1,162 @@
+package monitor
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// writeScript writes an executable shell script to dir
+func writeScript(t *testing.T, dir string, body string) string {
+	t.Helper()
+	path := filepath.Join(dir, "alert.sh")
+	if err := os.WriteFile(path, []byte("#!/bin/sh\n"+body), 0755); err != nil {
+		t.Fatalf("failed to create test script: %v", err)
+	}
+	return path
+}
+
+// TestScriptActionContract tests the arguments, environment, stdin and working directory of scripts
+func TestScriptActionContract(t *testing.T) {
+	dir := t.TempDir()
+	workdir := t.TempDir()
+	path := writeScript(t, dir, `echo "$@" > args.txt
+env | grep -E '^(TFC_|TEAM_CHANNEL=)' | sort > env.txt
+cat > input.json
+echo "notified on-call"
+`)
+
+	t.Setenv("CHANNEL", "storage-alerts")
+	action, err := NewScriptAction(map[string]interface{}{
+		"type":    "script",
+		"path":    path,
+		"args":    []interface{}{"--notify"},
+		"workdir": workdir,
+		"env":     map[interface{}]interface{}{"TEAM_CHANNEL": "#${CHANNEL}"},
+	})
+	if err != nil {
+		t.Fatalf("NewScriptAction() error = %v", err)
+	}
+
+	violation := ThresholdViolation{
+		Metric: "disk", Level: "critical", Message: "Disk /var is 95.5% full",
+		Value: 95.5, Threshold: 90, Device: "/dev/sdb1", Mountpoint: "/var",
+		Labels: map[string]string{"team": "storage", "service-tier": "gold"},
+		Stats:  &SystemStats{},
+	}
+	if err := action.Execute(violation); err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+
+	args, _ := os.ReadFile(filepath.Join(workdir, "args.txt"))
+	if got := strings.TrimSpace(string(args)); got != "--notify disk critical Disk /var is 95.5% full" {
+		t.Errorf("args = %q", got)
+	}
+
+	env, _ := os.ReadFile(filepath.Join(workdir, "env.txt"))
+	for _, want := range []string{
+		"TEAM_CHANNEL=#storage-alerts",
+		"TFC_METRIC=disk",
+		"TFC_LEVEL=critical",
+		"TFC_MESSAGE=Disk /var is 95.5% full",
+		"TFC_VALUE=95.5",
+		"TFC_THRESHOLD=90",
+		"TFC_DEVICE=/dev/sdb1",
+		"TFC_MOUNTPOINT=/var",
+		"TFC_LABEL_TEAM=storage",
+		"TFC_LABEL_SERVICE_TIER=gold",
+		"TFC_HOST=",
+		"TFC_TIME=",
+	} {
+		if !strings.Contains(string(env), want) {
+			t.Errorf("environment missing %q:\n%s", want, env)
+		}
+	}
+
+	data, _ := os.ReadFile(filepath.Join(workdir, "input.json"))
+	var input ScriptInput
+	if err := json.Unmarshal(data, &input); err != nil {
+		t.Fatalf("stdin is not valid JSON: %v\n%s", err, data)
+	}
+	if input.Violation.Mountpoint != "/var" || input.Violation.Threshold != 90 || input.Stats == nil || input.Host == "" {
+		t.Errorf("stdin = %s", data)
+	}
+}
+
+// TestScriptActionFailure tests that stderr is reported when a script fails
+func TestScriptActionFailure(t *testing.T) {
+	path := writeScript(t, t.TempDir(), "echo 'starting' >&2\necho 'pager service unavailable' >&2\nexit 3\n")
+	action := &ScriptAction{Path: path, Timeout: 5 * time.Second}
+
+	err := action.Execute(ThresholdViolation{Metric: "cpu", Level: "warning"})
+	if err == nil || !strings.Contains(err.Error(), "exit status 3") || !strings.Contains(err.Error(), "pager service unavailable") {
+		t.Errorf("Execute() error = %v, want exit status and last stderr line", err)
+	}
+}
+
+// TestScriptActionTimeoutKillsProcessGroup tests that children of a timed out script are stopped
+func TestScriptActionTimeoutKillsProcessGroup(t *testing.T) {
+	dir := t.TempDir()
+	pidFile := filepath.Join(dir, "child.pid")
+	path := writeScript(t, dir, "sleep 30 &\necho $! > "+pidFile+"\nwait\n")
+	action := &ScriptAction{Path: path, Timeout: 500 * time.Millisecond}
+
+	start := time.Now()
+	err := action.Execute(ThresholdViolation{Metric: "cpu", Level: "critical"})
+	if err == nil || !strings.Contains(err.Error(), "timed out") {
+		t.Fatalf("Execute() error = %v, want timeout", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("Execute() took %v, want it to return after the timeout", elapsed)
+	}
+
+	data, err := os.ReadFile(pidFile)
+	if err != nil {
+		t.Fatalf("failed to read child pid: %v", err)
+	}
+	var pid int
+	if _, err := fmt.Sscan(strings.TrimSpace(string(data)), &pid); err != nil {
+		t.Fatal(err)
+	}
+	deadline := time.Now().Add(2 * time.Second)
+	for processRunning(pid) {
+		if time.Now().After(deadline) {
+			syscall.Kill(pid, syscall.SIGKILL)
+			t.Fatalf("child process %d still running after timeout", pid)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
+
+// processRunning reports whether a process exists and is not a zombie waiting to be reaped
+func processRunning(pid int) bool {
+	if syscall.Kill(pid, 0) != nil {
+		return false
+	}
+	stat, err := os.ReadFile(fmt.Sprintf("/proc/%d/stat", pid))
+	if err != nil {
+		return !os.IsNotExist(err)
+	}
+	fields := strings.Fields(string(stat[bytes.LastIndexByte(stat, ')')+1:]))
+	return len(fields) == 0 || fields[0] != "Z"
+}
+
+// TestLimitedBuffer tests that captured output is bounded
+func TestLimitedBuffer(t *testing.T) {
+	var b limitedBuffer
+	chunk := strings.Repeat("x", 1000)
+	for i := 0; i < 100; i++ {
+		if n, err := b.Write([]byte(chunk)); n != len(chunk) || err != nil {
+			t.Fatalf("Write() = %d, %v", n, err)
+		}
+	}
+	if b.Len() != scriptOutputLimit || !strings.HasSuffix(b.String(), "[output truncated]") {
+		t.Errorf("buffer length = %d, truncated = %v", b.Len(), b.truncated)
+	}
+}
@@ -0,0 +1,77 @@
+package monitor
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Headers carrying the signature of webhook deliveries
+const (
+	SignatureHeader = "X-TFC-Signature"
+	TimestampHeader = "X-TFC-Timestamp"
+)
+
+// DefaultSignatureTolerance is the maximum age of a signed request accepted by VerifyWebhookRequest
+const DefaultSignatureTolerance = 5 * time.Minute
+
+// SignWebhook returns the signature of a webhook body sent at timestamp (Unix seconds).
+// The signature is "sha256=" followed by the hex HMAC-SHA256 of "<timestamp>.<body>".
+func SignWebhook(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	fmt.Fprintf(mac, "%d.", timestamp)
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// VerifyWebhook checks a signature and rejects timestamps more than tolerance away from now,
+// so captured requests cannot be replayed later
+func VerifyWebhook(secret string, signature string, timestamp string, body []byte, tolerance time.Duration, now time.Time) error {
+	ts, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid signature timestamp '%s'", timestamp)
+	}
+
+	age := now.Sub(time.Unix(ts, 0))
+	if age < 0 {
+		age = -age
+	}
+	if age > tolerance {
+		return fmt.Errorf("signature timestamp is %v old, tolerance is %v", age.Round(time.Second), tolerance)
+	}
+
+	if !strings.HasPrefix(signature, "sha256=") {
+		return fmt.Errorf("unsupported signature format")
+	}
+	if !hmac.Equal([]byte(signature), []byte(SignWebhook(secret, ts, body))) {
+		return fmt.Errorf("signature mismatch")
+	}
+	return nil
+}
+
+// VerifyWebhookRequest verifies a signed webhook request and returns its body.
+// The request body is replaced so handlers can read it again.
+func VerifyWebhookRequest(r *http.Request, secret string, tolerance time.Duration) ([]byte, error) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read request body: %w", err)
+	}
+	r.Body.Close()
+	r.Body = io.NopCloser(bytes.NewReader(body))
+
+	signature := r.Header.Get(SignatureHeader)
+	if signature == "" {
+		return nil, fmt.Errorf("missing %s header", SignatureHeader)
+	}
+	if err := VerifyWebhook(secret, signature, r.Header.Get(TimestampHeader), body, tolerance, time.Now()); err != nil {
+		return nil, err
+	}
+	return body, nil
+}
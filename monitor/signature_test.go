@@ -0,0 +1,97 @@
+package monitor
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestSignWebhook tests the signature against a known HMAC-SHA256 value
+func TestSignWebhook(t *testing.T) {
+	// echo -n '1700000000.{"a":1}' | openssl dgst -sha256 -hmac secret
+	want := "sha256=49f24e537407743fa4a0242bb63b94b9a47ee99cbbe071ccd8a22550ae411686"
+	got := SignWebhook("secret", 1700000000, []byte(`{"a":1}`))
+	if got != want {
+		t.Errorf("SignWebhook() = %s, want %s", got, want)
+	}
+	if got == SignWebhook("secret", 1700000001, []byte(`{"a":1}`)) {
+		t.Error("signature should depend on the timestamp")
+	}
+}
+
+// TestVerifyWebhook tests signature and replay checks
+func TestVerifyWebhook(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	body := []byte(`{"metric":"cpu"}`)
+	signature := SignWebhook("secret", now.Unix(), body)
+
+	tests := []struct {
+		name      string
+		secret    string
+		signature string
+		timestamp string
+		body      []byte
+		now       time.Time
+		wantErr   string
+	}{
+		{"valid", "secret", signature, "1700000000", body, now, ""},
+		{"valid within tolerance", "secret", signature, "1700000000", body, now.Add(4 * time.Minute), ""},
+		{"replayed", "secret", signature, "1700000000", body, now.Add(10 * time.Minute), "tolerance"},
+		{"wrong secret", "other", signature, "1700000000", body, now, "mismatch"},
+		{"tampered body", "secret", signature, "1700000000", []byte(`{"metric":"disk"}`), now, "mismatch"},
+		{"shifted timestamp", "secret", signature, "1700000001", body, now, "mismatch"},
+		{"bad timestamp", "secret", signature, "yesterday", body, now, "timestamp"},
+		{"bad format", "secret", "md5=abc", "1700000000", body, now, "format"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := VerifyWebhook(tt.secret, tt.signature, tt.timestamp, tt.body, DefaultSignatureTolerance, tt.now)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("VerifyWebhook() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("VerifyWebhook() error = %v, want error containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// TestWebhookSignedDelivery tests that a webhook with a secret can be verified by a receiver
+func TestWebhookSignedDelivery(t *testing.T) {
+	var verifyErr error
+	var received []byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received, verifyErr = VerifyWebhookRequest(r, "s3cret", DefaultSignatureTolerance)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	action, err := NewWebhookAction(map[string]interface{}{"url": server.URL, "secret": "s3cret"})
+	if err != nil {
+		t.Fatalf("NewWebhookAction() error = %v", err)
+	}
+	if err := action.Execute(ThresholdViolation{Metric: "cpu", Level: "warning", Message: "high", Value: 90}); err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+
+	if verifyErr != nil {
+		t.Errorf("VerifyWebhookRequest() error = %v", verifyErr)
+	}
+	if !strings.Contains(string(received), `"metric":"cpu"`) {
+		t.Errorf("received body = %s", received)
+	}
+}
+
+// TestVerifyWebhookRequestUnsigned tests rejection of unsigned requests
+func TestVerifyWebhookRequestUnsigned(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{}`))
+	if _, err := VerifyWebhookRequest(req, "s3cret", DefaultSignatureTolerance); err == nil {
+		t.Error("expected error for unsigned request")
+	}
+}
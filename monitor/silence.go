@@ -0,0 +1,301 @@
+package monitor
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Errors returned for unknown silences and violations
+var (
+	ErrSilenceNotFound   = errors.New("silence not found")
+	ErrViolationNotFound = errors.New("no active violation")
+)
+
+// silenceRetention is how long expired silences are kept for listing
+const silenceRetention = 24 * time.Hour
+
+// APIConfig protects the HTTP endpoints that acknowledge and silence alerts
+type APIConfig struct {
+	Token string `yaml:"token"` // Bearer token required by write endpoints; environment variables are expanded
+}
+
+// BearerToken returns the configured token with environment variables expanded, or ""
+// if none is set
+func (ac APIConfig) BearerToken() string {
+	return os.ExpandEnv(ac.Token)
+}
+
+// Silence suppresses alerts of matching violations for a time. Empty matchers match
+// any violation; at least one matcher must be set.
+type Silence struct {
+	ID        string            `json:"id"`
+	Metric    string            `json:"metric,omitempty"`
+	Level     string            `json:"level,omitempty"`
+	Resource  string            `json:"resource,omitempty"` // mountpoint or device
+	Labels    map[string]string `json:"labels,omitempty"`
+	Author    string            `json:"author"`
+	Comment   string            `json:"comment"`
+	StartsAt  time.Time         `json:"starts_at"`
+	EndsAt    time.Time         `json:"ends_at"`
+	CreatedAt time.Time         `json:"created_at"`
+	Status    string            `json:"status,omitempty"` // "pending", "active" or "expired", set when listed
+}
+
+// Acknowledgement stops repeat alerts and escalation of an active violation until it resolves
+type Acknowledgement struct {
+	Key     string    `json:"key"` // state key of the violation
+	Author  string    `json:"author"`
+	Comment string    `json:"comment,omitempty"`
+	Time    time.Time `json:"time"`
+}
+
+// silenceStore holds the silences and acknowledgements persisted in the state file
+type silenceStore struct {
+	Silences []Silence                  `json:"silences"`
+	Acks     map[string]Acknowledgement `json:"acks"`
+}
+
+// silenceMu serializes silence access between concurrent checks and API requests
+var silenceMu sync.Mutex
+
+// status returns the status of the silence at now
+func (s Silence) status(now time.Time) string {
+	switch {
+	case now.Before(s.StartsAt):
+		return "pending"
+	case now.Before(s.EndsAt):
+		return "active"
+	default:
+		return "expired"
+	}
+}
+
+// matches reports whether the silence applies to a violation
+func (s Silence) matches(v ThresholdViolation) bool {
+	if s.Metric != "" && s.Metric != v.Metric {
+		return false
+	}
+	if s.Level != "" && s.Level != v.Level {
+		return false
+	}
+	if s.Resource != "" && s.Resource != v.Mountpoint && s.Resource != v.Device {
+		return false
+	}
+	labels := v.routingLabels()
+	for name, value := range s.Labels {
+		if labels[name] != value {
+			return false
+		}
+	}
+	return true
+}
+
+// validate checks a new silence
+func (s Silence) validate() error {
+	if s.Metric == "" && s.Level == "" && s.Resource == "" && len(s.Labels) == 0 {
+		return fmt.Errorf("silence needs at least one of metric, level, resource or labels")
+	}
+	if s.Level != "" && s.Level != "warning" && s.Level != "critical" {
+		return fmt.Errorf("invalid silence level '%s'", s.Level)
+	}
+	if s.Author == "" {
+		return fmt.Errorf("silence missing author")
+	}
+	if s.Comment == "" {
+		return fmt.Errorf("silence missing comment")
+	}
+	if !s.EndsAt.After(s.StartsAt) {
+		return fmt.Errorf("silence must end after it starts")
+	}
+	return nil
+}
+
+// AddSilence validates and stores a silence. StartsAt defaults to now.
+func (sm *StateManager) AddSilence(silence Silence) (*Silence, error) {
+	silenceMu.Lock()
+	defer silenceMu.Unlock()
+
+	now := time.Now()
+	if silence.StartsAt.IsZero() {
+		silence.StartsAt = now
+	}
+	if !silence.EndsAt.After(now) {
+		return nil, fmt.Errorf("silence must end in the future")
+	}
+	if err := silence.validate(); err != nil {
+		return nil, err
+	}
+
+	id := make([]byte, 8)
+	rand.Read(id)
+	silence.ID = hex.EncodeToString(id)
+	silence.CreatedAt = now
+	silence.Status = ""
+
+	if err := sm.updateStateFile(func(file *stateFile) bool {
+		file.Silences = append(file.Silences, silence)
+		return true
+	}); err != nil {
+		return nil, err
+	}
+
+	log.Printf("Silence %s created by %s until %s", silence.ID, silence.Author, silence.EndsAt.Format(time.RFC3339))
+	silence.Status = silence.status(now)
+	return &silence, nil
+}
+
+// ExpireSilence ends a silence now
+func (sm *StateManager) ExpireSilence(id string) error {
+	silenceMu.Lock()
+	defer silenceMu.Unlock()
+
+	now := time.Now()
+	var expireErr error
+	if err := sm.updateStateFile(func(file *stateFile) bool {
+		for i := range file.Silences {
+			silence := &file.Silences[i]
+			if silence.ID != id {
+				continue
+			}
+			if silence.status(now) == "expired" {
+				expireErr = fmt.Errorf("silence %s already expired", id)
+				return false
+			}
+			silence.EndsAt = now
+			if silence.StartsAt.After(now) {
+				silence.StartsAt = now
+			}
+			return true
+		}
+		expireErr = fmt.Errorf("%w: %s", ErrSilenceNotFound, id)
+		return false
+	}); err != nil {
+		return err
+	}
+	if expireErr != nil {
+		return expireErr
+	}
+	log.Printf("Silence %s expired", id)
+	return nil
+}
+
+// Silences returns all silences that are pending, active or recently expired
+func (sm *StateManager) Silences() ([]Silence, error) {
+	silenceMu.Lock()
+	defer silenceMu.Unlock()
+
+	file, err := readStateFile(sm.StateFile)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	silences := make([]Silence, len(file.Silences))
+	for i, silence := range file.Silences {
+		silence.Status = silence.status(now)
+		silences[i] = silence
+	}
+	return silences, nil
+}
+
+// Acknowledge acknowledges an active violation
+func (sm *StateManager) Acknowledge(metric string, level string, mountpoint string, author string, comment string) (*Acknowledgement, error) {
+	silenceMu.Lock()
+	defer silenceMu.Unlock()
+
+	if author == "" {
+		return nil, fmt.Errorf("acknowledgement missing author")
+	}
+	key := stateKey(metric, level, mountpoint)
+	if _, ok := sm.States[key]; !ok {
+		return nil, fmt.Errorf("%w %s", ErrViolationNotFound, key)
+	}
+
+	ack := Acknowledgement{Key: key, Author: author, Comment: comment, Time: time.Now()}
+	if err := sm.updateStateFile(func(file *stateFile) bool {
+		file.Acks[key] = ack
+		return true
+	}); err != nil {
+		return nil, err
+	}
+
+	log.Printf("Violation %s acknowledged by %s", key, author)
+	return &ack, nil
+}
+
+// Acknowledgements returns the acknowledgements of active violations, by key
+func (sm *StateManager) Acknowledgements() ([]Acknowledgement, error) {
+	silenceMu.Lock()
+	defer silenceMu.Unlock()
+
+	file, err := readStateFile(sm.StateFile)
+	if err != nil {
+		return nil, err
+	}
+	acks := make([]Acknowledgement, 0, len(file.Acks))
+	for _, ack := range file.Acks {
+		acks = append(acks, ack)
+	}
+	sort.Slice(acks, func(i, j int) bool { return acks[i].Key < acks[j].Key })
+	return acks, nil
+}
+
+// refreshSilences reloads silences and acknowledgements before a check, dropping
+// acknowledgements of resolved violations and silences that expired long ago
+func (sm *StateManager) refreshSilences(now time.Time) error {
+	if sm.StateFile == "" {
+		return nil
+	}
+
+	silenceMu.Lock()
+	defer silenceMu.Unlock()
+
+	var store silenceStore
+	if err := sm.updateStateFile(func(file *stateFile) bool {
+		changed := false
+		for key := range file.Acks {
+			if _, ok := sm.States[key]; !ok {
+				delete(file.Acks, key)
+				changed = true
+			}
+		}
+		var kept []Silence
+		for _, silence := range file.Silences {
+			if now.Sub(silence.EndsAt) > silenceRetention {
+				changed = true
+				continue
+			}
+			kept = append(kept, silence)
+		}
+		file.Silences = kept
+		store = file.silenceStore
+		return changed
+	}); err != nil {
+		return err
+	}
+	sm.silences = &store
+	return nil
+}
+
+// suppressed returns why alerts of a violation are suppressed by an active silence
+// or an acknowledgement, or "" if they are not
+func (sm *StateManager) suppressed(v ThresholdViolation, now time.Time) string {
+	if sm.silences == nil {
+		return ""
+	}
+	if ack, ok := sm.silences.Acks[v.StateKey()]; ok {
+		return fmt.Sprintf("acknowledged by %s", ack.Author)
+	}
+	for _, silence := range sm.silences.Silences {
+		if silence.status(now) == "active" && silence.matches(v) {
+			return fmt.Sprintf("silenced by %s (%s)", silence.ID, silence.Author)
+		}
+	}
+	return ""
+}
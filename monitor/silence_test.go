@@ -0,0 +1,239 @@
+package monitor
+
+import (
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newSilenceTestManager returns a state manager with its file in a temporary directory
+func newSilenceTestManager(t *testing.T) *StateManager {
+	dir := t.TempDir()
+	return &StateManager{
+		StateFile: filepath.Join(dir, "state.json"),
+		States:    make(map[string]*ViolationState),
+	}
+}
+
+// TestSilenceMatches tests the silence matchers
+func TestSilenceMatches(t *testing.T) {
+	violation := ThresholdViolation{
+		Metric: "disk", Level: "warning", Device: "/dev/sdb1", Mountpoint: "/var",
+		Labels: map[string]string{"team": "storage"},
+	}
+	tests := []struct {
+		name    string
+		silence Silence
+		want    bool
+	}{
+		{"metric", Silence{Metric: "disk"}, true},
+		{"other metric", Silence{Metric: "cpu"}, false},
+		{"level", Silence{Metric: "disk", Level: "critical"}, false},
+		{"mountpoint", Silence{Resource: "/var"}, true},
+		{"device", Silence{Resource: "/dev/sdb1"}, true},
+		{"other resource", Silence{Resource: "/"}, false},
+		{"label", Silence{Labels: map[string]string{"team": "storage"}}, true},
+		{"other label", Silence{Labels: map[string]string{"team": "app"}}, false},
+		{"builtin label", Silence{Labels: map[string]string{"level": "warning"}}, true},
+	}
+	for _, tt := range tests {
+		if got := tt.silence.matches(violation); got != tt.want {
+			t.Errorf("%s: matches() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+// TestAddSilenceValidation tests that incomplete silences are rejected
+func TestAddSilenceValidation(t *testing.T) {
+	sm := newSilenceTestManager(t)
+	end := time.Now().Add(time.Hour)
+
+	tests := []struct {
+		name    string
+		silence Silence
+		wantErr string
+	}{
+		{"no matchers", Silence{Author: "alice", Comment: "x", EndsAt: end}, "at least one"},
+		{"no author", Silence{Metric: "cpu", Comment: "x", EndsAt: end}, "missing author"},
+		{"no comment", Silence{Metric: "cpu", Author: "alice", EndsAt: end}, "missing comment"},
+		{"no end", Silence{Metric: "cpu", Author: "alice", Comment: "x"}, "end in the future"},
+		{"invalid level", Silence{Level: "info", Author: "alice", Comment: "x", EndsAt: end}, "invalid silence level"},
+	}
+	for _, tt := range tests {
+		if _, err := sm.AddSilence(tt.silence); err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("%s: AddSilence() error = %v, want error containing %q", tt.name, err, tt.wantErr)
+		}
+	}
+}
+
+// TestSilenceLifecycle tests creating, listing and expiring silences
+func TestSilenceLifecycle(t *testing.T) {
+	sm := newSilenceTestManager(t)
+
+	silence, err := sm.AddSilence(Silence{
+		Metric: "disk", Resource: "/var", Author: "alice", Comment: "resizing volume",
+		EndsAt: time.Now().Add(2 * time.Hour),
+	})
+	if err != nil {
+		t.Fatalf("AddSilence() error = %v", err)
+	}
+	if silence.ID == "" || silence.Status != "active" {
+		t.Errorf("AddSilence() = %+v, want active silence with ID", silence)
+	}
+
+	silences, err := sm.Silences()
+	if err != nil || len(silences) != 1 || silences[0].ID != silence.ID || silences[0].Status != "active" {
+		t.Fatalf("Silences() = %+v, %v", silences, err)
+	}
+
+	if err := sm.ExpireSilence(silence.ID); err != nil {
+		t.Fatalf("ExpireSilence() error = %v", err)
+	}
+	silences, _ = sm.Silences()
+	if silences[0].Status != "expired" {
+		t.Errorf("status after expiry = %s, want expired", silences[0].Status)
+	}
+	if err := sm.ExpireSilence(silence.ID); err == nil {
+		t.Error("ExpireSilence() of an expired silence expected error")
+	}
+	if err := sm.ExpireSilence("unknown"); !errors.Is(err, ErrSilenceNotFound) {
+		t.Errorf("ExpireSilence() error = %v, want ErrSilenceNotFound", err)
+	}
+
+	// Expired silences are dropped after the retention period
+	if err := sm.refreshSilences(time.Now().Add(silenceRetention + time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+	if silences, _ := sm.Silences(); len(silences) != 0 {
+		t.Errorf("Silences() after retention = %+v, want none", silences)
+	}
+}
+
+// TestApplyThrottlingHonorsSilences tests that silenced violations do not alert
+func TestApplyThrottlingHonorsSilences(t *testing.T) {
+	sm := newSilenceTestManager(t)
+	config := &Config{}
+
+	if _, err := sm.AddSilence(Silence{
+		Resource: "/var", Author: "alice", Comment: "maintenance", EndsAt: time.Now().Add(time.Hour),
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err := sm.refreshSilences(time.Now()); err != nil {
+		t.Fatal(err)
+	}
+
+	violations := []ThresholdViolation{
+		{Metric: "disk", Level: "warning", Mountpoint: "/"},
+		{Metric: "disk", Level: "warning", Mountpoint: "/var"},
+	}
+	throttled, err := applyThrottling(config, violations, sm)
+	if err != nil {
+		t.Fatalf("applyThrottling() error = %v", err)
+	}
+	if len(throttled) != 1 || throttled[0].Mountpoint != "/" {
+		t.Errorf("applyThrottling() = %v, want only the / violation", throttled)
+	}
+
+	// The silenced violation is still tracked, but not marked as alerted
+	state, ok := sm.States[violations[1].StateKey()]
+	if !ok || state.HasAlerted {
+		t.Errorf("silenced state = %+v, want tracked and not alerted", state)
+	}
+}
+
+// TestAcknowledge tests that acknowledged violations stop alerting and escalating until they resolve
+func TestAcknowledge(t *testing.T) {
+	sm := newSilenceTestManager(t)
+	config := &Config{
+		Metrics: map[string]MetricConfig{
+			"cpu": {Throttle: ThrottleConfig{Repeat: true}},
+		},
+		Alerts: map[string]AlertLevel{
+			"critical": {Escalation: []EscalationStep{{After: "0s", Actions: []map[string]interface{}{{"type": "stdout"}}}}},
+		},
+	}
+	violation := ThresholdViolation{Metric: "cpu", Level: "critical", Value: 99}
+
+	if _, err := sm.Acknowledge("cpu", "critical", "", "alice", "looking into it"); !errors.Is(err, ErrViolationNotFound) {
+		t.Errorf("Acknowledge() of inactive violation error = %v, want ErrViolationNotFound", err)
+	}
+
+	if throttled, _ := applyThrottling(config, []ThresholdViolation{violation}, sm); len(throttled) != 1 {
+		t.Fatalf("applyThrottling() = %v, want the violation", throttled)
+	}
+
+	ack, err := sm.Acknowledge("cpu", "critical", "", "alice", "looking into it")
+	if err != nil {
+		t.Fatalf("Acknowledge() error = %v", err)
+	}
+	if ack.Key != "cpu_critical" || ack.Author != "alice" {
+		t.Errorf("Acknowledge() = %+v", ack)
+	}
+	if _, err := sm.Acknowledge("cpu", "critical", "", "", ""); err == nil {
+		t.Error("Acknowledge() without author expected error")
+	}
+
+	if err := sm.refreshSilences(time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	if throttled, _ := applyThrottling(config, []ThresholdViolation{violation}, sm); len(throttled) != 0 {
+		t.Errorf("applyThrottling() = %v, want acknowledged violation suppressed", throttled)
+	}
+	if err := collectEscalations(config, []ThresholdViolation{violation}, sm, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	if escalations := sm.TakeEscalations(); len(escalations) != 0 {
+		t.Errorf("escalations = %+v, want none for acknowledged violation", escalations)
+	}
+
+	// Resolving the violation drops the acknowledgement
+	if err := clearResolvedViolations(nil, sm); err != nil {
+		t.Fatal(err)
+	}
+	if err := sm.refreshSilences(time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	if acks, _ := sm.Acknowledgements(); len(acks) != 0 {
+		t.Errorf("Acknowledgements() = %+v, want none after resolution", acks)
+	}
+}
+
+// TestSilencesSharedStateFile tests that silences and violation states are kept in the
+// state file without overwriting each other
+func TestSilencesSharedStateFile(t *testing.T) {
+	server := newSilenceTestManager(t)
+	server.GetOrCreate("disk", "warning")
+	if err := server.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	// A CLI command adds a silence while the server holds its states in memory
+	cli := &StateManager{StateFile: server.StateFile}
+	if err := cli.load(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cli.AddSilence(Silence{
+		Metric: "disk", Author: "alice", Comment: "resizing volume", EndsAt: time.Now().Add(time.Hour),
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	server.GetOrCreate("cpu", "critical")
+	if err := server.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	reloaded := &StateManager{StateFile: server.StateFile}
+	if err := reloaded.load(); err != nil {
+		t.Fatal(err)
+	}
+	if len(reloaded.States) != 2 {
+		t.Errorf("reloaded states = %v, want 2", reloaded.States)
+	}
+	if silences, err := reloaded.Silences(); err != nil || len(silences) != 1 {
+		t.Errorf("Silences() = %+v, %v, want the CLI silence", silences, err)
+	}
+}
@@ -0,0 +1,468 @@
+package monitor
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/md5"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"log"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultSNMPTrapOID is the OID of TFC-SYSTEM-MONITOR-MIB (mibs/TFC-SYSTEM-MONITOR-MIB.txt)
+const DefaultSNMPTrapOID = "1.3.6.1.4.1.99999.1"
+
+// OIDs of the standard varbinds that start every SNMPv2 trap
+const (
+	oidSysUpTime   = "1.3.6.1.2.1.1.3.0"
+	oidSnmpTrapOID = "1.3.6.1.6.3.1.1.4.1.0"
+)
+
+// BER tags used in SNMP messages
+const (
+	berInteger     = 0x02
+	berOctetString = 0x04
+	berOID         = 0x06
+	berSequence    = 0x30
+	berTimeTicks   = 0x43
+	berTrapV2      = 0xa7
+)
+
+// snmpEngineEpoch is the start of the SNMPv3 engine time. Deriving the engine time from
+// the clock keeps it increasing across runs, as receivers require.
+var snmpEngineEpoch = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// snmpStart is the reference of the sysUpTime varbind
+var snmpStart = time.Now()
+
+// SNMPTrapAction sends alerts as SNMP traps defined by TFC-SYSTEM-MONITOR-MIB
+type SNMPTrapAction struct {
+	Host      string
+	Port      int
+	Version   string // "2c" or "3"
+	Community string // SNMPv2c community
+	OID       string // Base OID of the MIB
+	Timeout   time.Duration
+
+	// SNMPv3 user-based security
+	Username     string
+	AuthProtocol string // "md5" or "sha"; authentication is off without AuthPassword
+	AuthPassword string
+	PrivProtocol string // "aes"; encryption is off without PrivPassword
+	PrivPassword string
+	EngineID     []byte
+
+	ctx context.Context // cancels the connection, see setContext
+}
+
+// NewSNMPTrapAction creates a new SNMP trap alert action
+func NewSNMPTrapAction(config map[string]interface{}) (*SNMPTrapAction, error) {
+	sa := &SNMPTrapAction{
+		Port:         162,
+		Version:      "2c",
+		Community:    "public",
+		OID:          DefaultSNMPTrapOID,
+		Timeout:      5 * time.Second,
+		AuthProtocol: "sha",
+		PrivProtocol: "aes",
+	}
+
+	host, ok := config["host"].(string)
+	if !ok || host == "" {
+		return nil, fmt.Errorf("snmptrap action requires 'host' field")
+	}
+	sa.Host = host
+	if port, ok := actionNumber(config, "port"); ok {
+		if port < 1 || port > 65535 {
+			return nil, fmt.Errorf("snmptrap action 'port' must be between 1 and 65535")
+		}
+		sa.Port = int(port)
+	}
+
+	switch version := config["version"].(type) {
+	case nil:
+	case string:
+		sa.Version = version
+	default:
+		if n, ok := actionNumber(config, "version"); ok {
+			sa.Version = strconv.Itoa(int(n))
+		}
+	}
+	if sa.Version != "2c" && sa.Version != "3" {
+		return nil, fmt.Errorf("snmptrap action 'version' must be \"2c\" or \"3\"")
+	}
+
+	if community, ok := config["community"].(string); ok {
+		sa.Community = os.ExpandEnv(community)
+	}
+	if oid, ok := config["oid"].(string); ok {
+		if _, err := encodeOID(oid); err != nil {
+			return nil, fmt.Errorf("snmptrap action 'oid': %w", err)
+		}
+		sa.OID = strings.TrimPrefix(oid, ".")
+	}
+	if timeout, ok := actionNumber(config, "timeout"); ok {
+		sa.Timeout = time.Duration(timeout) * time.Second
+	}
+
+	if sa.Version == "3" {
+		if err := sa.configureUSM(config); err != nil {
+			return nil, fmt.Errorf("snmptrap action %w", err)
+		}
+	}
+	return sa, nil
+}
+
+// configureUSM reads the SNMPv3 user-based security settings
+func (sa *SNMPTrapAction) configureUSM(config map[string]interface{}) error {
+	username, ok := config["username"].(string)
+	if !ok || username == "" {
+		return fmt.Errorf("version 3 requires 'username' field")
+	}
+	sa.Username = username
+
+	if protocol, ok := config["auth_protocol"].(string); ok {
+		sa.AuthProtocol = strings.ToLower(protocol)
+	}
+	if sa.AuthProtocol != "md5" && sa.AuthProtocol != "sha" {
+		return fmt.Errorf("'auth_protocol' must be md5 or sha")
+	}
+	if protocol, ok := config["priv_protocol"].(string); ok {
+		sa.PrivProtocol = strings.ToLower(protocol)
+	}
+	if sa.PrivProtocol != "aes" {
+		return fmt.Errorf("'priv_protocol' must be aes")
+	}
+
+	if password, ok := config["auth_password"].(string); ok {
+		sa.AuthPassword = os.ExpandEnv(password)
+	}
+	if password, ok := config["priv_password"].(string); ok {
+		sa.PrivPassword = os.ExpandEnv(password)
+	}
+	if sa.PrivPassword != "" && sa.AuthPassword == "" {
+		return fmt.Errorf("'priv_password' requires 'auth_password'")
+	}
+	for _, password := range []string{sa.AuthPassword, sa.PrivPassword} {
+		if password != "" && len(password) < 8 {
+			return fmt.Errorf("passwords must have at least 8 characters")
+		}
+	}
+
+	if engineID, ok := config["engine_id"].(string); ok && engineID != "" {
+		id, err := hex.DecodeString(strings.TrimPrefix(strings.ToLower(engineID), "0x"))
+		if err != nil || len(id) < 5 || len(id) > 32 {
+			return fmt.Errorf("'engine_id' must be 5 to 32 bytes in hex")
+		}
+		sa.EngineID = id
+	} else {
+		sa.EngineID = defaultSNMPEngineID()
+	}
+	return nil
+}
+
+// defaultSNMPEngineID derives an engine ID from the host name (RFC 3411 text format)
+func defaultSNMPEngineID() []byte {
+	hostname, _ := os.Hostname()
+	if len(hostname) > 27 {
+		hostname = hostname[:27]
+	}
+	if hostname == "" {
+		hostname = "tfc-system-monitor"
+	}
+	return append([]byte{0x80, 0x01, 0x86, 0x9f, 0x04}, hostname...)
+}
+
+// Execute sends a violation as a warning or critical trap
+func (sa *SNMPTrapAction) Execute(violation ThresholdViolation) error {
+	notification := 1
+	if violation.Level == "critical" {
+		notification = 2
+	}
+	if err := sa.send(violation, notification); err != nil {
+		return err
+	}
+	log.Printf("SNMP trap sent to %s: %s/%s", sa.Host, violation.Metric, violation.Level)
+	return nil
+}
+
+// Resolve sends a resolved trap
+func (sa *SNMPTrapAction) Resolve(violation ThresholdViolation) error {
+	return sa.send(violation, 3)
+}
+
+// setContext makes sending stop when ctx is cancelled
+func (sa *SNMPTrapAction) setContext(ctx context.Context) {
+	sa.ctx = ctx
+}
+
+// send encodes and sends a trap
+func (sa *SNMPTrapAction) send(violation ThresholdViolation, notification int) error {
+	pdu, err := sa.trapPDU(violation, notification)
+	if err != nil {
+		return err
+	}
+
+	var message []byte
+	if sa.Version == "3" {
+		message, err = sa.v3Message(pdu, time.Now())
+		if err != nil {
+			return err
+		}
+	} else {
+		message = berTLV(berSequence, berInt(berInteger, 1), berTLV(berOctetString, []byte(sa.Community)), pdu)
+	}
+
+	address := net.JoinHostPort(sa.Host, strconv.Itoa(sa.Port))
+	conn, err := (&net.Dialer{Timeout: sa.Timeout}).DialContext(actionContext(sa.ctx), "udp", address)
+	if err != nil {
+		return fmt.Errorf("failed to connect to SNMP manager %s: %w", address, err)
+	}
+	defer conn.Close()
+	conn.SetWriteDeadline(time.Now().Add(sa.Timeout))
+	if _, err := conn.Write(message); err != nil {
+		return fmt.Errorf("failed to send SNMP trap to %s: %w", address, err)
+	}
+	return nil
+}
+
+// trapPDU builds the SNMPv2-Trap-PDU of a violation
+func (sa *SNMPTrapAction) trapPDU(violation ThresholdViolation, notification int) ([]byte, error) {
+	level := int64(1)
+	if violation.Level == "critical" {
+		level = 2
+	}
+	resource := violation.Mountpoint
+	if resource == "" {
+		resource = violation.Device
+	}
+	hostname, _ := os.Hostname()
+
+	object := func(n int) string { return fmt.Sprintf("%s.1.%d.0", sa.OID, n) }
+	type varbind struct {
+		oid   string
+		value []byte
+	}
+	trapOID, err := encodeOID(fmt.Sprintf("%s.0.%d", sa.OID, notification))
+	if err != nil {
+		return nil, err
+	}
+	varbinds := []varbind{
+		{oidSysUpTime, berInt(berTimeTicks, int64(time.Since(snmpStart)/(10*time.Millisecond))&0xffffffff)},
+		{oidSnmpTrapOID, berTLV(berOID, trapOID)},
+		{object(1), berTLV(berOctetString, []byte(violation.Metric))},
+		{object(2), berInt(berInteger, level)},
+		{object(3), berTLV(berOctetString, []byte(strconv.FormatFloat(violation.Value, 'f', -1, 64)))},
+		{object(4), berTLV(berOctetString, []byte(strconv.FormatFloat(violation.Threshold, 'f', -1, 64)))},
+		{object(5), berTLV(berOctetString, []byte(violation.Message))},
+		{object(6), berTLV(berOctetString, []byte(resource))},
+		{object(7), berTLV(berOctetString, []byte(hostname))},
+	}
+
+	var list [][]byte
+	for _, vb := range varbinds {
+		oid, err := encodeOID(vb.oid)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, berTLV(berSequence, berTLV(berOID, oid), vb.value))
+	}
+
+	requestID, err := randomUint32()
+	if err != nil {
+		return nil, err
+	}
+	return berTLV(berTrapV2,
+		berInt(berInteger, int64(requestID&0x7fffffff)),
+		berInt(berInteger, 0), // error-status
+		berInt(berInteger, 0), // error-index
+		berTLV(berSequence, list...),
+	), nil
+}
+
+// v3Message wraps a PDU in an SNMPv3 message, authenticated and encrypted as configured
+func (sa *SNMPTrapAction) v3Message(pdu []byte, now time.Time) ([]byte, error) {
+	var flags byte
+	if sa.AuthPassword != "" {
+		flags |= 0x01
+	}
+	if sa.PrivPassword != "" {
+		flags |= 0x02
+	}
+
+	msgID, err := randomUint32()
+	if err != nil {
+		return nil, err
+	}
+	boots, engineTime := int64(1), int64(now.Sub(snmpEngineEpoch)/time.Second)
+
+	header := berTLV(berSequence,
+		berInt(berInteger, int64(msgID&0x7fffffff)),
+		berInt(berInteger, 65507), // msgMaxSize
+		berTLV(berOctetString, []byte{flags}),
+		berInt(berInteger, 3), // USM
+	)
+	msgData := berTLV(berSequence, berTLV(berOctetString, sa.EngineID), berTLV(berOctetString, nil), pdu)
+
+	var authParams, privParams []byte
+	if flags&0x02 != 0 {
+		salt := make([]byte, 8)
+		if _, err := rand.Read(salt); err != nil {
+			return nil, fmt.Errorf("failed to generate SNMP salt: %w", err)
+		}
+		key := snmpLocalizeKey(sa.authHash, sa.PrivPassword, sa.EngineID)[:16]
+		iv := make([]byte, 0, 16)
+		iv = binary.BigEndian.AppendUint32(iv, uint32(boots))
+		iv = binary.BigEndian.AppendUint32(iv, uint32(engineTime))
+		iv = append(iv, salt...)
+		block, err := aes.NewCipher(key)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encrypt SNMP trap: %w", err)
+		}
+		encrypted := make([]byte, len(msgData))
+		cipher.NewCFBEncrypter(block, iv).XORKeyStream(encrypted, msgData)
+		msgData = berTLV(berOctetString, encrypted)
+		privParams = salt
+	}
+	if flags&0x01 != 0 {
+		authParams = make([]byte, 12) // filled in after encoding
+	}
+
+	usmFields := concatBytes(
+		berTLV(berOctetString, sa.EngineID),
+		berInt(berInteger, boots),
+		berInt(berInteger, engineTime),
+		berTLV(berOctetString, []byte(sa.Username)),
+	)
+	usmContent := concatBytes(usmFields, berTLV(berOctetString, authParams), berTLV(berOctetString, privParams))
+	usm := berTLV(berSequence, usmContent)
+	usmOctets := berTLV(berOctetString, usm)
+	version := berInt(berInteger, 3)
+	body := concatBytes(version, header, usmOctets, msgData)
+	message := berTLV(berSequence, body)
+
+	if flags&0x01 != 0 {
+		// The MAC covers the whole message with zeroed authentication parameters
+		offset := len(message) - len(body) + len(version) + len(header) +
+			len(usmOctets) - len(usmContent) + len(usmFields) + 2
+		mac := hmac.New(sa.authHash, snmpLocalizeKey(sa.authHash, sa.AuthPassword, sa.EngineID))
+		mac.Write(message)
+		copy(message[offset:offset+12], mac.Sum(nil)[:12])
+	}
+	return message, nil
+}
+
+// authHash returns the hash function of the authentication protocol
+func (sa *SNMPTrapAction) authHash() hash.Hash {
+	if sa.AuthProtocol == "md5" {
+		return md5.New()
+	}
+	return sha1.New()
+}
+
+// snmpLocalizeKey derives the key of a password localized to an engine (RFC 3414 A.2)
+func snmpLocalizeKey(newHash func() hash.Hash, password string, engineID []byte) []byte {
+	h := newHash()
+	buf := make([]byte, 64)
+	for i := 0; i < 1048576; i += 64 {
+		for j := range buf {
+			buf[j] = password[(i+j)%len(password)]
+		}
+		h.Write(buf)
+	}
+	key := h.Sum(nil)
+
+	h = newHash()
+	h.Write(key)
+	h.Write(engineID)
+	h.Write(key)
+	return h.Sum(nil)
+}
+
+// berTLV encodes a BER type-length-value with the concatenated contents
+func berTLV(tag byte, contents ...[]byte) []byte {
+	content := concatBytes(contents...)
+	out := []byte{tag}
+	if n := len(content); n < 0x80 {
+		out = append(out, byte(n))
+	} else {
+		var length []byte
+		for ; n > 0; n >>= 8 {
+			length = append([]byte{byte(n)}, length...)
+		}
+		out = append(out, 0x80|byte(len(length)))
+		out = append(out, length...)
+	}
+	return append(out, content...)
+}
+
+// berInt encodes an integer with the given tag in the fewest two's complement bytes
+func berInt(tag byte, v int64) []byte {
+	var content []byte
+	for {
+		content = append([]byte{byte(v)}, content...)
+		if v >= -0x80 && v < 0x80 {
+			break
+		}
+		v >>= 8
+	}
+	return berTLV(tag, content)
+}
+
+// encodeOID encodes the contents of a dotted object identifier
+func encodeOID(oid string) ([]byte, error) {
+	parts := strings.Split(strings.TrimPrefix(oid, "."), ".")
+	if len(parts) < 2 {
+		return nil, fmt.Errorf("invalid OID '%s'", oid)
+	}
+	arcs := make([]uint64, len(parts))
+	for i, part := range parts {
+		arc, err := strconv.ParseUint(part, 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("invalid OID '%s'", oid)
+		}
+		arcs[i] = arc
+	}
+	if arcs[0] > 2 || (arcs[0] < 2 && arcs[1] > 39) {
+		return nil, fmt.Errorf("invalid OID '%s'", oid)
+	}
+
+	var out []byte
+	for _, arc := range append([]uint64{arcs[0]*40 + arcs[1]}, arcs[2:]...) {
+		chunk := []byte{byte(arc & 0x7f)}
+		for arc >>= 7; arc > 0; arc >>= 7 {
+			chunk = append([]byte{byte(arc&0x7f) | 0x80}, chunk...)
+		}
+		out = append(out, chunk...)
+	}
+	return out, nil
+}
+
+// concatBytes joins byte slices
+func concatBytes(parts ...[]byte) []byte {
+	var out []byte
+	for _, p := range parts {
+		out = append(out, p...)
+	}
+	return out
+}
+
+// randomUint32 returns a random request or message ID
+func randomUint32() (uint32, error) {
+	var b [4]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return 0, fmt.Errorf("failed to generate SNMP request ID: %w", err)
+	}
+	return binary.BigEndian.Uint32(b[:]), nil
+}
@@ -0,0 +1,307 @@
+package monitor
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/md5"
+	"crypto/sha1"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// berNode is a decoded BER value
+type berNode struct {
+	tag      byte
+	content  []byte
+	children []berNode // for sequences and PDUs
+}
+
+// decodeBER decodes one BER value and returns the remaining bytes
+func decodeBER(t *testing.T, data []byte) (berNode, []byte) {
+	t.Helper()
+	if len(data) < 2 {
+		t.Fatalf("truncated BER value: %x", data)
+	}
+	tag, length, offset := data[0], int(data[1]), 2
+	if length&0x80 != 0 {
+		n := length & 0x7f
+		length = 0
+		for _, b := range data[2 : 2+n] {
+			length = length<<8 | int(b)
+		}
+		offset += n
+	}
+	if len(data) < offset+length {
+		t.Fatalf("truncated BER value: %x", data)
+	}
+	node := berNode{tag: tag, content: data[offset : offset+length]}
+	if tag&0x20 != 0 { // constructed
+		for rest := node.content; len(rest) > 0; {
+			var child berNode
+			child, rest = decodeBER(t, rest)
+			node.children = append(node.children, child)
+		}
+	}
+	return node, data[offset+length:]
+}
+
+// int decodes an integer node
+func (n berNode) int() int64 {
+	var v int64
+	if len(n.content) > 0 && n.content[0]&0x80 != 0 {
+		v = -1
+	}
+	for _, b := range n.content {
+		v = v<<8 | int64(b)
+	}
+	return v
+}
+
+// oid decodes an object identifier node
+func (n berNode) oid() string {
+	var arcs []string
+	var arc uint64
+	for _, b := range n.content {
+		arc = arc<<7 | uint64(b&0x7f)
+		if b&0x80 != 0 {
+			continue
+		}
+		if len(arcs) == 0 {
+			arcs = append(arcs, strconv.FormatUint(arc/40, 10), strconv.FormatUint(arc%40, 10))
+		} else {
+			arcs = append(arcs, strconv.FormatUint(arc, 10))
+		}
+		arc = 0
+	}
+	return strings.Join(arcs, ".")
+}
+
+// newTrapReceiver starts a local stand-in for an SNMP trap receiver
+func newTrapReceiver(t *testing.T) (int, func() []byte) {
+	t.Helper()
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	return conn.LocalAddr().(*net.UDPAddr).Port, func() []byte {
+		buf := make([]byte, 65535)
+		conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+		n, _, err := conn.ReadFrom(buf)
+		if err != nil {
+			t.Fatalf("failed to receive trap: %v", err)
+		}
+		return buf[:n]
+	}
+}
+
+// trapVarbinds returns the varbinds of an SNMPv2-Trap-PDU by OID
+func trapVarbinds(t *testing.T, pdu berNode) map[string]berNode {
+	t.Helper()
+	if pdu.tag != berTrapV2 || len(pdu.children) != 4 {
+		t.Fatalf("not a trap PDU: tag %x with %d children", pdu.tag, len(pdu.children))
+	}
+	varbinds := make(map[string]berNode)
+	for _, vb := range pdu.children[3].children {
+		varbinds[vb.children[0].oid()] = vb.children[1]
+	}
+	return varbinds
+}
+
+// TestSNMPTrapV2c tests the v2c message and the MIB varbinds
+func TestSNMPTrapV2c(t *testing.T) {
+	port, receive := newTrapReceiver(t)
+	action, err := CreateAction(map[string]interface{}{
+		"type":      "snmptrap",
+		"host":      "127.0.0.1",
+		"port":      port,
+		"community": "noc",
+	})
+	if err != nil {
+		t.Fatalf("CreateAction() error = %v", err)
+	}
+
+	violation := ThresholdViolation{Metric: "disk", Level: "critical", Value: 97.5, Threshold: 95, Mountpoint: "/var", Message: "disk usage: 97.5%"}
+	if err := action.Execute(violation); err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+
+	message, rest := decodeBER(t, receive())
+	if len(rest) != 0 || len(message.children) != 3 {
+		t.Fatalf("unexpected message layout: %d children, %d trailing bytes", len(message.children), len(rest))
+	}
+	if message.children[0].int() != 1 || string(message.children[1].content) != "noc" {
+		t.Errorf("version/community = %d/%q, want 1/noc", message.children[0].int(), message.children[1].content)
+	}
+
+	varbinds := trapVarbinds(t, message.children[2])
+	base := DefaultSNMPTrapOID
+	if got := varbinds[oidSnmpTrapOID].oid(); got != base+".0.2" {
+		t.Errorf("snmpTrapOID = %s, want %s.0.2", got, base)
+	}
+	if varbinds[oidSysUpTime].tag != berTimeTicks {
+		t.Errorf("sysUpTime tag = %x, want TimeTicks", varbinds[oidSysUpTime].tag)
+	}
+	want := map[int]string{1: "disk", 3: "97.5", 4: "95", 5: "disk usage: 97.5%", 6: "/var"}
+	for n, value := range want {
+		if got := string(varbinds[fmt.Sprintf("%s.1.%d.0", base, n)].content); got != value {
+			t.Errorf("object %d = %q, want %q", n, got, value)
+		}
+	}
+	if level := varbinds[base+".1.2.0"]; level.tag != berInteger || level.int() != 2 {
+		t.Errorf("tfcAlertLevel = %d, want critical(2)", level.int())
+	}
+
+	if err := action.(ResolvingAlertAction).Resolve(violation); err != nil {
+		t.Fatalf("Resolve() error = %v", err)
+	}
+	message, _ = decodeBER(t, receive())
+	if got := trapVarbinds(t, message.children[2])[oidSnmpTrapOID].oid(); got != base+".0.3" {
+		t.Errorf("resolved snmpTrapOID = %s, want %s.0.3", got, base)
+	}
+}
+
+// TestSNMPTrapV3 tests an authenticated and encrypted v3 trap
+func TestSNMPTrapV3(t *testing.T) {
+	port, receive := newTrapReceiver(t)
+	t.Setenv("SNMP_PRIV", "privpass123")
+	action, err := NewSNMPTrapAction(map[string]interface{}{
+		"host":          "127.0.0.1",
+		"port":          port,
+		"version":       3,
+		"oid":           "1.3.6.1.4.1.12345.7",
+		"username":      "monitor",
+		"auth_protocol": "SHA",
+		"auth_password": "authpass123",
+		"priv_password": "${SNMP_PRIV}",
+		"engine_id":     "0x80001f8804746663",
+	})
+	if err != nil {
+		t.Fatalf("NewSNMPTrapAction() error = %v", err)
+	}
+	if err := action.Execute(ThresholdViolation{Metric: "cpu", Level: "warning", Value: 85, Threshold: 80}); err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+
+	data := receive()
+	message, _ := decodeBER(t, data)
+	if len(message.children) != 4 || message.children[0].int() != 3 {
+		t.Fatalf("not an SNMPv3 message")
+	}
+	header := message.children[1]
+	if flags := header.children[2].content; len(flags) != 1 || flags[0] != 0x03 {
+		t.Errorf("msgFlags = %x, want 03 (authPriv)", flags)
+	}
+	if header.children[3].int() != 3 {
+		t.Errorf("msgSecurityModel = %d, want 3 (USM)", header.children[3].int())
+	}
+
+	usm, _ := decodeBER(t, message.children[2].content)
+	engineID, _ := hex.DecodeString("80001f8804746663")
+	if !bytes.Equal(usm.children[0].content, engineID) || string(usm.children[3].content) != "monitor" {
+		t.Errorf("USM engine/user = %x/%q", usm.children[0].content, usm.children[3].content)
+	}
+
+	// Verify the MAC over the message with zeroed authentication parameters
+	authParams := usm.children[4].content
+	if len(authParams) != 12 {
+		t.Fatalf("authentication parameters have %d bytes, want 12", len(authParams))
+	}
+	zeroed := bytes.Replace(data, authParams, make([]byte, 12), 1)
+	mac := hmac.New(sha1.New, snmpLocalizeKey(sha1.New, "authpass123", engineID))
+	mac.Write(zeroed)
+	if !bytes.Equal(mac.Sum(nil)[:12], authParams) {
+		t.Error("message authentication code does not verify")
+	}
+
+	// Decrypt the scoped PDU
+	iv := make([]byte, 0, 16)
+	iv = binary.BigEndian.AppendUint32(iv, uint32(usm.children[1].int()))
+	iv = binary.BigEndian.AppendUint32(iv, uint32(usm.children[2].int()))
+	iv = append(iv, usm.children[5].content...)
+	block, _ := aes.NewCipher(snmpLocalizeKey(sha1.New, "privpass123", engineID)[:16])
+	encrypted := message.children[3].content
+	plain := make([]byte, len(encrypted))
+	cipher.NewCFBDecrypter(block, iv).XORKeyStream(plain, encrypted)
+
+	scoped, _ := decodeBER(t, plain)
+	if !bytes.Equal(scoped.children[0].content, engineID) {
+		t.Errorf("contextEngineID = %x", scoped.children[0].content)
+	}
+	varbinds := trapVarbinds(t, scoped.children[2])
+	if got := varbinds[oidSnmpTrapOID].oid(); got != "1.3.6.1.4.1.12345.7.0.1" {
+		t.Errorf("snmpTrapOID = %s, want 1.3.6.1.4.1.12345.7.0.1", got)
+	}
+	if got := string(varbinds["1.3.6.1.4.1.12345.7.1.1.0"].content); got != "cpu" {
+		t.Errorf("tfcAlertMetric = %q, want cpu", got)
+	}
+}
+
+// TestSNMPLocalizeKey tests key localization against RFC 3414 appendix A.3
+func TestSNMPLocalizeKey(t *testing.T) {
+	engineID, _ := hex.DecodeString("000000000000000000000002")
+	md5Key := hex.EncodeToString(snmpLocalizeKey(md5.New, "maplesyrup", engineID))
+	if md5Key != "526f5eed9fcce26f8964c2930787d82b" {
+		t.Errorf("MD5 key = %s", md5Key)
+	}
+	shaKey := hex.EncodeToString(snmpLocalizeKey(sha1.New, "maplesyrup", engineID))
+	if shaKey != "6695febc9288e36282235fc7151f128497b38f3f" {
+		t.Errorf("SHA key = %s", shaKey)
+	}
+}
+
+// TestBEREncoding tests integer and OID encoding edge cases
+func TestBEREncoding(t *testing.T) {
+	ints := map[int64]string{0: "020100", 127: "02017f", 128: "02020080", 256: "02020100", -1: "0201ff", -129: "0202ff7f"}
+	for v, want := range ints {
+		if got := hex.EncodeToString(berInt(berInteger, v)); got != want {
+			t.Errorf("berInt(%d) = %s, want %s", v, got, want)
+		}
+	}
+
+	oid, err := encodeOID("1.3.6.1.4.1.99999.1")
+	if err != nil || hex.EncodeToString(oid) != "2b06010401868d1f01" {
+		t.Errorf("encodeOID() = %x, %v", oid, err)
+	}
+	for _, invalid := range []string{"1", "1.x.3", "3.1", "1.40.1"} {
+		if _, err := encodeOID(invalid); err == nil {
+			t.Errorf("encodeOID(%q) expected error", invalid)
+		}
+	}
+
+	long := berTLV(berOctetString, make([]byte, 300))
+	if hex.EncodeToString(long[:4]) != "0482012c" {
+		t.Errorf("long length = %x, want 0482012c", long[:4])
+	}
+}
+
+// TestNewSNMPTrapActionErrors tests invalid snmptrap configurations
+func TestNewSNMPTrapActionErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		config map[string]interface{}
+	}{
+		{"missing host", map[string]interface{}{}},
+		{"invalid version", map[string]interface{}{"host": "nms", "version": "1"}},
+		{"invalid oid", map[string]interface{}{"host": "nms", "oid": "enterprises.1"}},
+		{"v3 without user", map[string]interface{}{"host": "nms", "version": "3"}},
+		{"priv without auth", map[string]interface{}{"host": "nms", "version": "3", "username": "u", "priv_password": "privpass123"}},
+		{"short password", map[string]interface{}{"host": "nms", "version": "3", "username": "u", "auth_password": "short"}},
+		{"invalid auth protocol", map[string]interface{}{"host": "nms", "version": "3", "username": "u", "auth_protocol": "sha512"}},
+		{"invalid engine id", map[string]interface{}{"host": "nms", "version": "3", "username": "u", "engine_id": "abcd"}},
+	}
+	for _, tt := range tests {
+		if _, err := NewSNMPTrapAction(tt.config); err == nil {
+			t.Errorf("%s: expected error", tt.name)
+		}
+	}
+}
@@ -0,0 +1,416 @@
+package monitor
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	mathrand "math/rand"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// Spool defaults
+const (
+	DefaultSpoolDir        = "/tmp/tfc-monitor-spool"
+	defaultSpoolMaxAge     = 24 * time.Hour
+	defaultSpoolBackoff    = 30 * time.Second
+	defaultSpoolMaxBackoff = time.Hour
+)
+
+// SpoolConfig configures the on-disk queue for failed alert deliveries
+type SpoolConfig struct {
+	Enabled    bool   `yaml:"enabled"`
+	Dir        string `yaml:"dir"`         // Queue directory (default: /tmp/tfc-monitor-spool)
+	MaxAge     string `yaml:"max_age"`     // Entries older than this are dead-lettered (default: "24h")
+	Backoff    string `yaml:"backoff"`     // Delay before the first retry, doubled per attempt (default: "30s")
+	MaxBackoff string `yaml:"max_backoff"` // Upper bound of the retry delay (default: "1h")
+}
+
+// SpoolEntry is a failed delivery waiting for retry
+type SpoolEntry struct {
+	ID          string               `json:"id"`
+	Action      SpoolAction          `json:"action"`
+	Violations  []ThresholdViolation `json:"violations"`
+	Resolved    bool                 `json:"resolved,omitempty"` // resolved updates instead of alerts
+	Stats       *SystemStats         `json:"stats,omitempty"`    // restored into the violations on retry
+	CreatedAt   time.Time            `json:"created_at"`
+	Attempts    int                  `json:"attempts"`
+	NextAttempt time.Time            `json:"next_attempt"`
+	LastError   string               `json:"last_error"`
+}
+
+// SpoolAction refers to the configured action of a spooled delivery. The action's
+// settings, which may contain secrets, are not written to the spool but looked up in
+// the configuration on each retry.
+type SpoolAction struct {
+	Type     string `json:"type"`
+	Receiver string `json:"receiver,omitempty"`        // receiver the action belongs to, if routing is configured
+	Level    string `json:"level"`                     // level the action belongs to otherwise
+	Step     int    `json:"escalation_step,omitempty"` // escalation step of the level the action belongs to
+	Index    int    `json:"index"`                     // position of the action in its list
+}
+
+// config looks up the settings of the referenced action
+func (a SpoolAction) config(c *Config) (map[string]interface{}, error) {
+	var actions []map[string]interface{}
+	switch {
+	case a.Step > 0:
+		if steps := c.Alerts[a.Level].Escalation; a.Step <= len(steps) {
+			actions = steps[a.Step-1].Actions
+		}
+	case a.Receiver != "":
+		if receiver, ok := c.GetReceiver(a.Receiver); ok {
+			actions = receiver.Actions
+		}
+	default:
+		actions = c.GetAlertActions(a.Level)
+	}
+
+	if a.Index < 0 || a.Index >= len(actions) || actions[a.Index]["type"] != a.Type {
+		return nil, fmt.Errorf("%s action is no longer configured", a.Type)
+	}
+	return actions[a.Index], nil
+}
+
+// Spool is a persistent queue of failed alert deliveries. Entries are retried with
+// exponential backoff and jitter across check cycles and restarts; entries older than
+// MaxAge are moved to the "dead" subdirectory.
+type Spool struct {
+	Dir        string
+	MaxAge     time.Duration
+	Backoff    time.Duration
+	MaxBackoff time.Duration
+}
+
+// spoolMu serializes spool access between concurrent checks
+var spoolMu sync.Mutex
+
+// NewSpool creates a spool from its config, applying defaults
+func NewSpool(config SpoolConfig) (*Spool, error) {
+	s := &Spool{
+		Dir:        config.Dir,
+		MaxAge:     defaultSpoolMaxAge,
+		Backoff:    defaultSpoolBackoff,
+		MaxBackoff: defaultSpoolMaxBackoff,
+	}
+	if s.Dir == "" {
+		s.Dir = DefaultSpoolDir
+	}
+
+	durations := []struct {
+		name  string
+		value string
+		dest  *time.Duration
+	}{
+		{"max_age", config.MaxAge, &s.MaxAge},
+		{"backoff", config.Backoff, &s.Backoff},
+		{"max_backoff", config.MaxBackoff, &s.MaxBackoff},
+	}
+	for _, d := range durations {
+		if d.value == "" {
+			continue
+		}
+		parsed, err := parseDuration(d.value)
+		if err != nil {
+			return nil, fmt.Errorf("spool %s: %w", d.name, err)
+		}
+		if parsed <= 0 {
+			return nil, fmt.Errorf("spool %s must be positive", d.name)
+		}
+		*d.dest = parsed
+	}
+
+	return s, nil
+}
+
+// deadDir returns the directory of dead-lettered entries
+func (s *Spool) deadDir() string {
+	return filepath.Join(s.Dir, "dead")
+}
+
+// Enqueue stores a failed delivery for retry
+func (s *Spool) Enqueue(action SpoolAction, violations []ThresholdViolation, resolved bool, cause error) error {
+	spoolMu.Lock()
+	defer spoolMu.Unlock()
+
+	id := make([]byte, 4)
+	rand.Read(id)
+	now := time.Now()
+	entry := &SpoolEntry{
+		ID:          fmt.Sprintf("%d-%s", now.UnixNano(), hex.EncodeToString(id)),
+		Action:      action,
+		Violations:  violations,
+		Resolved:    resolved,
+		CreatedAt:   now,
+		Attempts:    1,
+		NextAttempt: now.Add(s.delay(1)),
+		LastError:   cause.Error(),
+	}
+	if len(violations) > 0 {
+		entry.Stats = violations[0].Stats
+	}
+
+	if err := s.write(entry); err != nil {
+		return err
+	}
+	log.Printf("Spooled %s delivery %s for retry at %s", entry.Action.Type, entry.ID, entry.NextAttempt.Format(time.RFC3339))
+	return nil
+}
+
+// Retry redelivers the entries that are due through the actions of config, with the
+// per-action time limit of config, reschedules those that fail again and dead-letters
+// those older than MaxAge or whose action was removed from config. Alerts are dropped
+// for violations that are no longer active in stateManager. An entry that cannot be
+// updated does not stop the others. It returns the number of delivered entries.
+func (s *Spool) Retry(config *Config, stateManager *StateManager) (int, error) {
+	spoolMu.Lock()
+	defer spoolMu.Unlock()
+
+	entries, err := s.load()
+	if err != nil {
+		return 0, err
+	}
+
+	timeout, err := config.Dispatch.timeout()
+	if err != nil {
+		timeout = defaultDispatchTimeout
+	}
+
+	delivered := 0
+	var errs []error
+	now := time.Now()
+	for _, entry := range entries {
+		if now.Sub(entry.CreatedAt) > s.MaxAge {
+			if err := s.bury(entry); err != nil {
+				errs = append(errs, err)
+			}
+			continue
+		}
+		if now.Before(entry.NextAttempt) {
+			continue
+		}
+
+		// An alert that resolved in the meantime would arrive after its resolved update
+		if !entry.Resolved && stateManager != nil {
+			entry.Violations = stateManager.activeViolations(entry.Violations)
+			if len(entry.Violations) == 0 {
+				if err := os.Remove(s.entryPath(s.Dir, entry.ID)); err != nil && !os.IsNotExist(err) {
+					errs = append(errs, fmt.Errorf("failed to remove spool entry: %w", err))
+				} else {
+					log.Printf("Dropped spooled delivery %s: its violations resolved", entry.ID)
+				}
+				continue
+			}
+		}
+
+		actionConfig, err := entry.Action.config(config)
+		if err != nil {
+			entry.LastError = err.Error()
+			if err := s.write(entry); err != nil {
+				errs = append(errs, err)
+			} else if err := s.bury(entry); err != nil {
+				errs = append(errs, err)
+			}
+			continue
+		}
+
+		if err := s.redeliver(actionConfig, entry, timeout); err != nil {
+			entry.Attempts++
+			entry.NextAttempt = now.Add(s.delay(entry.Attempts))
+			entry.LastError = err.Error()
+			log.Printf("Spooled delivery %s failed (attempt %d), next retry at %s: %v",
+				entry.ID, entry.Attempts, entry.NextAttempt.Format(time.RFC3339), err)
+			if err := s.write(entry); err != nil {
+				errs = append(errs, err)
+			}
+			continue
+		}
+
+		if err := os.Remove(s.entryPath(s.Dir, entry.ID)); err != nil && !os.IsNotExist(err) {
+			errs = append(errs, fmt.Errorf("failed to remove spool entry: %w", err))
+			continue
+		}
+		log.Printf("Spooled delivery %s sent after %d attempts", entry.ID, entry.Attempts+1)
+		delivered++
+	}
+
+	return delivered, errors.Join(errs...)
+}
+
+// Depth returns the number of queued and dead-lettered entries
+func (s *Spool) Depth() (queued int, dead int, err error) {
+	if queued, err = countEntries(s.Dir); err != nil {
+		return 0, 0, err
+	}
+	if dead, err = countEntries(s.deadDir()); err != nil {
+		return 0, 0, err
+	}
+	return queued, dead, nil
+}
+
+// redeliver executes a spooled delivery, cancelling it after timeout
+func (s *Spool) redeliver(actionConfig map[string]interface{}, entry *SpoolEntry, timeout time.Duration) error {
+	action, err := CreateAction(actionConfig)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	if ca, ok := action.(contextAction); ok {
+		ca.setContext(ctx)
+	}
+
+	violations := make([]ThresholdViolation, len(entry.Violations))
+	for i, v := range entry.Violations {
+		v.Stats = entry.Stats
+		violations[i] = v
+	}
+	if err := executeDelivery(action, violations, entry.Resolved); err != nil {
+		if ctx.Err() != nil {
+			return fmt.Errorf("%s action timed out after %v", entry.Action.Type, timeout)
+		}
+		return err
+	}
+	return nil
+}
+
+// bury moves an expired or undeliverable entry to the dead-letter directory
+func (s *Spool) bury(entry *SpoolEntry) error {
+	if err := ensurePrivateDir(s.deadDir()); err != nil {
+		return fmt.Errorf("failed to create spool dead-letter directory: %w", err)
+	}
+	if err := os.Rename(s.entryPath(s.Dir, entry.ID), s.entryPath(s.deadDir(), entry.ID)); err != nil {
+		return fmt.Errorf("failed to dead-letter spool entry: %w", err)
+	}
+	log.Printf("Spooled delivery %s dead-lettered after %d attempts: %s", entry.ID, entry.Attempts, entry.LastError)
+	return nil
+}
+
+// delay returns the backoff before the given attempt: exponential, capped at
+// MaxBackoff, with jitter so that queued deliveries do not retry in lockstep
+func (s *Spool) delay(attempt int) time.Duration {
+	d := s.Backoff
+	for i := 1; i < attempt && d < s.MaxBackoff; i++ {
+		d *= 2
+	}
+	if d > s.MaxBackoff {
+		d = s.MaxBackoff
+	}
+	half := d / 2
+	return half + time.Duration(mathrand.Int63n(int64(half)+1))
+}
+
+// entryPath returns the file of an entry in dir
+func (s *Spool) entryPath(dir string, id string) string {
+	return filepath.Join(dir, id+".json")
+}
+
+// write atomically stores an entry
+func (s *Spool) write(entry *SpoolEntry) error {
+	if err := ensurePrivateDir(s.Dir); err != nil {
+		return fmt.Errorf("failed to create spool directory: %w", err)
+	}
+
+	data, err := json.MarshalIndent(entry, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal spool entry: %w", err)
+	}
+
+	path := s.entryPath(s.Dir, entry.ID)
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return fmt.Errorf("failed to write spool entry: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("failed to write spool entry: %w", err)
+	}
+	return nil
+}
+
+// load reads all queued entries, oldest first
+func (s *Spool) load() ([]*SpoolEntry, error) {
+	if err := checkPrivateDir(s.Dir); os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	files, err := os.ReadDir(s.Dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read spool directory: %w", err)
+	}
+
+	var entries []*SpoolEntry
+	for _, f := range files {
+		if f.IsDir() || !strings.HasSuffix(f.Name(), ".json") {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(s.Dir, f.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read spool entry: %w", err)
+		}
+		var entry SpoolEntry
+		if err := json.Unmarshal(data, &entry); err != nil {
+			log.Printf("Skipping unreadable spool entry %s: %v", f.Name(), err)
+			continue
+		}
+		entries = append(entries, &entry)
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].CreatedAt.Before(entries[j].CreatedAt)
+	})
+	return entries, nil
+}
+
+// countEntries counts the entry files in dir
+func countEntries(dir string) (int, error) {
+	files, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return 0, nil
+	} else if err != nil {
+		return 0, fmt.Errorf("failed to read spool directory: %w", err)
+	}
+
+	count := 0
+	for _, f := range files {
+		if !f.IsDir() && strings.HasSuffix(f.Name(), ".json") {
+			count++
+		}
+	}
+	return count, nil
+}
+
+// ensurePrivateDir creates dir with mode 0700 if it does not exist and checks that an
+// existing one belongs to the current user
+func ensurePrivateDir(dir string) error {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return err
+	}
+	return checkPrivateDir(dir)
+}
+
+// checkPrivateDir refuses dir if it is a symlink, not a directory, or owned by another
+// user, so that another local user cannot plant or read spooled deliveries
+func checkPrivateDir(dir string) error {
+	info, err := os.Lstat(dir)
+	if err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("spool directory %s is not a directory", dir)
+	}
+	if stat, ok := info.Sys().(*syscall.Stat_t); ok && int(stat.Uid) != os.Getuid() {
+		return fmt.Errorf("spool directory %s is owned by another user (uid %d)", dir, stat.Uid)
+	}
+	return nil
+}
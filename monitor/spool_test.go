@@ -0,0 +1,269 @@
+package monitor
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// flakyServer fails with 503 until healthy is set
+func flakyServer(t *testing.T, healthy *atomic.Bool, hits *atomic.Int32) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		if healthy.Load() {
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+// TestProcessViolationsSpoolsFailures tests that failed deliveries are spooled and retried
+func TestProcessViolationsSpoolsFailures(t *testing.T) {
+	var healthy atomic.Bool
+	var hits atomic.Int32
+	server := flakyServer(t, &healthy, &hits)
+	dir := t.TempDir()
+
+	config := &Config{
+		Spool: SpoolConfig{Enabled: true, Dir: dir, Backoff: "1ms", MaxBackoff: "1ms"},
+		Alerts: map[string]AlertLevel{
+			"critical": {
+				Actions: []map[string]interface{}{
+					{"type": "webhook", "url": server.URL, "headers": map[interface{}]interface{}{"X-Env": "test"}},
+				},
+			},
+		},
+	}
+	violations := []ThresholdViolation{
+		{Metric: "cpu", Level: "critical", Message: "CPU usage is 99%", Value: 99},
+		{Metric: "memory", Level: "critical", Message: "Memory usage is 97%", Value: 97},
+	}
+
+	if err := ProcessViolations(config, nil, violations); err != nil {
+		t.Fatalf("ProcessViolations() error = %v, want failures to be spooled", err)
+	}
+
+	spool, err := NewSpool(config.Spool)
+	if err != nil {
+		t.Fatalf("NewSpool() error = %v", err)
+	}
+	if queued, dead, _ := spool.Depth(); queued != 2 || dead != 0 {
+		t.Fatalf("Depth() = %d queued, %d dead, want 2, 0", queued, dead)
+	}
+
+	// Entries refer to the action instead of storing its settings
+	files, _ := filepath.Glob(filepath.Join(dir, "*.json"))
+	for _, file := range files {
+		data, _ := os.ReadFile(file)
+		if strings.Contains(string(data), server.URL) || strings.Contains(string(data), "X-Env") {
+			t.Errorf("spool entry %s contains action settings:\n%s", file, data)
+		}
+	}
+	if entries, _ := spool.load(); entries[0].Action != (SpoolAction{Type: "webhook", Level: "critical"}) {
+		t.Errorf("spooled action = %+v", entries[0].Action)
+	}
+
+	// Still failing: entries are rescheduled
+	time.Sleep(5 * time.Millisecond)
+	if delivered, err := spool.Retry(config, nil); err != nil || delivered != 0 {
+		t.Fatalf("Retry() = %d, %v, want 0, nil", delivered, err)
+	}
+	entries, _ := spool.load()
+	if len(entries) != 2 || entries[0].Attempts != 2 {
+		t.Fatalf("entries after failed retry = %+v", entries)
+	}
+
+	// Endpoint recovers: entries are delivered and removed
+	healthy.Store(true)
+	time.Sleep(5 * time.Millisecond)
+	if delivered, err := spool.Retry(config, nil); err != nil || delivered != 2 {
+		t.Fatalf("Retry() = %d, %v, want 2, nil", delivered, err)
+	}
+	if queued, _, _ := spool.Depth(); queued != 0 {
+		t.Errorf("queued = %d after delivery, want 0", queued)
+	}
+}
+
+// TestProcessViolationsWithoutSpool tests that delivery errors are returned when the spool is disabled
+func TestProcessViolationsWithoutSpool(t *testing.T) {
+	var healthy atomic.Bool
+	var hits atomic.Int32
+	server := flakyServer(t, &healthy, &hits)
+
+	config := &Config{
+		Alerts: map[string]AlertLevel{
+			"warning": {Actions: []map[string]interface{}{{"type": "webhook", "url": server.URL}}},
+		},
+	}
+	err := ProcessViolations(config, []ThresholdViolation{{Metric: "cpu", Level: "warning"}}, nil)
+	if err == nil {
+		t.Error("expected error without spool")
+	}
+}
+
+// TestSpoolDeadLetter tests that expired entries move to the dead-letter directory
+func TestSpoolDeadLetter(t *testing.T) {
+	dir := t.TempDir()
+	spool, err := NewSpool(SpoolConfig{Dir: dir, MaxAge: "1h"})
+	if err != nil {
+		t.Fatalf("NewSpool() error = %v", err)
+	}
+
+	entry := &SpoolEntry{
+		ID:         "1-expired",
+		Action:     SpoolAction{Type: "webhook", Level: "warning"},
+		Violations: []ThresholdViolation{{Metric: "cpu", Level: "warning"}},
+		CreatedAt:  time.Now().Add(-2 * time.Hour),
+		Attempts:   5,
+	}
+	if err := spool.write(entry); err != nil {
+		t.Fatalf("write() error = %v", err)
+	}
+
+	if _, err := spool.Retry(&Config{}, nil); err != nil {
+		t.Fatalf("Retry() error = %v", err)
+	}
+	if queued, dead, _ := spool.Depth(); queued != 0 || dead != 1 {
+		t.Errorf("Depth() = %d queued, %d dead, want 0, 1", queued, dead)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "dead", "1-expired.json")); err != nil {
+		t.Errorf("dead-lettered entry missing: %v", err)
+	}
+}
+
+// TestSpoolRemovedAction tests that entries whose action was removed are dead-lettered
+func TestSpoolRemovedAction(t *testing.T) {
+	dir := t.TempDir()
+	spool, _ := NewSpool(SpoolConfig{Dir: dir})
+	entry := &SpoolEntry{
+		ID:         "1-removed",
+		Action:     SpoolAction{Type: "webhook", Receiver: "ops", Level: "critical", Index: 1},
+		Violations: []ThresholdViolation{{Metric: "cpu", Level: "critical"}},
+		CreatedAt:  time.Now(),
+		Attempts:   1,
+	}
+	if err := spool.write(entry); err != nil {
+		t.Fatalf("write() error = %v", err)
+	}
+
+	config := &Config{Receivers: []Receiver{{Name: "ops", Actions: []map[string]interface{}{{"type": "webhook", "url": "http://127.0.0.1:1"}}}}}
+	if _, err := spool.Retry(config, nil); err != nil {
+		t.Fatalf("Retry() error = %v", err)
+	}
+	if queued, dead, _ := spool.Depth(); queued != 0 || dead != 1 {
+		t.Errorf("Depth() = %d queued, %d dead, want 0, 1", queued, dead)
+	}
+	if info, err := os.Stat(filepath.Join(dir, "dead")); err != nil {
+		t.Errorf("dead-letter directory missing: %v", err)
+	} else if info.Mode().Perm() != 0700 {
+		t.Errorf("dead-letter directory mode = %v, want 0700", info.Mode().Perm())
+	}
+}
+
+// TestSpoolDropsResolvedAlerts tests that spooled alerts of resolved violations are not retried
+func TestSpoolDropsResolvedAlerts(t *testing.T) {
+	var healthy atomic.Bool
+	var hits atomic.Int32
+	healthy.Store(true)
+	server := flakyServer(t, &healthy, &hits)
+
+	spool, _ := NewSpool(SpoolConfig{Dir: t.TempDir()})
+	config := &Config{
+		Alerts: map[string]AlertLevel{
+			"critical": {Actions: []map[string]interface{}{{"type": "webhook", "url": server.URL}}},
+		},
+	}
+	cpu := ThresholdViolation{Metric: "cpu", Level: "critical", Value: 99}
+	memory := ThresholdViolation{Metric: "memory", Level: "critical", Value: 97}
+	for _, violations := range [][]ThresholdViolation{{cpu}, {memory}} {
+		if err := spool.Enqueue(SpoolAction{Type: "webhook", Level: "critical"}, violations, false, errors.New("down")); err != nil {
+			t.Fatalf("Enqueue() error = %v", err)
+		}
+	}
+	entries, _ := spool.load()
+	for _, entry := range entries {
+		entry.NextAttempt = time.Now()
+		spool.write(entry)
+	}
+
+	// Only the cpu violation is still active
+	sm := &StateManager{States: map[string]*ViolationState{cpu.StateKey(): {HasAlerted: true}}}
+	if delivered, err := spool.Retry(config, sm); err != nil || delivered != 1 {
+		t.Fatalf("Retry() = %d, %v, want 1, nil", delivered, err)
+	}
+	if hits.Load() != 1 {
+		t.Errorf("webhook received %d requests, want 1", hits.Load())
+	}
+	if queued, dead, _ := spool.Depth(); queued != 0 || dead != 0 {
+		t.Errorf("Depth() = %d queued, %d dead, want 0, 0", queued, dead)
+	}
+}
+
+// TestSpoolRefusesForeignDir tests that a symlinked or foreign spool directory is refused
+func TestSpoolRefusesForeignDir(t *testing.T) {
+	target := t.TempDir()
+	link := filepath.Join(t.TempDir(), "spool")
+	if err := os.Symlink(target, link); err != nil {
+		t.Fatalf("Symlink() error = %v", err)
+	}
+	spool, _ := NewSpool(SpoolConfig{Dir: link})
+	if err := spool.Enqueue(SpoolAction{Type: "stdout", Level: "warning"}, nil, false, errors.New("down")); err == nil {
+		t.Error("Enqueue() expected error for symlinked spool directory")
+	}
+
+	if os.Getuid() != 0 {
+		return
+	}
+	foreign := t.TempDir()
+	if err := os.Chown(foreign, 65534, 65534); err != nil {
+		t.Fatalf("Chown() error = %v", err)
+	}
+	spool, _ = NewSpool(SpoolConfig{Dir: foreign})
+	if _, err := spool.Retry(&Config{}, nil); err == nil || !strings.Contains(err.Error(), "owned by another user") {
+		t.Errorf("Retry() error = %v, want ownership error", err)
+	}
+}
+
+// TestSpoolDelay tests exponential backoff with jitter and the cap
+func TestSpoolDelay(t *testing.T) {
+	spool := &Spool{Backoff: 10 * time.Second, MaxBackoff: 60 * time.Second}
+
+	tests := []struct {
+		attempt int
+		max     time.Duration
+	}{
+		{1, 10 * time.Second},
+		{2, 20 * time.Second},
+		{3, 40 * time.Second},
+		{4, 60 * time.Second},
+		{20, 60 * time.Second},
+	}
+	for _, tt := range tests {
+		for i := 0; i < 20; i++ {
+			d := spool.delay(tt.attempt)
+			if d < tt.max/2 || d > tt.max {
+				t.Errorf("delay(%d) = %v, want within [%v, %v]", tt.attempt, d, tt.max/2, tt.max)
+			}
+		}
+	}
+}
+
+// TestNewSpoolInvalid tests rejection of invalid spool durations
+func TestNewSpoolInvalid(t *testing.T) {
+	if _, err := NewSpool(SpoolConfig{Backoff: "soon"}); err == nil {
+		t.Error("expected error for invalid backoff")
+	}
+	if _, err := NewSpool(SpoolConfig{MaxAge: "-1h"}); err == nil {
+		t.Error("expected error for negative max_age")
+	}
+}
@@ -4,6 +4,8 @@ import (
 	"encoding/json"
 	"fmt"
 	"os"
+	"path/filepath"
+	"syscall"
 	"time"
 )
 
@@ -20,24 +22,40 @@ const StateFile = "/tmp/tfc-monitor-state.json"
 
 // ViolationState tracks state of a single metric violation
 type ViolationState struct {
-	Metric            string   `json:"metric"`
-	Level             string   `json:"level"`
-	FirstDetectedTime float64  `json:"first_detected_time"`
-	LastAlertTime     *float64 `json:"last_alert_time"`
-	HasAlerted        bool     `json:"has_alerted"`
+	Metric            string            `json:"metric"`
+	Level             string            `json:"level"`
+	Mountpoint        string            `json:"mountpoint,omitempty"`
+	Device            string            `json:"device,omitempty"`
+	Message           string            `json:"message,omitempty"` // last violation message
+	Value             float64           `json:"value,omitempty"`   // last violation value
+	Labels            map[string]string `json:"labels,omitempty"`  // last violation labels
+	FirstDetectedTime float64           `json:"first_detected_time"`
+	FirstAlertTime    *float64          `json:"first_alert_time,omitempty"`
+	LastAlertTime     *float64          `json:"last_alert_time"`
+	HasAlerted        bool              `json:"has_alerted"`
+	Notified          bool              `json:"notified,omitempty"`            // a notification including the violation was sent
+	EscalationStep    int               `json:"escalation_step,omitempty"`     // escalation steps already run
+	HeldByMaintenance string            `json:"held_by_maintenance,omitempty"` // maintenance window holding its notifications
 }
 
 // StateManager manages violation state persistence
 type StateManager struct {
-	StateFile string
-	States    map[string]*ViolationState
+	StateFile        string
+	AlertmanagerFile string // Alerts last pushed to Alertmanager
+	States           map[string]*ViolationState
+	Baselines        *BaselineStore       // Learned baselines for anomaly mode (optional)
+	Resolved         []ThresholdViolation // Alerted violations that resolved since the last TakeResolved
+	Escalations      []Escalation         // Escalation steps that became due since the last TakeEscalations
+
+	silences *silenceStore // loaded at the start of each check
 }
 
 // NewStateManager creates a new state manager
 func NewStateManager() (*StateManager, error) {
 	sm := &StateManager{
-		StateFile: StateFile,
-		States:    make(map[string]*ViolationState),
+		StateFile:        StateFile,
+		AlertmanagerFile: AlertmanagerFile,
+		States:           make(map[string]*ViolationState),
 	}
 	if err := sm.load(); err != nil {
 		return nil, err
@@ -45,17 +63,32 @@ func NewStateManager() (*StateManager, error) {
 	return sm, nil
 }
 
+// stateKey builds the state key for a metric/level and optional mountpoint
+func stateKey(metric string, level string, mountpoint string) string {
+	if mountpoint == "" {
+		return fmt.Sprintf("%s_%s", metric, level)
+	}
+	return fmt.Sprintf("%s_%s_%s", metric, level, mountpoint)
+}
+
 // GetOrCreate gets existing state or creates new one
 func (sm *StateManager) GetOrCreate(metric string, level string) *ViolationState {
-	key := fmt.Sprintf("%s_%s", metric, level)
+	return sm.GetOrCreateFor(ThresholdViolation{Metric: metric, Level: level})
+}
+
+// GetOrCreateFor gets or creates the state tracking a violation
+func (sm *StateManager) GetOrCreateFor(violation ThresholdViolation) *ViolationState {
+	key := violation.StateKey()
 	if state, ok := sm.States[key]; ok {
 		return state
 	}
 
 	now := time.Now().Unix()
 	state := &ViolationState{
-		Metric:            metric,
-		Level:             level,
+		Metric:            violation.Metric,
+		Level:             violation.Level,
+		Mountpoint:        violation.Mountpoint,
+		Device:            violation.Device,
 		FirstDetectedTime: float64(now),
 		HasAlerted:        false,
 	}
@@ -65,7 +98,11 @@ func (sm *StateManager) GetOrCreate(metric string, level string) *ViolationState
 
 // Clear clears state for a metric/level (violation resolved)
 func (sm *StateManager) Clear(metric string, level string) error {
-	key := fmt.Sprintf("%s_%s", metric, level)
+	return sm.clearKey(stateKey(metric, level, ""))
+}
+
+// clearKey clears the state stored under key
+func (sm *StateManager) clearKey(key string) error {
 	if _, ok := sm.States[key]; ok {
 		delete(sm.States, key)
 		if err := sm.save(); err != nil {
@@ -75,12 +112,46 @@ func (sm *StateManager) Clear(metric string, level string) error {
 	return nil
 }
 
+// activeViolations returns the violations that still have a state, i.e. have not resolved
+func (sm *StateManager) activeViolations(violations []ThresholdViolation) []ThresholdViolation {
+	var active []ThresholdViolation
+	for _, v := range violations {
+		if _, ok := sm.States[v.StateKey()]; ok {
+			active = append(active, v)
+		}
+	}
+	return active
+}
+
+// markNotified marks the states of violations that were included in a notification
+func (sm *StateManager) markNotified(violations []ThresholdViolation) {
+	for _, v := range violations {
+		if state, ok := sm.States[v.StateKey()]; ok {
+			state.MarkNotified()
+		}
+	}
+}
+
+// TakeResolved returns and forgets the violations that resolved since the last call
+func (sm *StateManager) TakeResolved() []ThresholdViolation {
+	resolved := sm.Resolved
+	sm.Resolved = nil
+	return resolved
+}
+
+// TakeEscalations returns and forgets the escalation steps that became due since the last call
+func (sm *StateManager) TakeEscalations() []Escalation {
+	escalations := sm.Escalations
+	sm.Escalations = nil
+	return escalations
+}
+
 // Save persists state to file
 func (sm *StateManager) Save() error {
 	return sm.save()
 }
 
-// save writes state to file
+// save writes state to file, keeping the silences and acknowledgements stored in it
 func (sm *StateManager) save() error {
 	data := make(map[string]*ViolationState)
 	for key, state := range sm.States {
@@ -88,44 +159,109 @@ func (sm *StateManager) save() error {
 	}
 
 	// Create directory if needed
-	dir := "/tmp"
+	dir := filepath.Dir(sm.StateFile)
 	if err := os.MkdirAll(dir, 0755); err != nil {
 		return fmt.Errorf("failed to create directory: %w", err)
 	}
 
-	// Marshal and write
-	jsonData, err := json.MarshalIndent(data, "", "  ")
+	return sm.updateStateFile(func(file *stateFile) bool {
+		file.Violations = data
+		return true
+	})
+}
+
+// load reads state from file
+func (sm *StateManager) load() error {
+	file, err := readStateFile(sm.StateFile)
 	if err != nil {
-		return fmt.Errorf("failed to marshal state: %w", err)
+		return err
 	}
+	sm.States = file.Violations
+	return nil
+}
 
-	if err := os.WriteFile(sm.StateFile, jsonData, 0644); err != nil {
-		return fmt.Errorf("failed to write state file: %w", err)
+// stateFile is the content of the state file. Silences and acknowledgements are kept
+// next to the violation states; files of older versions hold only the violation states.
+type stateFile struct {
+	Violations map[string]*ViolationState `json:"violations"`
+	silenceStore
+}
+
+// updateStateFile reads the state file, applies update and writes the file back if
+// update reports a change. The file is locked meanwhile, so that CLI commands and the
+// server do not overwrite each other's changes.
+func (sm *StateManager) updateStateFile(update func(file *stateFile) bool) error {
+	lock, err := os.OpenFile(sm.StateFile+".lock", os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open state lock file: %w", err)
+	}
+	defer lock.Close()
+	if err := syscall.Flock(int(lock.Fd()), syscall.LOCK_EX); err != nil {
+		return fmt.Errorf("failed to lock state file: %w", err)
+	}
+	defer syscall.Flock(int(lock.Fd()), syscall.LOCK_UN)
+
+	file, err := readStateFile(sm.StateFile)
+	if err != nil {
+		return err
+	}
+	if !update(file) {
+		return nil
 	}
 
+	jsonData, err := json.MarshalIndent(file, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal state: %w", err)
+	}
+	tmp := sm.StateFile + ".tmp"
+	if err := os.WriteFile(tmp, jsonData, 0644); err != nil {
+		return fmt.Errorf("failed to write state file: %w", err)
+	}
+	if err := os.Rename(tmp, sm.StateFile); err != nil {
+		return fmt.Errorf("failed to write state file: %w", err)
+	}
 	return nil
 }
 
-// load reads state from file
-func (sm *StateManager) load() error {
-	if _, err := os.Stat(sm.StateFile); os.IsNotExist(err) {
-		return nil
+// readStateFile reads the state file, returning an empty one if it does not exist
+func readStateFile(path string) (*stateFile, error) {
+	file := &stateFile{
+		Violations:   make(map[string]*ViolationState),
+		silenceStore: silenceStore{Acks: make(map[string]Acknowledgement)},
+	}
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return file, nil
 	} else if err != nil {
-		return fmt.Errorf("failed to stat state file: %w", err)
+		return nil, fmt.Errorf("failed to read state file: %w", err)
 	}
 
-	data, err := os.ReadFile(sm.StateFile)
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal state: %w", err)
+	}
+	if _, ok := fields["violations"]; ok {
+		err = json.Unmarshal(data, file)
+	} else {
+		err = json.Unmarshal(data, &file.Violations)
+		// Older versions did not hold notifications back, so alerted violations were notified
+		for _, state := range file.Violations {
+			if state != nil {
+				state.Notified = state.HasAlerted
+			}
+		}
+	}
 	if err != nil {
-		return fmt.Errorf("failed to read state file: %w", err)
+		return nil, fmt.Errorf("failed to unmarshal state: %w", err)
 	}
-
-	var states map[string]*ViolationState
-	if err := json.Unmarshal(data, &states); err != nil {
-		return fmt.Errorf("failed to unmarshal state: %w", err)
+	if file.Violations == nil {
+		file.Violations = make(map[string]*ViolationState)
 	}
-
-	sm.States = states
-	return nil
+	if file.Acks == nil {
+		file.Acks = make(map[string]Acknowledgement)
+	}
+	return file, nil
 }
 
 // DurationMinutes returns duration in minutes since first detection
@@ -169,9 +305,52 @@ func (vs *ViolationState) ShouldAlert(minDurationMinutes float64, repeat bool, r
 	return true, nil
 }
 
-// MarkAlerted marks that an alert was sent at this time
+// Update records the latest message and value of the violation
+func (vs *ViolationState) Update(violation ThresholdViolation) {
+	vs.Message = violation.Message
+	vs.Value = violation.Value
+	vs.Labels = violation.Labels
+}
+
+// Violation returns the violation tracked by the state, as last seen
+func (vs *ViolationState) Violation() ThresholdViolation {
+	return ThresholdViolation{
+		Metric:     vs.Metric,
+		Level:      vs.Level,
+		Message:    vs.Message,
+		Value:      vs.Value,
+		Device:     vs.Device,
+		Mountpoint: vs.Mountpoint,
+		Labels:     vs.Labels,
+	}
+}
+
+// MarkAlerted marks that an alert passed throttling at this time. With grouping, its
+// notification may still be held; see MarkNotified.
 func (vs *ViolationState) MarkAlerted() {
 	now := float64(time.Now().Unix())
 	vs.LastAlertTime = &now
 	vs.HasAlerted = true
 }
+
+// MarkNotified marks that a notification including the violation was sent at this time.
+// Only notified violations escalate and get resolved updates.
+func (vs *ViolationState) MarkNotified() {
+	if vs.Notified {
+		return
+	}
+	now := float64(time.Now().Unix())
+	vs.FirstAlertTime = &now
+	vs.Notified = true
+}
+
+// escalationStart returns the time escalation is counted from: the first notification
+func (vs *ViolationState) escalationStart() float64 {
+	if vs.FirstAlertTime != nil {
+		return *vs.FirstAlertTime
+	}
+	if vs.LastAlertTime != nil {
+		return *vs.LastAlertTime
+	}
+	return vs.FirstDetectedTime
+}
@@ -275,6 +275,23 @@ func TestStatePersistence(t *testing.T) {
 	}
 }
 
+// TestLoadLegacyState tests loading a state file that holds only the violation states
+func TestLoadLegacyState(t *testing.T) {
+	stateFile := filepath.Join(t.TempDir(), "state.json")
+	legacy := `{"cpu_warning": {"metric": "cpu", "level": "warning", "first_detected_time": 1, "last_alert_time": null, "has_alerted": true}}`
+	if err := os.WriteFile(stateFile, []byte(legacy), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	sm := &StateManager{StateFile: stateFile}
+	if err := sm.load(); err != nil {
+		t.Fatalf("load() error = %v", err)
+	}
+	if state, ok := sm.States["cpu_warning"]; !ok || !state.HasAlerted {
+		t.Errorf("loaded states = %v, want alerted cpu_warning", sm.States)
+	}
+}
+
 // TestClearState tests clearing state
 func TestClearState(t *testing.T) {
 	tmpDir := t.TempDir()
@@ -334,6 +351,43 @@ func TestClearResolvedViolations(t *testing.T) {
 	}
 }
 
+// TestClearResolvedViolationsRecordsResolved tests collecting notified violations that resolved
+func TestClearResolvedViolationsRecordsResolved(t *testing.T) {
+	tmpDir := t.TempDir()
+	sm := &StateManager{
+		StateFile: filepath.Join(tmpDir, "state.json"),
+		States:    make(map[string]*ViolationState),
+	}
+
+	memory := sm.GetOrCreate("memory", "critical")
+	memory.Update(ThresholdViolation{Message: "free memory: 3.00%", Value: 3})
+	memory.MarkAlerted()
+	memory.MarkNotified()
+	cpu := sm.GetOrCreate("cpu", "warning") // escalated to critical below
+	cpu.MarkAlerted()
+	cpu.MarkNotified()
+	sm.GetOrCreate("disk", "warning")               // never alerted
+	sm.GetOrCreate("load", "warning").MarkAlerted() // held by grouping, never notified
+
+	currentViolations := []ThresholdViolation{
+		{Metric: "cpu", Level: "critical"},
+	}
+	if err := clearResolvedViolations(currentViolations, sm); err != nil {
+		t.Fatalf("clearResolvedViolations() error = %v", err)
+	}
+
+	resolved := sm.TakeResolved()
+	if len(resolved) != 1 {
+		t.Fatalf("TakeResolved() got %d violations, want 1: %v", len(resolved), resolved)
+	}
+	if resolved[0].Metric != "memory" || resolved[0].Message != "free memory: 3.00%" || resolved[0].Value != 3 {
+		t.Errorf("resolved violation = %+v", resolved[0])
+	}
+	if len(sm.TakeResolved()) != 0 {
+		t.Errorf("TakeResolved() returned violations twice")
+	}
+}
+
 // TestApplyThrottling tests the complete throttling flow
 func TestApplyThrottling(t *testing.T) {
 	tmpDir := t.TempDir()
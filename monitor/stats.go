@@ -10,6 +10,7 @@ import (
 	"github.com/shirou/gopsutil/v3/cpu"
 	"github.com/shirou/gopsutil/v3/disk"
 	"github.com/shirou/gopsutil/v3/host"
+	"github.com/shirou/gopsutil/v3/load"
 	"github.com/shirou/gopsutil/v3/mem"
 )
 
@@ -19,6 +20,7 @@ type SystemStats struct {
 	CPUInfo    CPUInfo    `json:"cpu_info"`
 	MemoryInfo MemoryInfo `json:"memory_info"`
 	DiskInfo   DiskInfo   `json:"disk_info"`
+	LoadInfo   LoadInfo   `json:"load_info"`
 }
 
 // BootTime contains boot time information
@@ -45,9 +47,11 @@ type MemoryInfo struct {
 
 // VirtualMemory contains virtual memory metrics
 type VirtualMemory struct {
-	Total      string `json:"total"`
-	Available  string `json:"available"`
-	Percentage string `json:"percentage"`
+	Total          string `json:"total"`
+	Available      string `json:"available"`
+	Percentage     string `json:"percentage"`
+	TotalBytes     uint64 `json:"total_bytes"`
+	AvailableBytes uint64 `json:"available_bytes"`
 }
 
 // SwapMemory contains swap memory metrics
@@ -56,6 +60,8 @@ type SwapMemory struct {
 	Free       string `json:"free"`
 	Used       string `json:"used"`
 	Percentage string `json:"percentage"`
+	TotalBytes uint64 `json:"total_bytes"`
+	UsedBytes  uint64 `json:"used_bytes"`
 }
 
 // DiskInfo contains disk metrics
@@ -73,6 +79,16 @@ type PartitionInfo struct {
 	Used       string `json:"used"`
 	Free       string `json:"free"`
 	Percentage string `json:"percentage"`
+	TotalBytes uint64 `json:"total_bytes"`
+	UsedBytes  uint64 `json:"used_bytes"`
+	FreeBytes  uint64 `json:"free_bytes"`
+}
+
+// LoadInfo contains system load averages
+type LoadInfo struct {
+	Load1  float64 `json:"load1"`
+	Load5  float64 `json:"load5"`
+	Load15 float64 `json:"load15"`
 }
 
 // IOStats contains disk IO statistics
@@ -113,6 +129,13 @@ func GetSystemStats() (*SystemStats, error) {
 	}
 	stats.DiskInfo = diskInfo
 
+	// Get load averages
+	loadInfo, err := getLoadInfo()
+	if err != nil {
+		return nil, fmt.Errorf("error getting load average: %w", err)
+	}
+	stats.LoadInfo = loadInfo
+
 	return stats, nil
 }
 
@@ -198,9 +221,11 @@ func getMemoryInfo() (MemoryInfo, error) {
 	percentage := 100 - ((float64(totalFreeMemory) / float64(vMemory.Total)) * 100)
 
 	memInfo.VirtualMemory = VirtualMemory{
-		Total:      formatBytes(vMemory.Total),
-		Available:  formatBytes(totalFreeMemory),
-		Percentage: fmt.Sprintf("%.2f", percentage),
+		Total:          formatBytes(vMemory.Total),
+		Available:      formatBytes(totalFreeMemory),
+		Percentage:     fmt.Sprintf("%.2f", percentage),
+		TotalBytes:     vMemory.Total,
+		AvailableBytes: totalFreeMemory,
 	}
 
 	// Get swap memory
@@ -214,6 +239,8 @@ func getMemoryInfo() (MemoryInfo, error) {
 		Free:       formatBytes(swapMem.Free),
 		Used:       formatBytes(swapMem.Used),
 		Percentage: fmt.Sprintf("%.2f", swapMem.UsedPercent),
+		TotalBytes: swapMem.Total,
+		UsedBytes:  swapMem.Used,
 	}
 
 	return memInfo, nil
@@ -250,6 +277,9 @@ func getDiskInfo() (DiskInfo, error) {
 			Used:       formatBytes(usage.Used),
 			Free:       formatBytes(usage.Free),
 			Percentage: fmt.Sprintf("%.2f", usage.UsedPercent),
+			TotalBytes: usage.Total,
+			UsedBytes:  usage.Used,
+			FreeBytes:  usage.Free,
 		})
 	}
 
@@ -274,6 +304,19 @@ func getDiskInfo() (DiskInfo, error) {
 	return diskInfo, nil
 }
 
+// getLoadInfo retrieves the 1, 5 and 15 minute load averages
+func getLoadInfo() (LoadInfo, error) {
+	avg, err := load.Avg()
+	if err != nil {
+		return LoadInfo{}, err
+	}
+	return LoadInfo{
+		Load1:  avg.Load1,
+		Load5:  avg.Load5,
+		Load15: avg.Load15,
+	}, nil
+}
+
 // readMemFreeFromProc reads MemFree from /proc/meminfo
 func readMemFreeFromProc() int64 {
 	data, err := os.ReadFile("/proc/meminfo")
@@ -5,20 +5,35 @@ import (
 	"log"
 	"path/filepath"
 	"strconv"
+	"time"
 )
 
 // ThresholdViolation represents a threshold violation for a metric
 type ThresholdViolation struct {
-	Metric  string  `json:"metric"`
-	Level   string  `json:"level"`
-	Message string  `json:"message"`
-	Value   float64 `json:"value"`
+	Metric     string  `json:"metric"`
+	Level      string  `json:"level"`
+	Message    string  `json:"message"`
+	Value      float64 `json:"value"`
+	Threshold  float64 `json:"threshold,omitempty"`  // violated threshold, in the unit of the value
+	Device     string  `json:"device,omitempty"`     // for disk violations
+	Mountpoint string  `json:"mountpoint,omitempty"` // for disk violations
+
+	Labels map[string]string `json:"labels,omitempty"` // custom labels, for routing
+	Stats  *SystemStats      `json:"-"`                // snapshot the violation was detected in, for alert templates
+}
+
+// StateKey returns the key under which the violation's state is tracked
+func (v ThresholdViolation) StateKey() string {
+	return stateKey(v.Metric, v.Level, v.Mountpoint)
 }
 
 // CheckAllThresholds checks all metrics against configured thresholds with throttling
 func CheckAllThresholds(config *Config, stats *SystemStats, stateManager *StateManager) ([]ThresholdViolation, []ThresholdViolation, error) {
 	var allViolations []ThresholdViolation
 
+	// Apply thresholds of active schedules
+	config = config.atTime(time.Now())
+
 	// Check disk thresholds
 	diskViolations, err := checkDiskThresholds(config, stats)
 	if err != nil {
@@ -42,6 +57,33 @@ func CheckAllThresholds(config *Config, stats *SystemStats, stateManager *StateM
 	memFree := 100 - memUsed
 	memViolations := checkMemoryThresholds(config, memUsed, memFree)
 	allViolations = append(allViolations, memViolations...)
+	memBytesViolations := checkMemoryBytesThresholds(config, stats.MemoryInfo.VirtualMemory.TotalBytes, stats.MemoryInfo.VirtualMemory.AvailableBytes)
+	allViolations = append(allViolations, memBytesViolations...)
+
+	// Check anomaly thresholds against learned baselines
+	anomalyValues := map[string]float64{"cpu": cpuUsage, "memory": memUsed}
+	anomalyViolations := checkAnomalyThresholds(config, stateManager.Baselines, anomalyValues, time.Now())
+	allViolations = append(allViolations, anomalyViolations...)
+
+	// Check expression-based rules
+	if len(config.Rules) > 0 {
+		vars, err := ruleVariables(config, stats)
+		if err != nil {
+			return nil, nil, fmt.Errorf("rule evaluation failed: %w", err)
+		}
+		allViolations = append(allViolations, checkRules(config, vars)...)
+	}
+
+	// Attach custom labels for routing and the stats snapshot for alert templates
+	for i := range allViolations {
+		allViolations[i].Labels = config.violationLabels(allViolations[i])
+		allViolations[i].Stats = stats
+	}
+
+	// Load silences and acknowledgements
+	if err := stateManager.refreshSilences(time.Now()); err != nil {
+		return nil, nil, fmt.Errorf("failed to load silences: %w", err)
+	}
 
 	// Apply throttling
 	throttledViolations, err := applyThrottling(config, allViolations, stateManager)
@@ -49,6 +91,11 @@ func CheckAllThresholds(config *Config, stats *SystemStats, stateManager *StateM
 		return nil, nil, fmt.Errorf("failed to apply throttling: %w", err)
 	}
 
+	// Collect escalation steps that are due
+	if err := collectEscalations(config, allViolations, stateManager, time.Now()); err != nil {
+		return nil, nil, fmt.Errorf("failed to evaluate escalations: %w", err)
+	}
+
 	// Clear resolved violations
 	if err := clearResolvedViolations(allViolations, stateManager); err != nil {
 		return nil, nil, fmt.Errorf("failed to clear resolved violations: %w", err)
@@ -114,6 +161,79 @@ func isPartitionExcludedByConfig(partition PartitionInfo, exclude ExcludeConfig)
 	return false
 }
 
+// patternSpecificity scores how specific a glob pattern is: literal characters
+// count once, and patterns without wildcards rank above any wildcard pattern
+func patternSpecificity(pattern string) int {
+	score := 0
+	literal := true
+	for _, c := range pattern {
+		switch c {
+		case '*', '?', '[', ']', '\\':
+			literal = false
+		default:
+			score++
+		}
+	}
+	if literal {
+		score += 1 << 16
+	}
+	return score
+}
+
+// matchDiskOverride returns the most specific override matching a partition.
+// Every pattern set on an override must match; ties go to the first rule.
+func matchDiskOverride(overrides []DiskOverride, partition PartitionInfo) (*DiskOverride, bool) {
+	var best *DiskOverride
+	bestScore := -1
+
+	for i := range overrides {
+		override := &overrides[i]
+		score := 0
+		if override.Mountpoint != "" {
+			if !matchesPattern(override.Mountpoint, partition.Mountpoint) {
+				continue
+			}
+			score += patternSpecificity(override.Mountpoint)
+		}
+		if override.Device != "" {
+			if !matchesPattern(override.Device, partition.Device) {
+				continue
+			}
+			score += patternSpecificity(override.Device)
+		}
+		if override.Mountpoint == "" && override.Device == "" {
+			continue
+		}
+		if score > bestScore {
+			best, bestScore = override, score
+		}
+	}
+
+	return best, best != nil
+}
+
+// forPartition returns the disk configuration with the matching override applied
+func (mc MetricConfig) forPartition(partition PartitionInfo) MetricConfig {
+	override, ok := matchDiskOverride(mc.Overrides, partition)
+	if !ok {
+		return mc
+	}
+
+	if override.Thresholds != nil {
+		mc.Thresholds = override.Thresholds
+	}
+	if override.Unit != "" {
+		mc.Unit = override.Unit
+	}
+	if override.Throttle != nil {
+		mc.Throttle = *override.Throttle
+	}
+	if override.Mode != "" {
+		mc.Mode = override.Mode
+	}
+	return mc
+}
+
 // checkDiskThresholds checks disk usage against configured thresholds
 func checkDiskThresholds(config *Config, stats *SystemStats) ([]ThresholdViolation, error) {
 	var violations []ThresholdViolation
@@ -123,9 +243,6 @@ func checkDiskThresholds(config *Config, stats *SystemStats) ([]ThresholdViolati
 		return violations, nil
 	}
 
-	thresholds := metricConfig.Thresholds
-	warningThreshold := thresholds["warning"]
-	criticalThreshold := thresholds["critical"]
 	exclude := metricConfig.Exclude
 
 	for _, partition := range stats.DiskInfo.Partitions {
@@ -134,29 +251,71 @@ func checkDiskThresholds(config *Config, stats *SystemStats) ([]ThresholdViolati
 			continue
 		}
 
+		// Apply the most specific override for this partition
+		if override, ok := matchDiskOverride(metricConfig.Overrides, partition); ok {
+			log.Printf("Applying disk override (mountpoint=%q device=%q) to %s mounted at %s",
+				override.Mountpoint, override.Device, partition.Device, partition.Mountpoint)
+		}
+		partitionConfig := metricConfig.forPartition(partition)
+		warningThreshold := partitionConfig.Thresholds["warning"]
+		criticalThreshold := partitionConfig.Thresholds["critical"]
+
+		// Absolute units are evaluated against raw byte values
+		if !isPercentageUnit(partitionConfig.Unit) {
+			if violation, ok := checkDiskBytesThresholds(partitionConfig, partition); ok {
+				violations = append(violations, violation)
+			}
+			continue
+		}
+
 		percentage, err := strconv.ParseFloat(partition.Percentage, 64)
 		if err != nil {
 			return nil, fmt.Errorf("failed to parse disk usage for device %s: %w", partition.Device, err)
 		}
 
+		if partitionConfig.Mode == "min_free" {
+			// Thresholds represent minimum free space percentage
+			freePercent := 100 - percentage
+			if level, threshold, ok := compareThresholds(freePercent, warningThreshold, criticalThreshold, true); ok {
+				message := fmt.Sprintf("partition %s, mounted at %s has %.2f%% free (%s threshold: below %.2f%%)",
+					partition.Device, partition.Mountpoint, freePercent, level, threshold)
+				violations = append(violations, ThresholdViolation{
+					Metric:     "disk",
+					Level:      level,
+					Message:    message,
+					Value:      freePercent,
+					Threshold:  threshold,
+					Device:     partition.Device,
+					Mountpoint: partition.Mountpoint,
+				})
+			}
+			continue
+		}
+
 		// Check critical first (higher severity)
 		if criticalThreshold > 0 && percentage > criticalThreshold {
 			message := fmt.Sprintf("partition %s, mounted at %s is %.2f%% full (critical threshold: %.2f%%)",
 				partition.Device, partition.Mountpoint, percentage, criticalThreshold)
 			violations = append(violations, ThresholdViolation{
-				Metric:  "disk",
-				Level:   "critical",
-				Message: message,
-				Value:   percentage,
+				Metric:     "disk",
+				Level:      "critical",
+				Message:    message,
+				Value:      percentage,
+				Threshold:  criticalThreshold,
+				Device:     partition.Device,
+				Mountpoint: partition.Mountpoint,
 			})
 		} else if warningThreshold > 0 && percentage > warningThreshold {
 			message := fmt.Sprintf("partition %s, mounted at %s is %.2f%% full (warning threshold: %.2f%%)",
 				partition.Device, partition.Mountpoint, percentage, warningThreshold)
 			violations = append(violations, ThresholdViolation{
-				Metric:  "disk",
-				Level:   "warning",
-				Message: message,
-				Value:   percentage,
+				Metric:     "disk",
+				Level:      "warning",
+				Message:    message,
+				Value:      percentage,
+				Threshold:  warningThreshold,
+				Device:     partition.Device,
+				Mountpoint: partition.Mountpoint,
 			})
 		}
 	}
@@ -164,12 +323,72 @@ func checkDiskThresholds(config *Config, stats *SystemStats) ([]ThresholdViolati
 	return violations, nil
 }
 
+// checkDiskBytesThresholds checks a partition against thresholds in an absolute unit
+func checkDiskBytesThresholds(metricConfig MetricConfig, partition PartitionInfo) (ThresholdViolation, bool) {
+	warningThreshold := thresholdBytes(metricConfig.Thresholds["warning"], metricConfig.Unit)
+	criticalThreshold := thresholdBytes(metricConfig.Thresholds["critical"], metricConfig.Unit)
+
+	if metricConfig.Mode == "min_free" {
+		free := float64(partition.FreeBytes)
+		level, threshold, ok := compareThresholds(free, warningThreshold, criticalThreshold, true)
+		if !ok {
+			return ThresholdViolation{}, false
+		}
+		return ThresholdViolation{
+			Metric:     "disk",
+			Level:      level,
+			Device:     partition.Device,
+			Mountpoint: partition.Mountpoint,
+			Message: fmt.Sprintf("partition %s, mounted at %s has %s free (%s threshold: below %s)",
+				partition.Device, partition.Mountpoint, formatBytes(partition.FreeBytes), level, formatBytes(uint64(threshold))),
+			Value:     free,
+			Threshold: threshold,
+		}, true
+	}
+
+	used := float64(partition.UsedBytes)
+	level, threshold, ok := compareThresholds(used, warningThreshold, criticalThreshold, false)
+	if !ok {
+		return ThresholdViolation{}, false
+	}
+	return ThresholdViolation{
+		Metric:     "disk",
+		Level:      level,
+		Device:     partition.Device,
+		Mountpoint: partition.Mountpoint,
+		Message: fmt.Sprintf("partition %s, mounted at %s has %s used (%s threshold: %s)",
+			partition.Device, partition.Mountpoint, formatBytes(partition.UsedBytes), level, formatBytes(uint64(threshold))),
+		Value:     used,
+		Threshold: threshold,
+	}, true
+}
+
+// compareThresholds returns the violated level and its threshold for a value.
+// With below set, lower values are worse (minimum thresholds).
+func compareThresholds(value, warningThreshold, criticalThreshold float64, below bool) (string, float64, bool) {
+	exceeds := func(threshold float64) bool {
+		if below {
+			return value < threshold
+		}
+		return value > threshold
+	}
+
+	// Check critical first (higher severity)
+	if criticalThreshold > 0 && exceeds(criticalThreshold) {
+		return "critical", criticalThreshold, true
+	}
+	if warningThreshold > 0 && exceeds(warningThreshold) {
+		return "warning", warningThreshold, true
+	}
+	return "", 0, false
+}
+
 // checkCPUThresholds checks CPU usage against configured thresholds
 func checkCPUThresholds(config *Config, cpuUsage float64) []ThresholdViolation {
 	var violations []ThresholdViolation
 
 	metricConfig, ok := config.GetMetricConfig("cpu")
-	if !ok || !metricConfig.Enabled {
+	if !ok || !metricConfig.Enabled || metricConfig.Mode == "anomaly" {
 		return violations
 	}
 
@@ -181,18 +400,20 @@ func checkCPUThresholds(config *Config, cpuUsage float64) []ThresholdViolation {
 	if criticalThreshold > 0 && cpuUsage > criticalThreshold {
 		message := fmt.Sprintf("cpu usage: %.2f%% (critical threshold: %.2f%%)", cpuUsage, criticalThreshold)
 		violations = append(violations, ThresholdViolation{
-			Metric:  "cpu",
-			Level:   "critical",
-			Message: message,
-			Value:   cpuUsage,
+			Metric:    "cpu",
+			Level:     "critical",
+			Message:   message,
+			Value:     cpuUsage,
+			Threshold: criticalThreshold,
 		})
 	} else if warningThreshold > 0 && cpuUsage > warningThreshold {
 		message := fmt.Sprintf("cpu usage: %.2f%% (warning threshold: %.2f%%)", cpuUsage, warningThreshold)
 		violations = append(violations, ThresholdViolation{
-			Metric:  "cpu",
-			Level:   "warning",
-			Message: message,
-			Value:   cpuUsage,
+			Metric:    "cpu",
+			Level:     "warning",
+			Message:   message,
+			Value:     cpuUsage,
+			Threshold: warningThreshold,
 		})
 	}
 
@@ -204,7 +425,7 @@ func checkMemoryThresholds(config *Config, memUsed float64, memFree float64) []T
 	var violations []ThresholdViolation
 
 	metricConfig, ok := config.GetMetricConfig("memory")
-	if !ok || !metricConfig.Enabled {
+	if !ok || !metricConfig.Enabled || metricConfig.Mode == "anomaly" || !isPercentageUnit(metricConfig.Unit) {
 		return violations
 	}
 
@@ -227,19 +448,21 @@ func checkMemoryThresholds(config *Config, memUsed float64, memFree float64) []T
 			message := fmt.Sprintf("free memory: %.2f%% (critical threshold: below %.2f%%)",
 				freePercent, criticalThreshold)
 			violations = append(violations, ThresholdViolation{
-				Metric:  "memory",
-				Level:   "critical",
-				Message: message,
-				Value:   freePercent,
+				Metric:    "memory",
+				Level:     "critical",
+				Message:   message,
+				Value:     freePercent,
+				Threshold: criticalThreshold,
 			})
 		} else if warningThreshold > 0 && freePercent < warningThreshold {
 			message := fmt.Sprintf("free memory: %.2f%% (warning threshold: below %.2f%%)",
 				freePercent, warningThreshold)
 			violations = append(violations, ThresholdViolation{
-				Metric:  "memory",
-				Level:   "warning",
-				Message: message,
-				Value:   freePercent,
+				Metric:    "memory",
+				Level:     "warning",
+				Message:   message,
+				Value:     freePercent,
+				Threshold: warningThreshold,
 			})
 		}
 	} else {
@@ -252,21 +475,63 @@ func checkMemoryThresholds(config *Config, memUsed float64, memFree float64) []T
 			message := fmt.Sprintf("memory used: %.2f%% (critical threshold: %.2f%%)",
 				usedPercent, criticalThreshold)
 			violations = append(violations, ThresholdViolation{
-				Metric:  "memory",
-				Level:   "critical",
-				Message: message,
-				Value:   usedPercent,
+				Metric:    "memory",
+				Level:     "critical",
+				Message:   message,
+				Value:     usedPercent,
+				Threshold: criticalThreshold,
 			})
 		} else if warningThreshold > 0 && usedPercent > warningThreshold {
 			message := fmt.Sprintf("memory used: %.2f%% (warning threshold: %.2f%%)",
 				usedPercent, warningThreshold)
 			violations = append(violations, ThresholdViolation{
-				Metric:  "memory",
-				Level:   "warning",
-				Message: message,
-				Value:   usedPercent,
+				Metric:    "memory",
+				Level:     "warning",
+				Message:   message,
+				Value:     usedPercent,
+				Threshold: warningThreshold,
+			})
+		}
+	}
+
+	return violations
+}
+
+// checkMemoryBytesThresholds checks memory against thresholds in an absolute unit
+func checkMemoryBytesThresholds(config *Config, totalBytes uint64, availableBytes uint64) []ThresholdViolation {
+	var violations []ThresholdViolation
+
+	metricConfig, ok := config.GetMetricConfig("memory")
+	if !ok || !metricConfig.Enabled || isPercentageUnit(metricConfig.Unit) {
+		return violations
+	}
+
+	warningThreshold := thresholdBytes(metricConfig.Thresholds["warning"], metricConfig.Unit)
+	criticalThreshold := thresholdBytes(metricConfig.Thresholds["critical"], metricConfig.Unit)
+
+	if metricConfig.Mode == "max_used" {
+		usedBytes := totalBytes - availableBytes
+		if level, threshold, ok := compareThresholds(float64(usedBytes), warningThreshold, criticalThreshold, false); ok {
+			violations = append(violations, ThresholdViolation{
+				Metric:    "memory",
+				Level:     level,
+				Message:   fmt.Sprintf("memory used: %s (%s threshold: %s)", formatBytes(usedBytes), level, formatBytes(uint64(threshold))),
+				Value:     float64(usedBytes),
+				Threshold: threshold,
 			})
 		}
+		return violations
+	}
+
+	// mode == "min_free" (default)
+	if level, threshold, ok := compareThresholds(float64(availableBytes), warningThreshold, criticalThreshold, true); ok {
+		violations = append(violations, ThresholdViolation{
+			Metric:    "memory",
+			Level:     level,
+			Message:   fmt.Sprintf("free memory: %s (%s threshold: below %s)", formatBytes(availableBytes), level, formatBytes(uint64(threshold))),
+			Value:     float64(availableBytes),
+			Threshold: threshold,
+		})
 	}
 
 	return violations
@@ -277,13 +542,38 @@ func applyThrottling(config *Config, violations []ThresholdViolation, stateManag
 	var throttled []ThresholdViolation
 
 	for _, violation := range violations {
-		throttleConfig := config.GetThrottleConfig(violation.Metric)
+		throttleConfig := config.GetViolationThrottleConfig(violation)
 		minDuration := throttleConfig.MinDurationMinutes
 		repeat := throttleConfig.Repeat
 		repeatInterval := throttleConfig.RepeatInterval
 
 		// Get or create state
-		state := stateManager.GetOrCreate(violation.Metric, violation.Level)
+		state := stateManager.GetOrCreateFor(violation)
+		state.Update(violation)
+
+		// Silenced and acknowledged violations do not alert
+		if reason := stateManager.suppressed(violation, time.Now()); reason != "" {
+			log.Printf("Throttle: %s/%s suppressed (%s)", violation.Metric, violation.Level, reason)
+			continue
+		}
+
+		// Maintenance windows hold notifications; violations first seen during a window
+		// and still active when it ends are reported then
+		if window, ok := config.maintenanceWindow(violation, time.Now()); ok {
+			if !state.HasAlerted {
+				state.HeldByMaintenance = window
+			}
+			log.Printf("Throttle: %s/%s held by maintenance window %s", violation.Metric, violation.Level, window)
+			continue
+		}
+		if state.HeldByMaintenance != "" && state.DurationMinutes() >= minDuration {
+			log.Printf("Throttle: %s/%s reported after maintenance window %s ended",
+				violation.Metric, violation.Level, state.HeldByMaintenance)
+			state.HeldByMaintenance = ""
+			throttled = append(throttled, violation)
+			markAlerted(config, state)
+			continue
+		}
 
 		// Check if we should alert
 		shouldAlert, err := state.ShouldAlert(minDuration, repeat, repeatInterval)
@@ -292,7 +582,7 @@ func applyThrottling(config *Config, violations []ThresholdViolation, stateManag
 		}
 		if shouldAlert {
 			throttled = append(throttled, violation)
-			state.MarkAlerted()
+			markAlerted(config, state)
 			log.Printf("Throttle: %s/%s will alert (duration %.1fm >= %.1fm)",
 				violation.Metric, violation.Level, state.DurationMinutes(), minDuration)
 		} else {
@@ -304,13 +594,23 @@ func applyThrottling(config *Config, violations []ThresholdViolation, stateManag
 	return throttled, nil
 }
 
+// markAlerted marks that a violation passed throttling; without grouping, its
+// notification is sent in this check
+func markAlerted(config *Config, state *ViolationState) {
+	state.MarkAlerted()
+	if !config.Grouping.enabled() {
+		state.MarkNotified()
+	}
+}
+
 // clearResolvedViolations clears state for metrics that are no longer violating
 func clearResolvedViolations(currentViolations []ThresholdViolation, stateManager *StateManager) error {
-	// Get currently violating metric/level combinations
+	// Get currently violating metric/level/mountpoint combinations
 	currentKeys := make(map[string]bool)
+	stillViolating := make(map[[2]string]bool) // metric and mountpoint
 	for _, v := range currentViolations {
-		key := fmt.Sprintf("%s_%s", v.Metric, v.Level)
-		currentKeys[key] = true
+		currentKeys[v.StateKey()] = true
+		stillViolating[[2]string{v.Metric, v.Mountpoint}] = true
 	}
 
 	// Get all state keys and check which ones are no longer violating
@@ -321,10 +621,14 @@ func clearResolvedViolations(currentViolations []ThresholdViolation, stateManage
 		}
 	}
 
-	// Clear non-violating states
+	// Clear non-violating states; notified ones are resolved unless the
+	// metric is still violating at another level
 	for _, key := range keysToClear {
 		if state, ok := stateManager.States[key]; ok {
-			if err := stateManager.Clear(state.Metric, state.Level); err != nil {
+			if state.Notified && !stillViolating[[2]string{state.Metric, state.Mountpoint}] {
+				stateManager.Resolved = append(stateManager.Resolved, state.Violation())
+			}
+			if err := stateManager.clearKey(key); err != nil {
 				return fmt.Errorf("failed to clear state for %s/%s: %w", state.Metric, state.Level, err)
 			}
 		}
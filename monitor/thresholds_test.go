@@ -655,3 +655,132 @@ func TestCheckAllThresholds(t *testing.T) {
 		}
 	}
 }
+
+// TestMatchDiskOverride tests that the most specific override wins
+func TestMatchDiskOverride(t *testing.T) {
+	overrides := []DiskOverride{
+		{Mountpoint: "/var/lib/*", Thresholds: map[string]float64{"warning": 75, "critical": 85}},
+		{Mountpoint: "/var/lib/docker", Thresholds: map[string]float64{"warning": 85, "critical": 95}},
+		{Mountpoint: "/boot", Thresholds: map[string]float64{"warning": 70, "critical": 80}},
+		{Device: "/dev/nvme*", Thresholds: map[string]float64{"warning": 60, "critical": 70}},
+		{Device: "/dev/nvme0n1p2", Mountpoint: "/var/lib/*", Thresholds: map[string]float64{"warning": 50, "critical": 55}},
+	}
+
+	tests := []struct {
+		name      string
+		partition PartitionInfo
+		wantIndex int
+	}{
+		{name: "exact mountpoint beats glob", partition: PartitionInfo{Device: "/dev/sdb1", Mountpoint: "/var/lib/docker"}, wantIndex: 1},
+		{name: "glob mountpoint", partition: PartitionInfo{Device: "/dev/sdb1", Mountpoint: "/var/lib/postgres"}, wantIndex: 0},
+		{name: "boot", partition: PartitionInfo{Device: "/dev/sda1", Mountpoint: "/boot"}, wantIndex: 2},
+		{name: "device glob", partition: PartitionInfo{Device: "/dev/nvme1n1", Mountpoint: "/data"}, wantIndex: 3},
+		{name: "device and mountpoint combined", partition: PartitionInfo{Device: "/dev/nvme0n1p2", Mountpoint: "/var/lib/mysql"}, wantIndex: 4},
+		{name: "no match", partition: PartitionInfo{Device: "/dev/sda2", Mountpoint: "/"}, wantIndex: -1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := matchDiskOverride(overrides, tt.partition)
+			if tt.wantIndex < 0 {
+				if ok {
+					t.Errorf("matchDiskOverride() matched %+v, want no match", *got)
+				}
+				return
+			}
+			if !ok {
+				t.Fatalf("matchDiskOverride() found no match, want override %d", tt.wantIndex)
+			}
+			if got != &overrides[tt.wantIndex] {
+				t.Errorf("matchDiskOverride() = %+v, want override %d", *got, tt.wantIndex)
+			}
+		})
+	}
+}
+
+// TestCheckDiskThresholdsOverrides tests per-mountpoint thresholds
+func TestCheckDiskThresholdsOverrides(t *testing.T) {
+	config := &Config{
+		Metrics: map[string]MetricConfig{
+			"disk": {
+				Enabled:    true,
+				Thresholds: map[string]float64{"warning": 80, "critical": 90},
+				Overrides: []DiskOverride{
+					{Mountpoint: "/var/lib/docker", Thresholds: map[string]float64{"warning": 85, "critical": 95}},
+					{Mountpoint: "/boot", Thresholds: map[string]float64{"warning": 70, "critical": 80}},
+				},
+			},
+		},
+	}
+
+	stats := &SystemStats{
+		DiskInfo: DiskInfo{
+			Partitions: []PartitionInfo{
+				{Device: "/dev/sda2", Mountpoint: "/", Percentage: "82"},
+				{Device: "/dev/sdb1", Mountpoint: "/var/lib/docker", Percentage: "82"},
+				{Device: "/dev/sda1", Mountpoint: "/boot", Percentage: "82"},
+			},
+		},
+	}
+
+	violations, err := checkDiskThresholds(config, stats)
+	if err != nil {
+		t.Fatalf("checkDiskThresholds() error = %v", err)
+	}
+
+	got := make(map[string]string)
+	for _, v := range violations {
+		got[v.Mountpoint] = v.Level
+	}
+	want := map[string]string{"/": "warning", "/boot": "critical"}
+	if len(got) != len(want) {
+		t.Fatalf("checkDiskThresholds() = %v, want %v", got, want)
+	}
+	for mountpoint, level := range want {
+		if got[mountpoint] != level {
+			t.Errorf("mountpoint %s level = %q, want %q", mountpoint, got[mountpoint], level)
+		}
+	}
+}
+
+// TestOverrideThrottle tests that overrides carry their own throttle settings
+func TestOverrideThrottle(t *testing.T) {
+	config := &Config{
+		Metrics: map[string]MetricConfig{
+			"disk": {
+				Enabled:    true,
+				Thresholds: map[string]float64{"warning": 80, "critical": 90},
+				Throttle:   ThrottleConfig{MinDurationMinutes: 0},
+				Overrides: []DiskOverride{
+					{Mountpoint: "/var/lib/docker", Throttle: &ThrottleConfig{MinDurationMinutes: 30}},
+				},
+			},
+		},
+	}
+
+	sm := &StateManager{
+		StateFile: t.TempDir() + "/state.json",
+		States:    make(map[string]*ViolationState),
+	}
+
+	violations := []ThresholdViolation{
+		{Metric: "disk", Level: "warning", Device: "/dev/sda2", Mountpoint: "/"},
+		{Metric: "disk", Level: "warning", Device: "/dev/sdb1", Mountpoint: "/var/lib/docker"},
+	}
+
+	throttled, err := applyThrottling(config, violations, sm)
+	if err != nil {
+		t.Fatalf("applyThrottling() error = %v", err)
+	}
+	if len(throttled) != 1 || throttled[0].Mountpoint != "/" {
+		t.Errorf("applyThrottling() = %v, want only the / violation", throttled)
+	}
+
+	// Each mountpoint is tracked in its own state
+	if len(sm.States) != 2 {
+		t.Errorf("state count = %d, want 2", len(sm.States))
+	}
+	if _, ok := sm.States["disk_warning_/var/lib/docker"]; !ok {
+		t.Errorf("missing per-mountpoint state key, have %v", sm.States)
+	}
+}
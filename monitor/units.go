@@ -0,0 +1,190 @@
+package monitor
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// UnitPercentage is the default threshold unit
+const UnitPercentage = "percentage"
+
+// unitFactors maps absolute threshold units to their size in bytes
+var unitFactors = map[string]float64{
+	"bytes": 1,
+	"b":     1,
+	"kb":    1e3,
+	"mb":    1e6,
+	"gb":    1e9,
+	"tb":    1e12,
+	"kib":   1 << 10,
+	"mib":   1 << 20,
+	"gib":   1 << 30,
+	"tib":   1 << 40,
+}
+
+// isPercentageUnit reports whether a unit means percentages (the default)
+func isPercentageUnit(unit string) bool {
+	return unit == "" || unit == UnitPercentage
+}
+
+// unitFactor returns the size of an absolute unit in bytes
+func unitFactor(unit string) (float64, bool) {
+	factor, ok := unitFactors[strings.ToLower(unit)]
+	return factor, ok
+}
+
+// validateUnit checks that a unit is percentage or a known absolute unit
+func validateUnit(unit string) error {
+	if isPercentageUnit(unit) {
+		return nil
+	}
+	if _, ok := unitFactor(unit); !ok {
+		return fmt.Errorf("unknown unit '%s' (use percentage, bytes, KB, MB, GB, TB, KiB, MiB, GiB or TiB)", unit)
+	}
+	return nil
+}
+
+// parseSize parses a size like "10GiB", "1.5 TB" or "512" into bytes.
+// A bare number is interpreted in defaultUnit.
+func parseSize(s string, defaultUnit string) (float64, error) {
+	s = strings.TrimSpace(s)
+	i := 0
+	for i < len(s) && (s[i] >= '0' && s[i] <= '9' || s[i] == '.') {
+		i++
+	}
+	if i == 0 {
+		return 0, fmt.Errorf("invalid size '%s'", s)
+	}
+
+	value, err := strconv.ParseFloat(s[:i], 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid size '%s': %w", s, err)
+	}
+
+	unit := strings.TrimSpace(s[i:])
+	if unit == "" {
+		unit = defaultUnit
+	}
+	factor, ok := unitFactor(unit)
+	if !ok {
+		return 0, fmt.Errorf("invalid size '%s': unknown unit '%s'", s, unit)
+	}
+	return value * factor, nil
+}
+
+// thresholdBytes converts a threshold expressed in an absolute unit to bytes
+func thresholdBytes(value float64, unit string) float64 {
+	factor, ok := unitFactor(unit)
+	if !ok {
+		return value
+	}
+	return value * factor
+}
+
+// normalizeThresholdUnits rewrites size strings such as "10GiB" in the raw YAML
+// metrics section into plain numbers expressed in each metric's unit.
+// A metric without a unit that uses size strings gets unit "bytes".
+func normalizeThresholdUnits(rawMap map[interface{}]interface{}) error {
+	metricsRaw, ok := rawMap["metrics"].(map[interface{}]interface{})
+	if !ok {
+		return nil
+	}
+
+	for metricName, metricRaw := range metricsRaw {
+		metricConfig, ok := metricRaw.(map[interface{}]interface{})
+		if !ok {
+			continue
+		}
+
+		unit, _ := metricConfig["unit"].(string)
+		if unit == "" && hasSizeStrings(metricConfig["thresholds"]) {
+			unit = "bytes"
+			metricConfig["unit"] = unit
+		}
+
+		where := fmt.Sprintf("metric '%v'", metricName)
+		if err := normalizeThresholdMap(metricConfig["thresholds"], unit, where); err != nil {
+			return err
+		}
+
+		// Schedules always use the metric unit
+		schedulesRaw, _ := metricConfig["schedules"].([]interface{})
+		for i, scheduleVal := range schedulesRaw {
+			scheduleRaw, ok := scheduleVal.(map[interface{}]interface{})
+			if !ok {
+				continue
+			}
+			where := fmt.Sprintf("schedule %d of metric '%v'", i, metricName)
+			if err := normalizeThresholdMap(scheduleRaw["thresholds"], unit, where); err != nil {
+				return err
+			}
+		}
+
+		// Overrides inherit the metric unit unless they set their own
+		overridesRaw, _ := metricConfig["overrides"].([]interface{})
+		for i, overrideVal := range overridesRaw {
+			overrideRaw, ok := overrideVal.(map[interface{}]interface{})
+			if !ok {
+				continue
+			}
+			overrideUnit, _ := overrideRaw["unit"].(string)
+			if overrideUnit == "" {
+				overrideUnit = unit
+			}
+			if overrideUnit == "" && hasSizeStrings(overrideRaw["thresholds"]) {
+				overrideUnit = "bytes"
+				overrideRaw["unit"] = overrideUnit
+			}
+			where := fmt.Sprintf("override %d of metric '%v'", i, metricName)
+			if err := normalizeThresholdMap(overrideRaw["thresholds"], overrideUnit, where); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// hasSizeStrings reports whether a raw thresholds map contains string values
+func hasSizeStrings(thresholdsVal interface{}) bool {
+	thresholdsRaw, ok := thresholdsVal.(map[interface{}]interface{})
+	if !ok {
+		return false
+	}
+	for _, value := range thresholdsRaw {
+		if _, ok := value.(string); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// normalizeThresholdMap converts size strings in a raw thresholds map to numbers in unit
+func normalizeThresholdMap(thresholdsVal interface{}, unit string, where string) error {
+	thresholdsRaw, ok := thresholdsVal.(map[interface{}]interface{})
+	if !ok {
+		return nil
+	}
+
+	for level, value := range thresholdsRaw {
+		str, ok := value.(string)
+		if !ok {
+			continue
+		}
+		if isPercentageUnit(unit) {
+			return fmt.Errorf("threshold '%v' of %s is a size (%s) but the unit is percentage", level, where, str)
+		}
+		factor, ok := unitFactor(unit)
+		if !ok {
+			return fmt.Errorf("unknown unit '%s' in %s", unit, where)
+		}
+		bytes, err := parseSize(str, unit)
+		if err != nil {
+			return fmt.Errorf("threshold '%v' of %s: %w", level, where, err)
+		}
+		thresholdsRaw[level] = bytes / factor
+	}
+
+	return nil
+}
@@ -0,0 +1,283 @@
+package monitor
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const gib = 1 << 30
+
+// TestParseSize tests parsing sizes with units
+func TestParseSize(t *testing.T) {
+	tests := []struct {
+		name        string
+		input       string
+		defaultUnit string
+		want        float64
+		wantErr     bool
+	}{
+		{name: "GiB", input: "10GiB", defaultUnit: "bytes", want: 10 * gib},
+		{name: "GB with space", input: "1.5 GB", defaultUnit: "bytes", want: 1.5e9},
+		{name: "lowercase unit", input: "512mib", defaultUnit: "bytes", want: 512 << 20},
+		{name: "bare number uses default unit", input: "2", defaultUnit: "GiB", want: 2 * gib},
+		{name: "bytes", input: "100B", defaultUnit: "GiB", want: 100},
+		{name: "unknown unit", input: "10XB", defaultUnit: "bytes", wantErr: true},
+		{name: "no number", input: "GiB", defaultUnit: "bytes", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseSize(tt.input, tt.defaultUnit)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseSize() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && got != tt.want {
+				t.Errorf("parseSize() = %f, want %f", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestLoadConfigSizeThresholds tests that size thresholds are converted into the metric's unit
+func TestLoadConfigSizeThresholds(t *testing.T) {
+	tests := []struct {
+		name         string
+		yaml         string
+		metric       string
+		wantUnit     string
+		wantWarning  float64
+		wantCritical float64
+		wantErr      bool
+	}{
+		{
+			name: "sizes in GiB unit",
+			yaml: `
+metrics:
+  disk:
+    enabled: true
+    mode: min_free
+    unit: GiB
+    thresholds:
+      warning: 20
+      critical: 512MiB
+`,
+			metric:       "disk",
+			wantUnit:     "GiB",
+			wantWarning:  20,
+			wantCritical: 0.5,
+		},
+		{
+			name: "unit inferred from sizes",
+			yaml: `
+metrics:
+  memory:
+    enabled: true
+    mode: min_free
+    thresholds:
+      warning: 2GiB
+      critical: 1GiB
+`,
+			metric:       "memory",
+			wantUnit:     "bytes",
+			wantWarning:  2 * gib,
+			wantCritical: 1 * gib,
+		},
+		{
+			name: "size with percentage unit",
+			yaml: `
+metrics:
+  disk:
+    enabled: true
+    unit: percentage
+    thresholds:
+      warning: 10GiB
+      critical: 90
+`,
+			wantErr: true,
+		},
+		{
+			name: "unknown unit",
+			yaml: `
+metrics:
+  disk:
+    enabled: true
+    unit: parsecs
+    thresholds:
+      warning: 80
+      critical: 90
+`,
+			wantErr: true,
+		},
+		{
+			name: "absolute unit on cpu",
+			yaml: `
+metrics:
+  cpu:
+    enabled: true
+    unit: GiB
+    thresholds:
+      warning: 80
+      critical: 90
+`,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "config.yaml")
+			if err := os.WriteFile(path, []byte(tt.yaml), 0644); err != nil {
+				t.Fatalf("failed to write config: %v", err)
+			}
+
+			config, err := LoadConfig(path)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("LoadConfig() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+
+			mc := config.Metrics[tt.metric]
+			if mc.Unit != tt.wantUnit {
+				t.Errorf("unit = %s, want %s", mc.Unit, tt.wantUnit)
+			}
+			if mc.Thresholds["warning"] != tt.wantWarning {
+				t.Errorf("warning = %f, want %f", mc.Thresholds["warning"], tt.wantWarning)
+			}
+			if mc.Thresholds["critical"] != tt.wantCritical {
+				t.Errorf("critical = %f, want %f", mc.Thresholds["critical"], tt.wantCritical)
+			}
+		})
+	}
+}
+
+// TestCheckDiskBytesThresholds tests disk thresholds evaluated against raw byte values
+func TestCheckDiskBytesThresholds(t *testing.T) {
+	bigVolume := PartitionInfo{
+		Device:     "/dev/md0",
+		Mountpoint: "/data",
+		Percentage: "90",
+		TotalBytes: 20000 * gib,
+		UsedBytes:  18000 * gib,
+		FreeBytes:  2000 * gib,
+	}
+	smallRoot := PartitionInfo{
+		Device:     "/dev/sda1",
+		Mountpoint: "/",
+		Percentage: "90",
+		TotalBytes: 20 * gib,
+		UsedBytes:  18 * gib,
+		FreeBytes:  2 * gib,
+	}
+
+	tests := []struct {
+		name      string
+		mc        MetricConfig
+		partition PartitionInfo
+		wantLevel string
+	}{
+		{
+			name:      "large volume with plenty free",
+			mc:        MetricConfig{Enabled: true, Mode: "min_free", Unit: "GiB", Thresholds: map[string]float64{"warning": 10, "critical": 5}},
+			partition: bigVolume,
+		},
+		{
+			name:      "small root nearly full",
+			mc:        MetricConfig{Enabled: true, Mode: "min_free", Unit: "GiB", Thresholds: map[string]float64{"warning": 10, "critical": 5}},
+			partition: smallRoot,
+			wantLevel: "critical",
+		},
+		{
+			name:      "max used in bytes",
+			mc:        MetricConfig{Enabled: true, Unit: "GiB", Thresholds: map[string]float64{"warning": 15, "critical": 19}},
+			partition: smallRoot,
+			wantLevel: "warning",
+		},
+		{
+			name:      "min free percentage",
+			mc:        MetricConfig{Enabled: true, Mode: "min_free", Thresholds: map[string]float64{"warning": 15, "critical": 5}},
+			partition: smallRoot,
+			wantLevel: "warning",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := &Config{Metrics: map[string]MetricConfig{"disk": tt.mc}}
+			stats := &SystemStats{DiskInfo: DiskInfo{Partitions: []PartitionInfo{tt.partition}}}
+
+			violations, err := checkDiskThresholds(config, stats)
+			if err != nil {
+				t.Fatalf("checkDiskThresholds() error = %v", err)
+			}
+			if tt.wantLevel == "" {
+				if len(violations) != 0 {
+					t.Errorf("checkDiskThresholds() got %d violations, want 0", len(violations))
+				}
+				return
+			}
+			if len(violations) != 1 {
+				t.Fatalf("checkDiskThresholds() got %d violations, want 1", len(violations))
+			}
+			if violations[0].Level != tt.wantLevel {
+				t.Errorf("checkDiskThresholds() level = %s, want %s (%s)", violations[0].Level, tt.wantLevel, violations[0].Message)
+			}
+		})
+	}
+}
+
+// TestCheckMemoryBytesThresholds tests memory thresholds in absolute units
+func TestCheckMemoryBytesThresholds(t *testing.T) {
+	tests := []struct {
+		name      string
+		mc        MetricConfig
+		total     uint64
+		available uint64
+		wantLevel string
+	}{
+		{
+			name:      "percentage unit is ignored",
+			mc:        MetricConfig{Enabled: true, Mode: "min_free", Thresholds: map[string]float64{"warning": 20, "critical": 5}},
+			total:     16 * gib,
+			available: 1,
+		},
+		{
+			name:      "min free warning",
+			mc:        MetricConfig{Enabled: true, Mode: "min_free", Unit: "GiB", Thresholds: map[string]float64{"warning": 2, "critical": 0.5}},
+			total:     16 * gib,
+			available: 1 * gib,
+			wantLevel: "warning",
+		},
+		{
+			name:      "max used critical",
+			mc:        MetricConfig{Enabled: true, Mode: "max_used", Unit: "GiB", Thresholds: map[string]float64{"warning": 12, "critical": 14}},
+			total:     16 * gib,
+			available: 1 * gib,
+			wantLevel: "critical",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := &Config{Metrics: map[string]MetricConfig{"memory": tt.mc}}
+			violations := checkMemoryBytesThresholds(config, tt.total, tt.available)
+			if tt.wantLevel == "" {
+				if len(violations) != 0 {
+					t.Errorf("checkMemoryBytesThresholds() got %d violations, want 0", len(violations))
+				}
+				return
+			}
+			if len(violations) != 1 || violations[0].Level != tt.wantLevel {
+				t.Errorf("checkMemoryBytesThresholds() = %v, want one %s violation", violations, tt.wantLevel)
+			}
+		})
+	}
+
+	// Percentage checks must not fire for absolute units
+	config := &Config{Metrics: map[string]MetricConfig{"memory": tests[1].mc}}
+	if v := checkMemoryThresholds(config, 99, 1); len(v) != 0 {
+		t.Errorf("checkMemoryThresholds() with absolute unit got %d violations, want 0", len(v))
+	}
+}
@@ -0,0 +1,232 @@
+package monitor
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// Content types used by the webhook encodings
+const (
+	contentTypeJSON = "application/json"
+	contentTypeForm = "application/x-www-form-urlencoded"
+)
+
+// WebhookAction sends alerts via HTTP webhook
+type WebhookAction struct {
+	URL         string
+	Method      string // defaults to POST
+	Encoding    string // "json" (default) or "form"
+	ContentType string // defaults to the encoding's content type
+	Headers     map[string]string
+	Body        *template.Template // nil sends the default payload
+	Secret      string             // signs deliveries with HMAC-SHA256 when set
+	Timeout     time.Duration
+	Retry       int
+
+	ctx context.Context // cancels requests, see setContext
+}
+
+// WebhookData is passed to webhook body templates
+type WebhookData struct {
+	Violation ThresholdViolation
+	Host      string
+	Time      time.Time
+	Stats     *SystemStats // snapshot the violation was detected in; nil for resolved violations
+}
+
+// webhookTemplateFuncs are available in webhook body templates
+var webhookTemplateFuncs = template.FuncMap{
+	"upper": strings.ToUpper,
+	"lower": strings.ToLower,
+	"json": func(v interface{}) (string, error) {
+		data, err := json.Marshal(v)
+		return string(data), err
+	},
+}
+
+// NewWebhookAction creates a new webhook alert action
+func NewWebhookAction(config map[string]interface{}) (*WebhookAction, error) {
+	wa := &WebhookAction{
+		Timeout: 5 * time.Second,
+		Retry:   1,
+	}
+
+	if url, ok := config["url"].(string); ok {
+		wa.URL = url
+	} else {
+		return nil, fmt.Errorf("webhook action requires 'url' field")
+	}
+
+	if method, ok := config["method"].(string); ok {
+		wa.Method = strings.ToUpper(method)
+		if wa.Method != http.MethodPost && wa.Method != http.MethodPut && wa.Method != http.MethodPatch {
+			return nil, fmt.Errorf("webhook action 'method' must be POST, PUT or PATCH")
+		}
+	}
+
+	if encoding, ok := config["encoding"].(string); ok {
+		if encoding != "json" && encoding != "form" {
+			return nil, fmt.Errorf("webhook action 'encoding' must be 'json' or 'form'")
+		}
+		wa.Encoding = encoding
+	}
+
+	if contentType, ok := config["content_type"].(string); ok {
+		wa.ContentType = contentType
+	}
+
+	headers, err := actionStringMap(config, "headers")
+	if err != nil {
+		return nil, fmt.Errorf("webhook action %w", err)
+	}
+	for name, value := range headers {
+		headers[name] = os.ExpandEnv(value)
+	}
+	wa.Headers = headers
+
+	if secret, ok := config["secret"].(string); ok {
+		wa.Secret = os.ExpandEnv(secret)
+	}
+
+	if body, ok := config["body"].(string); ok {
+		tmpl, err := template.New("body").Funcs(webhookTemplateFuncs).Option("missingkey=error").Parse(body)
+		if err != nil {
+			return nil, fmt.Errorf("invalid webhook body template: %w", err)
+		}
+		wa.Body = tmpl
+	}
+
+	if timeout, ok := actionNumber(config, "timeout"); ok {
+		wa.Timeout = time.Duration(timeout) * time.Second
+	}
+
+	if retry, ok := actionNumber(config, "retry"); ok {
+		wa.Retry = int(retry)
+	}
+
+	return wa, nil
+}
+
+// setContext makes requests stop when ctx is cancelled
+func (wa *WebhookAction) setContext(ctx context.Context) {
+	wa.ctx = ctx
+}
+
+// Execute sends alert via webhook
+func (wa *WebhookAction) Execute(violation ThresholdViolation) error {
+	body, err := wa.render(violation, time.Now())
+	if err != nil {
+		return err
+	}
+
+	method := wa.Method
+	if method == "" {
+		method = http.MethodPost
+	}
+	contentType := wa.ContentType
+	if contentType == "" {
+		contentType = contentTypeJSON
+		if wa.Encoding == "form" {
+			contentType = contentTypeForm
+		}
+	}
+
+	var lastError error
+	for attempt := 0; attempt < wa.Retry; attempt++ {
+		req, err := http.NewRequestWithContext(actionContext(wa.ctx), method, wa.URL, bytes.NewReader(body))
+		if err != nil {
+			return fmt.Errorf("failed to create webhook request: %w", err)
+		}
+		req.Header.Set("Content-Type", contentType)
+		for name, value := range wa.Headers {
+			req.Header.Set(name, value)
+		}
+		if wa.Secret != "" {
+			// Sign each attempt with a fresh timestamp so retries pass the receiver's replay window
+			timestamp := time.Now().Unix()
+			req.Header.Set(TimestampHeader, strconv.FormatInt(timestamp, 10))
+			req.Header.Set(SignatureHeader, SignWebhook(wa.Secret, timestamp, body))
+		}
+
+		client := &http.Client{Timeout: wa.Timeout}
+		resp, err := client.Do(req)
+		if err != nil {
+			lastError = err
+			log.Printf("Webhook alert failed (attempt %d/%d): %v", attempt+1, wa.Retry, err)
+			continue
+		}
+
+		if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+			log.Printf("Webhook alert sent to %s: %s/%s", wa.URL, violation.Metric, violation.Level)
+			resp.Body.Close()
+			return nil
+		}
+
+		resp.Body.Close()
+		lastError = fmt.Errorf("webhook returned status %d", resp.StatusCode)
+		log.Printf("Webhook alert failed (attempt %d/%d): %v", attempt+1, wa.Retry, lastError)
+	}
+
+	return fmt.Errorf("failed to send webhook alert after %d attempts: %w", wa.Retry, lastError)
+}
+
+// render builds the request body from the body template, or the default payload
+func (wa *WebhookAction) render(violation ThresholdViolation, now time.Time) ([]byte, error) {
+	if wa.Body != nil {
+		hostname, _ := os.Hostname()
+		data := WebhookData{
+			Violation: violation,
+			Host:      hostname,
+			Time:      now,
+			Stats:     violation.Stats,
+		}
+
+		var buf bytes.Buffer
+		if err := wa.Body.Execute(&buf, data); err != nil {
+			return nil, fmt.Errorf("failed to render webhook body: %w", err)
+		}
+		return buf.Bytes(), nil
+	}
+
+	if wa.Encoding == "form" {
+		form := url.Values{}
+		form.Set("metric", violation.Metric)
+		form.Set("level", violation.Level)
+		form.Set("message", violation.Message)
+		form.Set("value", fmt.Sprintf("%g", violation.Value))
+		if violation.Mountpoint != "" {
+			form.Set("mountpoint", violation.Mountpoint)
+			form.Set("device", violation.Device)
+		}
+		return []byte(form.Encode()), nil
+	}
+
+	payload := map[string]interface{}{
+		"metric":  violation.Metric,
+		"level":   violation.Level,
+		"message": violation.Message,
+		"value":   violation.Value,
+	}
+	if violation.Mountpoint != "" {
+		payload["mountpoint"] = violation.Mountpoint
+	}
+	if violation.Device != "" {
+		payload["device"] = violation.Device
+	}
+
+	jsonData, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal webhook payload: %w", err)
+	}
+	return jsonData, nil
+}
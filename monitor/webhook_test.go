@@ -0,0 +1,164 @@
+package monitor
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+// webhookRequest is a request captured by a test webhook server
+type webhookRequest struct {
+	Method string
+	Header http.Header
+	Body   string
+}
+
+func newWebhookServer(t *testing.T, requests *[]webhookRequest) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		*requests = append(*requests, webhookRequest{Method: r.Method, Header: r.Header, Body: string(body)})
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+// TestWebhookTemplateBody tests templated bodies, methods and headers
+func TestWebhookTemplateBody(t *testing.T) {
+	t.Setenv("WEBHOOK_TOKEN", "s3cret")
+
+	var requests []webhookRequest
+	server := newWebhookServer(t, &requests)
+
+	action, err := NewWebhookAction(map[string]interface{}{
+		"url":    server.URL,
+		"method": "put",
+		"headers": map[interface{}]interface{}{
+			"Authorization": "Bearer ${WEBHOOK_TOKEN}",
+			"X-Source":      "tfc",
+		},
+		"body": `{"text": {{json .Violation.Message}}, "level": "{{upper .Violation.Level}}", "cores": {{.Stats.CPUInfo.TotalCores}}}`,
+	})
+	if err != nil {
+		t.Fatalf("NewWebhookAction() error = %v", err)
+	}
+
+	violation := ThresholdViolation{
+		Metric:  "cpu",
+		Level:   "warning",
+		Message: `CPU usage is "high"`,
+		Value:   85,
+		Stats:   &SystemStats{CPUInfo: CPUInfo{TotalCores: 8}},
+	}
+	if err := action.Execute(violation); err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+
+	req := requests[0]
+	if req.Method != http.MethodPut {
+		t.Errorf("method = %s, want PUT", req.Method)
+	}
+	if got := req.Header.Get("Authorization"); got != "Bearer s3cret" {
+		t.Errorf("Authorization = %q", got)
+	}
+	if got := req.Header.Get("X-Source"); got != "tfc" {
+		t.Errorf("X-Source = %q", got)
+	}
+	if got := req.Header.Get("Content-Type"); got != contentTypeJSON {
+		t.Errorf("Content-Type = %q", got)
+	}
+	want := `{"text": "CPU usage is \"high\"", "level": "WARNING", "cores": 8}`
+	if req.Body != want {
+		t.Errorf("body = %s, want %s", req.Body, want)
+	}
+}
+
+// TestWebhookJSONPayload tests the default JSON payload
+func TestWebhookJSONPayload(t *testing.T) {
+	var requests []webhookRequest
+	server := newWebhookServer(t, &requests)
+
+	action, err := NewWebhookAction(map[string]interface{}{"url": server.URL})
+	if err != nil {
+		t.Fatalf("NewWebhookAction() error = %v", err)
+	}
+
+	violations := []ThresholdViolation{
+		{Metric: "disk", Level: "critical", Message: "full", Value: 95.5, Mountpoint: "/", Device: "/dev/sda1"},
+		{Metric: "cpu", Level: "warning", Message: "busy", Value: 85},
+	}
+	for _, violation := range violations {
+		if err := action.Execute(violation); err != nil {
+			t.Fatalf("Execute() error = %v", err)
+		}
+	}
+
+	want := []string{
+		`{"device":"/dev/sda1","level":"critical","message":"full","metric":"disk","mountpoint":"/","value":95.5}`,
+		`{"level":"warning","message":"busy","metric":"cpu","value":85}`,
+	}
+	if len(requests) != len(want) {
+		t.Fatalf("got %d requests, want %d", len(requests), len(want))
+	}
+	for i, req := range requests {
+		if req.Body != want[i] {
+			t.Errorf("body = %s, want %s", req.Body, want[i])
+		}
+	}
+}
+
+// TestWebhookFormEncoding tests the default form-encoded payload
+func TestWebhookFormEncoding(t *testing.T) {
+	var requests []webhookRequest
+	server := newWebhookServer(t, &requests)
+
+	action, err := NewWebhookAction(map[string]interface{}{"url": server.URL, "encoding": "form"})
+	if err != nil {
+		t.Fatalf("NewWebhookAction() error = %v", err)
+	}
+	if err := action.Execute(ThresholdViolation{Metric: "disk", Level: "critical", Message: "full", Value: 95.5, Mountpoint: "/"}); err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+
+	req := requests[0]
+	if req.Method != http.MethodPost {
+		t.Errorf("method = %s, want POST", req.Method)
+	}
+	if got := req.Header.Get("Content-Type"); got != contentTypeForm {
+		t.Errorf("Content-Type = %q", got)
+	}
+	form, err := url.ParseQuery(req.Body)
+	if err != nil {
+		t.Fatalf("invalid form body %q: %v", req.Body, err)
+	}
+	if form.Get("metric") != "disk" || form.Get("value") != "95.5" || form.Get("mountpoint") != "/" {
+		t.Errorf("form = %v", form)
+	}
+}
+
+// TestWebhookInvalidConfig tests rejection of invalid webhook options
+func TestWebhookInvalidConfig(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  map[string]interface{}
+		wantErr string
+	}{
+		{"bad method", map[string]interface{}{"url": "http://x", "method": "DELETE"}, "method"},
+		{"bad encoding", map[string]interface{}{"url": "http://x", "encoding": "xml"}, "encoding"},
+		{"bad template", map[string]interface{}{"url": "http://x", "body": "{{.Violation"}, "template"},
+		{"bad headers", map[string]interface{}{"url": "http://x", "headers": []interface{}{"a"}}, "headers"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewWebhookAction(tt.config)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("NewWebhookAction() error = %v, want error containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}